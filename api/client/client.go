@@ -10,15 +10,18 @@ import (
 // CallContractParam 是查询合约的参数
 // From 代表查询的发起地址，如果不传，会随机从链上选择一个账户作为查询发起方
 // ContractAddress 是独一无二的合约地址
-// CalledFunc 对应的方法名称必须存在与 Abi 中，才能被正确解析
+// Abi 可以是 json 格式的 abi，也可以是 human-readable 的方法定义，如 function balanceOf(address) view returns (uint256)
+// CalledFunc 对应的方法名称必须存在与 Abi 中，才能被正确解析，方法存在重载时需要使用方法签名，如 transfer(address,uint256)
+// Abi 为空时，CalledFunc 可以直接使用包含返回值的方法定义
 // Params 对应的参数必须和 Abi 中 CalledFunc 对应的方法参数的数量类型相对应
-// 注意 如果 Abi 中定义了的某个参数类型为 address 类型，代表参数类型为 address.Address,
-// 此时使用string格式的地址是非法的，需要使用 address.Encoder 将string格式的账户地址转换为 address.Address 类型
+// 参数可以直接使用 abi 编码对应的 Go 类型，也可以使用字符串或 json 解析得到的值，由链的实现转换为对应的 abi 类型，
+// 如 address 使用16进制字符串、uint256 使用10进制或16进制字符串、数组使用json数组、结构体使用以字段名为key的对象，
+// 无法转换时返回的错误会指出是第几个参数
 type CallContractParam struct {
 	From            string        //调用发起方
 	ContractAddress string        //合约地址
-	Abi             string        //string格式的abi
-	CalledFunc      string        //方法名
+	Abi             string        //string格式的abi，支持 json 与 human-readable 格式
+	CalledFunc      string        //方法名、方法签名或方法定义
 	Params          []interface{} //合约方法参数
 }
 
@@ -49,6 +52,14 @@ type Client interface {
 	// 当设置过私钥之后，才可以调用 SendTx 接口发送交易以及 Transfer 转账接口
 	SetPrivate(hexPrivate string) (err error)
 
+	// SetKeystore 使用加密的 keystore 文件内容和密码设置 Client 的私钥
+	// 作用与 SetPrivate 相同，避免在程序之外明文传递私钥
+	SetKeystore(keyJSON string, passphrase string) (err error)
+
+	// SetMnemonic 使用 BIP-39 助记词、可选的助记词密码和派生路径设置 Client 的私钥
+	// path 为空时使用当前链默认的第一个账户的派生路径
+	SetMnemonic(mnemonic string, password string, path string) (err error)
+
 	// GetAccount 获取当前 Client 的使用账户地址
 	// 必须先调用过 SetPrivate 才能获取正确的 GetAccount 返回值
 	GetAccount() string
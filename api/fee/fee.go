@@ -1,5 +1,9 @@
 package fee
 
+import (
+	"time"
+)
+
 // OptionFee 是可选的交易费用参数
 // "可选" 代表在有该参数的接口，不指定该参数时，方法会在内部计算交易的推荐费用并使用
 // 如果使用者需要自行设置交易费，请清楚你在做什么
@@ -8,4 +12,65 @@ type OptionFee struct {
 	GasPrice uint64
 	// GasPrice 是完成当前交易需要消耗多少Gas
 	GasLimit uint64
+	// Speed 可选，GasPrice 为0时按照档位由费用预言机计算 gas 价格，为空时使用节点的 eth_gasPrice
+	Speed Speed
+}
+
+// Speed 是交易费用的档位，档位越快 gas 价格越高，预计的上链时间越短
+type Speed string
+
+const (
+	SpeedSlow     Speed = "slow"
+	SpeedStandard Speed = "standard"
+	SpeedFast     Speed = "fast"
+)
+
+// Estimate 是一个档位的推荐费用
+// 链支持 EIP-1559 时，MaxFeePerGas 与 MaxPriorityFeePerGas 用于动态手续费交易，GasPrice 用于传统交易；
+// 不支持时三者相同
+type Estimate struct {
+	Speed                Speed
+	GasPrice             uint64
+	MaxFeePerGas         uint64
+	MaxPriorityFeePerGas uint64
+	// WaitTime 是预计的上链时间，由档位对应的区块数与近期的出块时间计算得到
+	WaitTime time.Duration
+}
+
+// Recommendation 是费用预言机给出的各档位的推荐费用
+type Recommendation struct {
+	BaseFee   uint64        // 下一个区块的 base fee，链不支持 EIP-1559 时为0
+	BlockTime time.Duration // 近期的平均出块时间
+	Slow      Estimate
+	Standard  Estimate
+	Fast      Estimate
+}
+
+// Get 返回 speed 对应档位的推荐费用，未知的档位返回 Standard
+func (r *Recommendation) Get(speed Speed) Estimate {
+	switch speed {
+	case SpeedSlow:
+		return r.Slow
+	case SpeedFast:
+		return r.Fast
+	}
+	return r.Standard
+}
+
+// Caps 是自动计算的 gas 价格的上下限，为0代表不限制
+// BSC、OKC 等链的验证节点有最低 gas 价格，节点的 eth_gasPrice 可能低于该值，可以通过 Min 设置
+type Caps struct {
+	Min uint64
+	Max uint64
+}
+
+// Apply 将 price 限制在上下限之间
+func (c Caps) Apply(price uint64) uint64 {
+	if c.Min != 0 && price < c.Min {
+		price = c.Min
+	}
+	if c.Max != 0 && price > c.Max {
+		price = c.Max
+	}
+	return price
 }
@@ -1,7 +1,21 @@
 package provider
 
+import (
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/middleware"
+)
+
 // CommonProvider 定义了链调用服务提供者
 type CommonProvider struct {
 	// ProviderUrl 是节点地址，需要带scheme
 	ProviderUrl string
+
+	// Chain 可选，是链的名称，用于拦截器中的 Call.Chain，为空时 multichain.NewClient 等方法使用链类型的名称
+	Chain string
+
+	// Interceptors 可选，作用于每个发往节点的 JSON-RPC 请求与每次 Client 方法调用，参考 middleware 包
+	Interceptors []middleware.Interceptor
+
+	// FeeCaps 可选，是自动计算的 gas 价格的上下限，作用于 eth_gasPrice 与费用预言机的结果，用户指定的 gas 价格不受限制
+	FeeCaps fee.Caps
 }
@@ -58,7 +58,7 @@ type Tx interface {
 type TxData struct {
 	Status          uint8    `json:"status"`
 	TxHash          string   `json:"txHash"`
-	ContractAddress string   `json:"contractAddress"` //  部署合约交易返回的交易地址
+	ContractAddress string   `json:"contractAddress"` //  部署合约交易返回的交易地址，交易上链前为按照 from 与 nonce 计算的地址
 	TxType          string   `json:"txType"`
 	From            string   `json:"from"`  // 交易的发起方
 	Nonce           uint64   `json:"nonce"` // 请参考以太坊nonce定义
@@ -69,6 +69,24 @@ type TxData struct {
 	GasUsed         uint64   `json:"gas"`
 	GasPrice        uint64   `json:"gasPrice"`
 	BlockNumber     uint64   `json:"blockNumber"`
-	Date            string   `json:"date"`
-	Raw             []byte   `json:"raw"` //  交易详情原文,使用者可按需解析
+	Date            string   `json:"date"` // 交易所在区块的时间，UTC 时区的 RFC3339 格式
+	Raw             []byte   `json:"raw"`  //  交易详情原文,使用者可按需解析
+
+	// 以下字段在交易上链后从交易收据中获取
+	BlockHash         string `json:"blockHash"`
+	TxIndex           uint64 `json:"transactionIndex"`
+	CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+	EffectiveGasPrice uint64 `json:"effectiveGasPrice"` // 实际支付的 gas 价格，EIP-1559 交易与 GasPrice 可能不同
+	Logs              []*Log `json:"logs"`
+}
+
+// Log 是交易执行时合约产生的事件日志
+type Log struct {
+	Address  string   `json:"address"` // 产生日志的合约地址
+	Topics   []string `json:"topics"`
+	Data     []byte   `json:"data"`
+	LogIndex uint64   `json:"logIndex"` // 日志在区块中的序号
+	// 查询时提供了 abi 且能匹配到事件定义时，Event 为事件签名，Decoded 为以参数名为key的解析结果
+	Event   string                 `json:"event,omitempty"`
+	Decoded map[string]interface{} `json:"decoded,omitempty"`
 }
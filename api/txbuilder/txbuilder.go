@@ -1,19 +1,21 @@
 package txbuilder
 
 import (
+	"github.com/mgintoki/multichain/api/fee"
 	"github.com/mgintoki/multichain/api/tx"
 	"math/big"
 )
 
 // BuildTxParam 是构造一个交易的参数
 type BuildTxParam struct {
-	From     string   //交易的发起地址
-	To       string   //是交易的目标地址
-	Value    *big.Int //交易的原生币数量
-	Nonce    uint64   //可选，不传则内部计算nonce
-	GasLimit uint64   //可选，不传则内部计算推荐值并使用
-	GasPrice uint64   //可选，不传则内部计算推荐值并使用
-	Payload  []byte   //交易负载数据
+	From     string    //交易的发起地址
+	To       string    //是交易的目标地址
+	Value    *big.Int  //交易的原生币数量
+	Nonce    uint64    //可选，不传则内部计算nonce
+	GasLimit uint64    //可选，不传则内部计算推荐值并使用
+	GasPrice uint64    //可选，不传则内部计算推荐值并使用
+	Payload  []byte    //交易负载数据
+	Speed    fee.Speed //可选，GasPrice 为0时按照档位由费用预言机计算 gas 价格
 }
 
 // TxBuilder 是交易的构造器
@@ -24,6 +26,7 @@ type TxBuilder interface {
 }
 
 // BuildDeployTxReq 定义了一种特定的交易类型-部署合约交易
+// Params 与 client.CallContractParam 一样，支持字符串与 json 格式的参数
 type BuildDeployTxReq struct {
 	From     string        //交易的发起方
 	Abi      string        //智能合约的应用程序二进制接口
@@ -33,19 +36,23 @@ type BuildDeployTxReq struct {
 	Value    *big.Int      //可选的交易的Value字段
 	GasLimit uint64        //可选，不传则内部计算推荐值并使用
 	GasPrice uint64        //可选，不传则内部计算推荐值并使用
+	Speed    fee.Speed     //可选，GasPrice 为0时按照档位由费用预言机计算 gas 价格
 }
 
 // BuildInvokeTxReq 定义了一种特定的交易类型-调用合约交易
+// Abi 与 Method 的格式与 client.CallContractParam 的 Abi 与 CalledFunc 相同，支持 human-readable abi 与方法签名
+// Params 与 client.CallContractParam 一样，支持字符串与 json 格式的参数
 type BuildInvokeTxReq struct {
 	From            string        //交易的发起方
 	Abi             string        //智能合约的应用程序二进制接口
-	Method          string        //调用的交易方法（需要在Abi中有定义）
+	Method          string        //调用的交易方法（需要在Abi中有定义），重载的方法需要使用方法签名
 	Params          []interface{} //调用合约的参数
 	Nonce           uint64        //可选，不传则内部计算nonce
 	Value           *big.Int      //可选的交易的Value字段
 	ContractAddress string        //合约地址
 	GasLimit        uint64        //可选，不传则内部计算推荐值并使用
 	GasPrice        uint64        //可选，不传则内部计算推荐值并使用
+	Speed           fee.Speed     //可选，GasPrice 为0时按照档位由费用预言机计算 gas 价格
 }
 
 // ContractTxBuilder 是 TxBuilder 之上的一层封装，主要用于构建智能合约相关的交易
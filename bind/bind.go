@@ -0,0 +1,173 @@
+// Package bind 提供 cmd/mcbind 生成的合约绑定代码在运行时使用的类型与方法
+package bind
+
+import (
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// TransactOpts 是绑定代码构建交易时使用的参数
+type TransactOpts struct {
+	From     string   // 交易的发起方
+	Nonce    uint64   // 可选，不传则内部计算nonce
+	Value    *big.Int // 可选，调用 payable 方法时发送的原生币数量
+	GasLimit uint64   // 可选，不传则内部计算推荐值并使用
+	GasPrice uint64   // 可选，不传则内部计算推荐值并使用
+}
+
+// ParseLog 按照事件定义解析日志，返回以参数名为key的map
+// 与 abi.ParseLog 不同，indexed 的 bytesN 参数会解析为 [N]byte，
+// indexed 的 string、bytes、数组与结构体只能得到其 keccak256，解析为 web3.Hash
+func ParseLog(event *abi.Event, log *web3.Log) (map[string]interface{}, error) {
+	if !event.Anonymous {
+		if len(log.Topics) == 0 || log.Topics[0] != event.ID() {
+			return nil, fmt.Errorf("log is not event %s", event.Name)
+		}
+	}
+	topics := log.Topics
+	if !event.Anonymous {
+		topics = topics[1:]
+	}
+
+	res := map[string]interface{}{}
+	var nonIndexed []*abi.ArgumentStr
+	for i, arg := range event.Inputs.TupleElems() {
+		name := ArgName(arg.Name, i)
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, Argument(name, arg.Elem))
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("missing topic for %s", name)
+		}
+		topic := topics[0]
+		topics = topics[1:]
+
+		switch arg.Elem.Kind() {
+		case abi.KindBool, abi.KindInt, abi.KindUInt, abi.KindAddress:
+			v, err := abi.ParseTopic(arg.Elem, topic)
+			if err != nil {
+				return nil, err
+			}
+			res[name] = v
+		case abi.KindFixedBytes:
+			v := reflect.New(arg.Elem.GoType()).Elem()
+			reflect.Copy(v, reflect.ValueOf(topic[:arg.Elem.Size()]))
+			res[name] = v.Interface()
+		default:
+			res[name] = topic
+		}
+	}
+
+	if len(nonIndexed) != 0 {
+		t, err := abi.NewTypeFromArgument(&abi.ArgumentStr{Type: "tuple", Components: nonIndexed})
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := abi.Decode(t, log.Data)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range decoded.(map[string]interface{}) {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
+
+// Argument 由 abi 类型还原出参数定义
+func Argument(name string, t *abi.Type) *abi.ArgumentStr {
+	arg := &abi.ArgumentStr{Name: name, Type: t.String()}
+	base, suffix := t, ""
+	for base.Kind() == abi.KindSlice || base.Kind() == abi.KindArray {
+		if base.Kind() == abi.KindSlice {
+			suffix = "[]" + suffix
+		} else {
+			suffix = fmt.Sprintf("[%d]", base.Size()) + suffix
+		}
+		base = base.Elem()
+	}
+	if base.Kind() == abi.KindTuple {
+		arg.Type = "tuple" + suffix
+		for i, elem := range base.TupleElems() {
+			arg.Components = append(arg.Components, Argument(ArgName(elem.Name, i), elem.Elem))
+		}
+	}
+	return arg
+}
+
+// ArgName 返回参数在解析结果中的key，匿名参数使用其序号
+func ArgName(name string, index int) string {
+	if name == "" {
+		return fmt.Sprint(index)
+	}
+	return name
+}
+
+// Convert 将解析结果中的值转换为绑定代码中的类型
+// tuple 解析为以字段名为key的map，按照结构体字段的 abi 标签转换为绑定代码生成的结构体
+func Convert(res map[string]interface{}, key string, out interface{}) error {
+	v, ok := res[key]
+	if !ok {
+		return fmt.Errorf("missing value %s", key)
+	}
+	if err := convertValue(reflect.ValueOf(v), reflect.ValueOf(out).Elem()); err != nil {
+		return fmt.Errorf("value %s: %v", key, err)
+	}
+	return nil
+}
+
+func convertValue(value reflect.Value, target reflect.Value) error {
+	if value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return fmt.Errorf("expect %s, got nil", typeName(target.Type()))
+	}
+	if value.Type().AssignableTo(target.Type()) {
+		target.Set(value)
+		return nil
+	}
+
+	switch {
+	case target.Kind() == reflect.Struct && value.Kind() == reflect.Map:
+		for i := 0; i < target.NumField(); i++ {
+			field := target.Type().Field(i)
+			name := field.Tag.Get("abi")
+			if name == "" {
+				name = field.Name
+			}
+			item := value.MapIndex(reflect.ValueOf(name))
+			if !item.IsValid() {
+				return fmt.Errorf("missing field %s", name)
+			}
+			if err := convertValue(item, target.Field(i)); err != nil {
+				return fmt.Errorf(".%s: %v", name, err)
+			}
+		}
+		return nil
+	case target.Kind() == reflect.Slice && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array):
+		target.Set(reflect.MakeSlice(target.Type(), value.Len(), value.Len()))
+		return convertItems(value, target)
+	case target.Kind() == reflect.Array && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && target.Len() == value.Len():
+		return convertItems(value, target)
+	}
+	return fmt.Errorf("expect %s, got %s", typeName(target.Type()), value.Type())
+}
+
+func convertItems(value reflect.Value, target reflect.Value) error {
+	for i := 0; i < value.Len(); i++ {
+		if err := convertValue(value.Index(i), target.Index(i)); err != nil {
+			return fmt.Errorf("[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func typeName(t reflect.Type) string {
+	return strings.Replace(t.String(), "interface {}", "interface{}", -1)
+}
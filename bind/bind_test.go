@@ -0,0 +1,200 @@
+package bind
+
+import (
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testABI = `[
+{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+{"type":"event","name":"Tagged","anonymous":false,"inputs":[{"name":"from","type":"address","indexed":true},{"name":"tag","type":"string","indexed":true},{"name":"id","type":"bytes4","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+]`
+
+const testTupleABI = `[
+{"type":"constructor","inputs":[{"name":"owner","type":"address"}]},
+{"type":"function","name":"submit","stateMutability":"nonpayable","inputs":[{"name":"order","type":"tuple","components":[{"name":"id","type":"uint256"},{"name":"owner","type":"address"},{"name":"items","type":"tuple[]","components":[{"name":"amount","type":"uint256"},{"name":"token","type":"address"}]}]}],"outputs":[]},
+{"type":"function","name":"getOrder","stateMutability":"view","inputs":[{"name":"id","type":"uint256"}],"outputs":[{"name":"order","type":"tuple","components":[{"name":"id","type":"uint256"},{"name":"owner","type":"address"},{"name":"items","type":"tuple[]","components":[{"name":"amount","type":"uint256"},{"name":"token","type":"address"}]}]}]},
+{"type":"function","name":"price","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"tuple","components":[{"name":"amount","type":"uint256"},{"name":"decimals","type":"uint8"}]}]},
+{"type":"event","name":"Filled","anonymous":false,"inputs":[{"name":"id","type":"uint256","indexed":true},{"name":"items","type":"tuple[2]","indexed":false,"components":[{"name":"amount","type":"uint256"},{"name":"token","type":"address"}]}]}
+]`
+
+// testShadowABI 的参数与生成代码导入的包同名
+const testShadowABI = `[
+{"type":"constructor","inputs":[{"name":"bind","type":"address"},{"name":"client","type":"uint256"}]},
+{"type":"function","name":"lookup","stateMutability":"view","inputs":[{"name":"bind","type":"uint256"},{"name":"big","type":"uint256"},{"name":"abi","type":"bytes"},{"name":"web3","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"send","stateMutability":"nonpayable","inputs":[{"name":"tx","type":"bytes32"},{"name":"fmt","type":"string"},{"name":"txbuilder","type":"address"},{"name":"bind","type":"uint256"}],"outputs":[]}
+]`
+
+func TestParseArtifact(t *testing.T) {
+	foundry := `{"abi":` + testABI + `,"bytecode":{"object":"0x6080","sourceMap":""}}`
+	artifact, err := ParseArtifact([]byte(foundry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Bin != "0x6080" {
+		t.Fatalf("unexpected bytecode %s", artifact.Bin)
+	}
+
+	artifact.Name = "token"
+	code, err := Generate("token", artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"func DeployToken(builder txbuilder.ContractTxBuilder, opts *bind.TransactOpts) (tx.Tx, error)",
+		"func (c *Token) BalanceOf(account web3.Address) (out0 *big.Int, err error)",
+		"func (c *Token) Transfer(opts *bind.TransactOpts, to web3.Address, amount *big.Int) (tx.Tx, error)",
+		"func (c *Token) ParseTagged(log *web3.Log) (*TokenTagged, error)",
+	} {
+		if !strings.Contains(code, s) {
+			t.Fatalf("generated code does not contain %q:\n%s", s, code)
+		}
+	}
+}
+
+func TestGenerateTupleStructs(t *testing.T) {
+	code, err := Generate("exchange", &Artifact{Name: "exchange", ABI: testTupleABI, Bin: "0x6080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"type ExchangeOrder struct {",
+		"Items []ExchangeItems",
+		"type ExchangeItems struct {",
+		"type ExchangeTuple struct {",
+		"`abi:\"decimals\"`",
+		"func (c *Exchange) Submit(opts *bind.TransactOpts, order ExchangeOrder) (tx.Tx, error)",
+		"func (c *Exchange) GetOrder(id *big.Int) (out0 ExchangeOrder, err error)",
+		"Items [2]ExchangeItems",
+	} {
+		if !strings.Contains(code, s) {
+			t.Fatalf("generated code does not contain %q:\n%s", s, code)
+		}
+	}
+}
+
+// TestGeneratedCodeCompiles 使用 go build 编译生成的代码
+func TestGeneratedCodeCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip go build in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 生成的代码放在临时模块中，通过 replace 引用当前的 multichain
+	dir := t.TempDir()
+	goMod := "module gentest\n\ngo 1.16\n\nrequire github.com/mgintoki/multichain v0.0.0\n\nreplace github.com/mgintoki/multichain => " + root + "\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goSum, err := ioutil.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, abiJSON := range map[string]string{"token": testABI, "exchange": testTupleABI, "shadow": testShadowABI} {
+		code, err := Generate("gentest", &Artifact{Name: name, ABI: abiJSON, Bin: "0x6080"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".go"), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestConvertTuple(t *testing.T) {
+	type item struct {
+		Amount *big.Int     `abi:"amount"`
+		Token  web3.Address `abi:"token"`
+	}
+	type order struct {
+		ID    *big.Int `abi:"id"`
+		Items []item   `abi:"items"`
+	}
+	token := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	res := map[string]interface{}{
+		"order": map[string]interface{}{
+			"id":    big.NewInt(7),
+			"items": []map[string]interface{}{{"amount": big.NewInt(1), "token": token}},
+		},
+	}
+
+	var got order
+	if err := Convert(res, "order", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID.Int64() != 7 || len(got.Items) != 1 || got.Items[0].Amount.Int64() != 1 || got.Items[0].Token != token {
+		t.Fatalf("unexpected order %+v", got)
+	}
+
+	delete(res["order"].(map[string]interface{}), "items")
+	if err := Convert(res, "order", &got); err == nil {
+		t.Fatal("expect missing field error")
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	parsed, err := abi.NewABI(testABI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events["Tagged"]
+	from := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	var fromTopic, tagTopic, idTopic web3.Hash
+	copy(fromTopic[12:], from[:])
+	tagTopic[0] = 0xaa
+	copy(idTopic[:], []byte{1, 2, 3, 4})
+	data, err := abi.Encode([]interface{}{big.NewInt(42)}, abi.MustNewType("tuple(uint256)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ParseLog(event, &web3.Log{Topics: []web3.Hash{event.ID(), fromTopic, tagTopic, idTopic}, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		gotFrom  web3.Address
+		gotTag   web3.Hash
+		gotID    [4]byte
+		gotValue *big.Int
+	)
+	for key, out := range map[string]interface{}{"from": &gotFrom, "tag": &gotTag, "id": &gotID, "value": &gotValue} {
+		if err := Convert(res, key, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gotFrom != from || gotTag != tagTopic || gotID != [4]byte{1, 2, 3, 4} || gotValue.Int64() != 42 {
+		t.Fatalf("unexpected parsed log %v", res)
+	}
+
+	var wrong string
+	if err := Convert(res, "value", &wrong); err == nil {
+		t.Fatal("expect type mismatch")
+	}
+}
@@ -0,0 +1,427 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mgintoki/go-web3/abi"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Artifact 是生成绑定代码需要的合约信息
+type Artifact struct {
+	Name string // 合约名称，作为生成的类型名
+	ABI  string // json 格式的 abi
+	Bin  string // 可选，16进制的部署字节码，为空时不生成部署方法
+}
+
+// ParseArtifact 解析 abi 文件或编译产物
+// 支持 json 格式的 abi 数组、Hardhat 产物 ({"contractName", "abi", "bytecode": "0x..."})
+// 以及 Foundry 产物 ({"abi", "bytecode": {"object": "0x..."}})
+func ParseArtifact(data []byte) (*Artifact, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty artifact")
+	}
+	if data[0] == '[' {
+		return &Artifact{ABI: string(data)}, nil
+	}
+
+	var raw struct {
+		ContractName string          `json:"contractName"`
+		ABI          json.RawMessage `json:"abi"`
+		Bytecode     json.RawMessage `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw.ABI) == 0 {
+		return nil, fmt.Errorf("abi not found in artifact")
+	}
+	artifact := &Artifact{Name: raw.ContractName, ABI: string(raw.ABI)}
+
+	if len(raw.Bytecode) != 0 {
+		var hardhat string
+		var foundry struct {
+			Object string `json:"object"`
+		}
+		if err := json.Unmarshal(raw.Bytecode, &hardhat); err == nil {
+			artifact.Bin = hardhat
+		} else if err := json.Unmarshal(raw.Bytecode, &foundry); err == nil {
+			artifact.Bin = foundry.Object
+		} else {
+			return nil, fmt.Errorf("invalid bytecode: %v", err)
+		}
+	}
+	if artifact.Bin != "" && !strings.HasPrefix(artifact.Bin, "0x") {
+		artifact.Bin = "0x" + artifact.Bin
+	}
+	if artifact.Bin == "0x" {
+		artifact.Bin = ""
+	}
+	return artifact, nil
+}
+
+type tmplArg struct {
+	Name  string // 生成代码中的参数名
+	Key   string // 解析结果中的key
+	Type  string
+	Field string // 事件结构体中的字段名
+}
+
+type tmplMethod struct {
+	Name     string
+	Original string
+	Const    bool
+	Inputs   []tmplArg
+	Outputs  []tmplArg
+}
+
+// tmplStruct 是由合约中的 tuple 类型生成的结构体
+type tmplStruct struct {
+	Name   string
+	Tuple  string // tuple 类型的定义
+	Fields []tmplArg
+}
+
+type tmplEvent struct {
+	Name     string
+	Original string
+	Fields   []tmplArg
+}
+
+type tmplData struct {
+	Package     string
+	Type        string
+	ABI         string
+	Bin         string
+	Constructor []tmplArg
+	Methods     []tmplMethod
+	Events      []tmplEvent
+	Structs     []*tmplStruct
+}
+
+// generator 记录生成过程中由 tuple 类型生成的结构体，相同定义的 tuple 只生成一个结构体
+type generator struct {
+	prefix  string
+	structs []*tmplStruct
+	byTuple map[string]*tmplStruct
+	names   map[string]bool
+}
+
+// Generate 生成合约的绑定代码
+// 重载的方法与 abi 解析结果一致，只保留最后一个定义
+func Generate(pkg string, artifact *Artifact) (string, error) {
+	parsed, err := abi.NewABI(artifact.ABI)
+	if err != nil {
+		return "", err
+	}
+	if artifact.Name == "" {
+		return "", fmt.Errorf("contract name not set")
+	}
+
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, []byte(artifact.ABI)); err != nil {
+		return "", err
+	}
+	data := tmplData{
+		Package: pkg,
+		Type:    exportedName(artifact.Name),
+		ABI:     strings.Replace(compact.String(), "`", "` + \"`\" + `", -1),
+		Bin:     artifact.Bin,
+	}
+	g := &generator{prefix: data.Type, byTuple: map[string]*tmplStruct{}, names: map[string]bool{}}
+	if parsed.Constructor != nil {
+		data.Constructor = g.inputs(parsed.Constructor.Inputs)
+	}
+
+	methodNames := make([]string, 0, len(parsed.Methods))
+	for name := range parsed.Methods {
+		methodNames = append(methodNames, name)
+	}
+	sort.Strings(methodNames)
+	for _, name := range methodNames {
+		m := parsed.Methods[name]
+		method := tmplMethod{Name: exportedName(name), Original: name, Const: m.Const, Inputs: g.inputs(m.Inputs)}
+		for i, elem := range m.Outputs.TupleElems() {
+			method.Outputs = append(method.Outputs, tmplArg{
+				Name: fmt.Sprintf("out%d", i),
+				Key:  ArgName(elem.Name, i),
+				Type: g.goType(elem.Elem, elem.Name),
+			})
+		}
+		data.Methods = append(data.Methods, method)
+	}
+
+	eventNames := make([]string, 0, len(parsed.Events))
+	for name := range parsed.Events {
+		eventNames = append(eventNames, name)
+	}
+	sort.Strings(eventNames)
+	for _, name := range eventNames {
+		e := parsed.Events[name]
+		event := tmplEvent{Name: exportedName(name), Original: name}
+		for i, elem := range e.Inputs.TupleElems() {
+			t := g.goType(elem.Elem, elem.Name)
+			if elem.Indexed {
+				switch elem.Elem.Kind() {
+				case abi.KindBool, abi.KindInt, abi.KindUInt, abi.KindAddress, abi.KindFixedBytes:
+				default:
+					t = "web3.Hash"
+				}
+			}
+			field := exportedName(elem.Name)
+			if elem.Name == "" {
+				field = fmt.Sprintf("Arg%d", i)
+			}
+			event.Fields = append(event.Fields, tmplArg{Key: ArgName(elem.Name, i), Type: t, Field: field})
+		}
+		data.Events = append(data.Events, event)
+	}
+	data.Structs = g.structs
+
+	var buf bytes.Buffer
+	if err := bindTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, buf.String())
+	}
+	return string(code), nil
+}
+
+// reservedNames 是生成的代码中使用的标识符，包括 bindTemplate 导入的包名，参数与其重名时需要加 _ 后缀
+var reservedNames = map[string]bool{
+	"c": true, "opts": true, "builder": true, "err": true, "res": true,
+	"fmt": true, "big": true, "web3": true, "abi": true, "client": true, "tx": true, "txbuilder": true, "bind": true,
+}
+
+func (g *generator) inputs(t *abi.Type) []tmplArg {
+	var args []tmplArg
+	for i, elem := range t.TupleElems() {
+		name := elem.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if token.IsKeyword(name) || reservedNames[name] || strings.HasPrefix(name, "out") {
+			name += "_"
+		}
+		args = append(args, tmplArg{Name: name, Type: g.goType(elem.Elem, elem.Name)})
+	}
+	return args
+}
+
+// goType 返回 abi 类型在绑定代码中的类型，tuple 生成为结构体，hint 为参数名，用于结构体命名
+func (g *generator) goType(t *abi.Type, hint string) string {
+	switch t.Kind() {
+	case abi.KindTuple:
+		return g.tupleStruct(t, hint).Name
+	case abi.KindSlice:
+		return "[]" + g.goType(t.Elem(), hint)
+	case abi.KindArray:
+		return fmt.Sprintf("[%d]%s", t.Size(), g.goType(t.Elem(), hint))
+	default:
+		return strings.Replace(t.GoType().String(), "interface {}", "interface{}", -1)
+	}
+}
+
+// tupleStruct 返回 tuple 对应的结构体，结构体以合约名加参数名命名，重名时加序号
+func (g *generator) tupleStruct(t *abi.Type, hint string) *tmplStruct {
+	key := tupleSignature(t)
+	if s, ok := g.byTuple[key]; ok {
+		return s
+	}
+	base := g.prefix + exportedName(hint)
+	if hint == "" {
+		base = g.prefix + "Tuple"
+	}
+	name := base
+	for i := 1; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	s := &tmplStruct{Name: name, Tuple: key}
+	g.names[name] = true
+	g.byTuple[key] = s
+
+	for i, elem := range t.TupleElems() {
+		field := exportedName(elem.Name)
+		if elem.Name == "" {
+			field = fmt.Sprintf("Arg%d", i)
+		}
+		s.Fields = append(s.Fields, tmplArg{Key: ArgName(elem.Name, i), Type: g.goType(elem.Elem, elem.Name), Field: field})
+	}
+	g.structs = append(g.structs, s)
+	return s
+}
+
+// tupleSignature 返回带字段名的类型定义，如 tuple(uint256 id,address owner)
+// abi.Type 的 String 不包含 tuple 的字段名，字段名不同的 tuple 需要生成不同的结构体
+func tupleSignature(t *abi.Type) string {
+	switch t.Kind() {
+	case abi.KindTuple:
+		fields := make([]string, len(t.TupleElems()))
+		for i, elem := range t.TupleElems() {
+			fields[i] = strings.TrimSpace(tupleSignature(elem.Elem) + " " + elem.Name)
+		}
+		return "tuple(" + strings.Join(fields, ",") + ")"
+	case abi.KindSlice:
+		return tupleSignature(t.Elem()) + "[]"
+	case abi.KindArray:
+		return fmt.Sprintf("%s[%d]", tupleSignature(t.Elem()), t.Size())
+	default:
+		return t.String()
+	}
+}
+
+func exportedName(name string) string {
+	name = strings.TrimLeft(name, "_")
+	if name == "" {
+		return "X"
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+var bindTemplate = template.Must(template.New("bind").Parse(`// Code generated by mcbind. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/bind"
+)
+
+var (
+	_ = big.NewInt
+	_ = web3.HexToAddress
+	_ = fmt.Errorf
+)
+
+// {{.Type}}ABI 是 {{.Type}} 合约的 abi
+const {{.Type}}ABI = ` + "`" + `{{.ABI}}` + "`" + `
+{{range .Structs}}
+// {{.Name}} 对应合约中的 {{.Tuple}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Field}} {{.Type}} ` + "`" + `abi:"{{.Key}}"` + "`" + `
+{{- end}}
+}
+{{end}}{{if .Bin}}
+// {{.Type}}Bin 是 {{.Type}} 合约的部署字节码
+const {{.Type}}Bin = "{{.Bin}}"
+
+// Deploy{{.Type}} 构建部署 {{.Type}} 合约的交易
+func Deploy{{.Type}}(builder txbuilder.ContractTxBuilder, opts *bind.TransactOpts{{range .Constructor}}, {{.Name}} {{.Type}}{{end}}) (tx.Tx, error) {
+	return builder.BuildDeployTx(txbuilder.BuildDeployTxReq{
+		From:     opts.From,
+		Abi:      {{.Type}}ABI,
+		ByteCode: {{.Type}}Bin,
+		Params:   []interface{}{ {{range $i, $a := .Constructor}}{{if $i}}, {{end}}{{$a.Name}}{{end}} },
+		Nonce:    opts.Nonce,
+		Value:    opts.Value,
+		GasLimit: opts.GasLimit,
+		GasPrice: opts.GasPrice,
+	})
+}
+{{end}}
+// {{.Type}} 是 {{.Type}} 合约的绑定
+type {{.Type}} struct {
+	address string
+	abi     *abi.ABI
+	client  client.Client
+	builder txbuilder.ContractTxBuilder
+}
+
+// New{{.Type}} 新建 {{.Type}} 合约的绑定
+// client 用于查询合约，builder 用于构建调用合约的交易，不需要时可以为nil
+func New{{.Type}}(address string, client client.Client, builder txbuilder.ContractTxBuilder) (*{{.Type}}, error) {
+	parsed, err := abi.NewABI({{.Type}}ABI)
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Type}}{address: address, abi: parsed, client: client, builder: builder}, nil
+}
+
+func (c *{{.Type}}) call(method string, params ...interface{}) (map[string]interface{}, error) {
+	res, err := c.client.QueryContract(client.CallContractParam{
+		ContractAddress: c.address,
+		Abi:             {{.Type}}ABI,
+		CalledFunc:      method,
+		Params:          params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.DecodeRes, nil
+}
+
+func (c *{{.Type}}) transact(opts *bind.TransactOpts, method string, params ...interface{}) (tx.Tx, error) {
+	return c.builder.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            opts.From,
+		Abi:             {{.Type}}ABI,
+		Method:          method,
+		Params:          params,
+		Nonce:           opts.Nonce,
+		Value:           opts.Value,
+		ContractAddress: c.address,
+		GasLimit:        opts.GasLimit,
+		GasPrice:        opts.GasPrice,
+	})
+}
+{{range .Methods}}{{$m := .}}{{if .Const}}
+// {{.Name}} 查询合约方法 {{.Original}}
+func (c *{{$.Type}}) {{.Name}}({{range $i, $a := .Inputs}}{{if $i}}, {{end}}{{$a.Name}} {{$a.Type}}{{end}}) ({{range .Outputs}}{{.Name}} {{.Type}}, {{end}}err error) {
+	res, err := c.call("{{.Original}}"{{range .Inputs}}, {{.Name}}{{end}})
+	if err != nil {
+		return
+	}
+{{- range .Outputs}}
+	if err = bind.Convert(res, "{{.Key}}", &{{.Name}}); err != nil {
+		return
+	}
+{{- end}}
+	return
+}
+{{else}}
+// {{.Name}} 构建调用合约方法 {{.Original}} 的交易
+func (c *{{$.Type}}) {{.Name}}(opts *bind.TransactOpts{{range .Inputs}}, {{.Name}} {{.Type}}{{end}}) (tx.Tx, error) {
+	return c.transact(opts, "{{.Original}}"{{range .Inputs}}, {{.Name}}{{end}})
+}
+{{end}}{{end}}{{range .Events}}
+// {{$.Type}}{{.Name}} 是合约事件 {{.Original}}
+type {{$.Type}}{{.Name}} struct {
+{{- range .Fields}}
+	{{.Field}} {{.Type}}
+{{- end}}
+	Raw *web3.Log
+}
+
+// Parse{{.Name}} 解析合约事件 {{.Original}} 的日志
+func (c *{{$.Type}}) Parse{{.Name}}(log *web3.Log) (*{{$.Type}}{{.Name}}, error) {
+	res, err := bind.ParseLog(c.abi.Events["{{.Original}}"], log)
+	if err != nil {
+		return nil, err
+	}
+	event := &{{$.Type}}{{.Name}}{Raw: log}
+{{- range .Fields}}
+	if err := bind.Convert(res, "{{.Key}}", &event.{{.Field}}); err != nil {
+		return nil, err
+	}
+{{- end}}
+	return event, nil
+}
+{{end}}`))
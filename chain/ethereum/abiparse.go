@@ -0,0 +1,381 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/multichain/errno"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContractABI 是解析后的合约 abi
+// 与 abi.ABI 不同，ContractABI 保留了所有重载的方法，abi.ABI.Methods 中同名的方法只保留最后一个
+type ContractABI struct {
+	*abi.ABI
+	overloads map[string][]*abi.Method
+	events    map[web3.Hash]*abi.Event
+}
+
+const maxCachedABI = 256
+
+var (
+	abiCacheLock sync.Mutex
+	abiCache     = map[string]*ContractABI{}
+)
+
+// ParseContractABI 解析合约 abi，解析结果会被缓存，相同的 abi 不会被重复解析
+// 支持以下格式:
+//
+//	json 格式的 abi: [{"type":"function","name":"balanceOf",...}]
+//	json 数组格式的 human-readable abi: ["function balanceOf(address owner) view returns (uint256)"]
+//	以换行或分号分隔的 human-readable abi: function balanceOf(address) view returns (uint256); event Transfer(address indexed from, address indexed to, uint256 value)
+//
+// human-readable abi 支持 function、event、constructor 定义，fallback、receive、error 定义会被忽略
+func ParseContractABI(s string) (*ContractABI, error) {
+	s = strings.TrimSpace(s)
+	abiCacheLock.Lock()
+	cached, ok := abiCache[s]
+	abiCacheLock.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	parsed, err := parseContractABI(s)
+	if err != nil {
+		return nil, err
+	}
+
+	abiCacheLock.Lock()
+	if len(abiCache) >= maxCachedABI {
+		abiCache = map[string]*ContractABI{}
+	}
+	abiCache[s] = parsed
+	abiCacheLock.Unlock()
+	return parsed, nil
+}
+
+func parseContractABI(s string) (*ContractABI, error) {
+	a := &ContractABI{
+		ABI:       &abi.ABI{Methods: map[string]*abi.Method{}, Events: map[string]*abi.Event{}},
+		overloads: map[string][]*abi.Method{},
+		events:    map[web3.Hash]*abi.Event{},
+	}
+	if s == "" {
+		return a, nil
+	}
+
+	var fragments []string
+	if strings.HasPrefix(s, "[") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, errno.InvalidABI.Add(err.Error())
+		}
+		if len(raw) != 0 && strings.HasPrefix(strings.TrimSpace(string(raw[0])), "{") {
+			if err := a.parseJSON(s); err != nil {
+				return nil, errno.InvalidABI.Add(err.Error())
+			}
+			return a, nil
+		}
+		if err := json.Unmarshal([]byte(s), &fragments); err != nil {
+			return nil, errno.InvalidABI.Add(err.Error())
+		}
+	} else {
+		fragments = strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ';' })
+	}
+
+	for _, f := range fragments {
+		if err := a.parseFragment(f); err != nil {
+			return nil, errno.InvalidABI.Add(fmt.Sprintf("%q: %v", strings.TrimSpace(f), err))
+		}
+	}
+	return a, nil
+}
+
+func (a *ContractABI) parseJSON(s string) error {
+	var fields []struct {
+		Type            string
+		Name            string
+		Constant        bool
+		Anonymous       bool
+		StateMutability string
+		Inputs          []*abi.ArgumentStr
+		Outputs         []*abi.ArgumentStr
+	}
+	if err := json.Unmarshal([]byte(s), &fields); err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		switch field.Type {
+		case "constructor":
+			if a.Constructor != nil {
+				return fmt.Errorf("multiple constructor declaration")
+			}
+			inputs, err := tupleType(field.Inputs)
+			if err != nil {
+				return err
+			}
+			a.Constructor = &abi.Method{Inputs: inputs}
+
+		case "function", "":
+			inputs, err := tupleType(field.Inputs)
+			if err != nil {
+				return err
+			}
+			outputs, err := tupleType(field.Outputs)
+			if err != nil {
+				return err
+			}
+			a.addMethod(&abi.Method{
+				Name:    field.Name,
+				Const:   field.Constant || field.StateMutability == "view" || field.StateMutability == "pure",
+				Inputs:  inputs,
+				Outputs: outputs,
+			})
+
+		case "event":
+			inputs, err := tupleType(field.Inputs)
+			if err != nil {
+				return err
+			}
+			a.addEvent(&abi.Event{Name: field.Name, Anonymous: field.Anonymous, Inputs: inputs})
+
+		case "fallback", "receive", "error":
+
+		default:
+			return fmt.Errorf("unknown field type '%s'", field.Type)
+		}
+	}
+	return nil
+}
+
+func tupleType(args []*abi.ArgumentStr) (*abi.Type, error) {
+	if args == nil {
+		args = []*abi.ArgumentStr{}
+	}
+	return abi.NewTypeFromArgument(&abi.ArgumentStr{Type: "tuple", Components: args})
+}
+
+func (a *ContractABI) parseFragment(f string) error {
+	f = strings.TrimSpace(f)
+	if f == "" {
+		return nil
+	}
+	kind := strings.Fields(f)[0]
+	if i := strings.Index(kind, "("); i != -1 {
+		kind = kind[:i]
+	}
+
+	switch kind {
+	case "function":
+		m, err := parseMethodFragment(f)
+		if err != nil {
+			return err
+		}
+		a.addMethod(m)
+	case "event":
+		e, err := parseEventFragment(f)
+		if err != nil {
+			return err
+		}
+		a.addEvent(e)
+	case "constructor":
+		if a.Constructor != nil {
+			return fmt.Errorf("multiple constructor declaration")
+		}
+		_, inputs, _, err := splitFragment(strings.TrimPrefix(f, "constructor"))
+		if err != nil {
+			return err
+		}
+		a.Constructor = &abi.Method{Inputs: inputs}
+	case "fallback", "receive", "error":
+	default:
+		return fmt.Errorf("expect function, event or constructor")
+	}
+	return nil
+}
+
+func (a *ContractABI) addMethod(m *abi.Method) {
+	a.Methods[m.Name] = m
+	a.overloads[m.Name] = append(a.overloads[m.Name], m)
+}
+
+func (a *ContractABI) addEvent(e *abi.Event) {
+	a.Events[e.Name] = e
+	if !e.Anonymous {
+		a.events[e.ID()] = e
+	}
+}
+
+// EventByID 按照 topic0 查找事件定义，重载的事件也可以被找到，匿名事件无法被找到
+func (a *ContractABI) EventByID(id web3.Hash) (*abi.Event, bool) {
+	e, ok := a.events[id]
+	return e, ok
+}
+
+// Method 查找合约方法
+// method 可以是方法名、方法签名 (如 transfer(address,uint256)) 或 human-readable 的方法定义
+// 方法存在重载时，必须使用方法签名或方法定义指定其中一个
+// abi 中不存在该方法时，如果 method 是包含返回值的方法定义，直接使用该定义
+func (a *ContractABI) Method(method string) (*abi.Method, error) {
+	method = strings.TrimSpace(method)
+	if !strings.Contains(method, "(") {
+		return a.methodByName(method)
+	}
+
+	m, err := parseMethodFragment(method)
+	if err != nil {
+		return nil, errno.InvalidABI.Add(fmt.Sprintf("%q: %v", method, err))
+	}
+	sig := m.Sig()
+	for _, candidate := range a.overloads[m.Name] {
+		if candidate.Sig() == sig {
+			return candidate, nil
+		}
+	}
+	if strings.HasSuffix(method, "()") && len(a.overloads[m.Name]) != 0 {
+		//兼容 CalledFunc 传入 name() 的用法
+		return a.methodByName(m.Name)
+	}
+	if len(a.overloads[m.Name]) == 0 && fragmentHasReturns(method) {
+		return m, nil
+	}
+	return nil, errno.MethodNotFound.Add(sig + a.candidates(m.Name))
+}
+
+func (a *ContractABI) methodByName(name string) (*abi.Method, error) {
+	methods := a.overloads[name]
+	switch len(methods) {
+	case 0:
+		return nil, errno.MethodNotFound.Add(name)
+	case 1:
+		return methods[0], nil
+	}
+	return nil, errno.AmbiguousMethod.Add(name + a.candidates(name))
+}
+
+func (a *ContractABI) candidates(name string) string {
+	var sigs []string
+	for _, m := range a.overloads[name] {
+		sigs = append(sigs, m.Sig())
+	}
+	if len(sigs) == 0 {
+		return ""
+	}
+	sort.Strings(sigs)
+	return ", candidates: " + strings.Join(sigs, ", ")
+}
+
+var (
+	returnsRegexp = regexp.MustCompile(`\)\s*[\w\s]*\breturns\s*\(`)
+	intRegexp     = regexp.MustCompile(`\b(u?int)\b`)
+	locRegexp     = regexp.MustCompile(`\b(memory|calldata|storage|payable)\b`)
+)
+
+func fragmentHasReturns(f string) bool {
+	return returnsRegexp.MatchString(f)
+}
+
+// parseMethodFragment 解析方法定义，如:
+// function transfer(address to, uint256 amount) external returns (bool)
+// balanceOf(address) view returns (uint256)
+// transfer(address,uint256)
+func parseMethodFragment(f string) (*abi.Method, error) {
+	f = strings.TrimPrefix(strings.TrimSpace(f), "function ")
+	name, inputs, rest, err := splitFragment(f)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("method name not set")
+	}
+
+	m := &abi.Method{Name: name, Inputs: inputs}
+	modifiers := rest
+	if i := strings.Index(rest, "returns"); i != -1 {
+		modifiers = rest[:i]
+		_, outputs, tail, err := splitFragment(strings.TrimSpace(rest[i+len("returns"):]))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(tail) != "" {
+			return nil, fmt.Errorf("unexpected %q", tail)
+		}
+		m.Outputs = outputs
+	} else {
+		m.Outputs, _ = abi.NewType("tuple()")
+	}
+	for _, modifier := range strings.Fields(modifiers) {
+		switch modifier {
+		case "view", "pure", "constant":
+			m.Const = true
+		case "external", "public", "payable", "nonpayable", "virtual", "override":
+		default:
+			return nil, fmt.Errorf("unexpected %q", modifier)
+		}
+	}
+	return m, nil
+}
+
+// parseEventFragment 解析事件定义，如:
+// event Transfer(address indexed from, address indexed to, uint256 value)
+func parseEventFragment(f string) (*abi.Event, error) {
+	f = strings.TrimPrefix(strings.TrimSpace(f), "event ")
+	name, inputs, rest, err := splitFragment(f)
+	if err != nil {
+		return nil, err
+	}
+	e := abi.NewEventFromType(name, inputs)
+	switch strings.TrimSpace(rest) {
+	case "":
+	case "anonymous":
+		e.Anonymous = true
+	default:
+		return nil, fmt.Errorf("unexpected %q", rest)
+	}
+	return e, nil
+}
+
+// splitFragment 将 name(params) rest 拆分为名称、参数类型与剩余部分
+func splitFragment(f string) (string, *abi.Type, string, error) {
+	start := strings.Index(f, "(")
+	if start == -1 {
+		return "", nil, "", fmt.Errorf("expect '(' in %q", f)
+	}
+	depth, end := 0, -1
+	for i := start; i < len(f) && end == -1; i++ {
+		switch f[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end == -1 {
+		return "", nil, "", fmt.Errorf("unbalanced parentheses in %q", f)
+	}
+
+	params := f[start+1 : end]
+	params = locRegexp.ReplaceAllString(params, "")
+	params = intRegexp.ReplaceAllString(params, "${1}256")
+	var b strings.Builder
+	for i := 0; i < len(params); i++ {
+		//匿名的结构体 (address,uint256) 需要写成 tuple(address,uint256)
+		if params[i] == '(' && !strings.HasSuffix(b.String(), "tuple") {
+			b.WriteString("tuple")
+		}
+		b.WriteByte(params[i])
+	}
+	t, err := abi.NewType("tuple(" + b.String() + ")")
+	if err != nil {
+		return "", nil, "", err
+	}
+	return strings.TrimSpace(f[:start]), t, f[end+1:], nil
+}
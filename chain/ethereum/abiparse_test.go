@@ -0,0 +1,97 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"github.com/mgintoki/multichain/errno"
+	"strings"
+	"testing"
+)
+
+const overloadedABI = `[
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"safeTransferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+func TestContractABIMethod(t *testing.T) {
+	a, err := ParseContractABI(overloadedABI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Method("safeTransferFrom"); err == nil || !strings.Contains(err.Error(), errno.AmbiguousMethod.Msg) {
+		t.Fatalf("expect ambiguous method, got %v", err)
+	}
+	cases := map[string]string{
+		"safeTransferFrom(address,address,uint256)":                                "42842e0e",
+		"safeTransferFrom(address,address,uint256,bytes)":                          "b88d4fde",
+		"function safeTransferFrom(address from, address to, uint tokenId, bytes)": "b88d4fde",
+		"balanceOf":   "70a08231",
+		"balanceOf()": "70a08231",
+	}
+	for method, id := range cases {
+		m, err := a.Method(method)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if hex.EncodeToString(m.ID()) != id {
+			t.Fatalf("%s: expect selector %s, got %x", method, id, m.ID())
+		}
+	}
+	if _, err := a.Method("safeTransferFrom(address)"); err == nil || !strings.Contains(err.Error(), "candidates") {
+		t.Fatalf("expect method not found, got %v", err)
+	}
+}
+
+func TestParseHumanReadableABI(t *testing.T) {
+	fragments := []string{
+		`["function balanceOf(address owner) view returns (uint256)", "function transfer(address to, uint256 amount) returns (bool)", "event Transfer(address indexed from, address indexed to, uint256 value)", "constructor(string memory name, (address,uint256)[] shares)"]`,
+		"function balanceOf(address) external view returns (uint256 balance)\nfunction transfer(address,uint256) returns (bool); event Transfer(address indexed, address indexed, uint256)\nconstructor(string, tuple(address,uint256)[])",
+	}
+	for _, f := range fragments {
+		a, err := ParseContractABI(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		balanceOf, err := a.Method("balanceOf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !balanceOf.Const || len(balanceOf.Outputs.TupleElems()) != 1 {
+			t.Fatalf("unexpected balanceOf %+v", balanceOf)
+		}
+		transfer, err := a.Method("transfer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transfer.Const || hex.EncodeToString(transfer.ID()) != "a9059cbb" {
+			t.Fatalf("unexpected transfer %s", transfer.Sig())
+		}
+		if a.Events["Transfer"].ID().String() != "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+			t.Fatalf("unexpected event id %s", a.Events["Transfer"].ID())
+		}
+		if a.Constructor == nil || a.Constructor.Inputs.String() != "(string,(address,uint256)[])" {
+			t.Fatalf("unexpected constructor")
+		}
+	}
+
+	// abi 为空时直接使用方法定义
+	empty, err := ParseContractABI("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := empty.Method("function decimals() view returns (uint8)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(m.ID()) != "313ce567" || m.Outputs.String() != "(uint8)" {
+		t.Fatalf("unexpected method %s", m.Sig())
+	}
+	if _, err := empty.Method("decimals()"); err == nil {
+		t.Fatal("expect method not found")
+	}
+
+	if _, err := ParseContractABI("function broken(address"); err == nil {
+		t.Fatal("expect invalid abi")
+	}
+}
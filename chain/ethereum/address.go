@@ -1,6 +1,8 @@
 package ethereum
 
 import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mgintoki/go-web3"
 	"github.com/mgintoki/multichain/api/address"
 )
@@ -29,3 +31,13 @@ func (a *AddressEncoder) AddressToHex(addr address.Address) string {
 func (a *AddressEncoder) HexToAddress(addr string) address.Address {
 	return web3.HexToAddress(addr)
 }
+
+// checksumAddress 返回 EIP-55 校验和格式的地址
+func checksumAddress(addr web3.Address) string {
+	return common.Address(addr).Hex()
+}
+
+// createAddress 计算 from 使用 nonce 通过 CREATE 部署得到的合约地址
+func createAddress(from web3.Address, nonce uint64) string {
+	return checksumAddress(web3.Address(crypto.CreateAddress(common.Address(from), nonce)))
+}
@@ -0,0 +1,192 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/jsonrpc"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+)
+
+// SignTxArgs 是 account_signTransaction 的请求参数，与 clef 的 SendTxArgs 兼容
+type SignTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice hexutil.Big     `json:"gasPrice"`
+	Value    hexutil.Big     `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     *hexutil.Bytes  `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId,omitempty"`
+}
+
+// SignTxResult 是 account_signTransaction 的返回值
+// Raw 为RLP编码的签名交易
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// ExternalSigner 是通过 JSON-RPC 调用外部签名服务的 Signer
+// 外部签名服务需要实现 clef 风格的 account_list 和 account_signTransaction 接口，
+// 可以是 clef 本身，也可以是 NewSignerServer 启动的签名服务
+// 私钥只保存在签名服务中，当前进程不接触私钥
+type ExternalSigner struct {
+	rpc     *jsonrpc.Client
+	address web3.Address
+}
+
+// NewExternalSigner 新建一个连接到 endpoint 签名服务的 ExternalSigner
+// account 为使用的账户地址，为空时使用签名服务 account_list 返回的第一个账户
+func NewExternalSigner(endpoint string, account string) (*ExternalSigner, error) {
+	c, err := jsonrpc.NewClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s := &ExternalSigner{rpc: c}
+
+	if account != "" {
+		s.address = web3.HexToAddress(account)
+		return s, nil
+	}
+
+	var accounts []web3.Address
+	if err := c.Call("account_list", &accounts); err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errno.PrivateNotSet.Add("no account in external signer")
+	}
+	s.address = accounts[0]
+	return s, nil
+}
+
+func (s *ExternalSigner) Address() web3.Address {
+	return s.address
+}
+
+// SignHash clef 出于安全考虑不提供对任意hash的签名，因此 ExternalSigner 不支持该操作
+func (s *ExternalSigner) SignHash(hash []byte) ([]byte, error) {
+	return nil, errno.SignerNotSupport.Add("external signer does not sign raw hash")
+}
+
+func (s *ExternalSigner) SignTx(tx *web3.Transaction, chainID uint64) (*web3.Transaction, error) {
+	args := SignTxArgs{
+		From:     common.Address(s.address),
+		Gas:      hexutil.Uint64(tx.Gas),
+		GasPrice: hexutil.Big(*new(big.Int).SetUint64(tx.GasPrice)),
+		Nonce:    hexutil.Uint64(tx.Nonce),
+		ChainID:  (*hexutil.Big)(new(big.Int).SetUint64(chainID)),
+	}
+	if tx.To != nil {
+		to := common.Address(*tx.To)
+		args.To = &to
+	}
+	if tx.Value != nil {
+		args.Value = hexutil.Big(*tx.Value)
+	}
+	data := hexutil.Bytes(tx.Input)
+	args.Data = &data
+
+	var res SignTxResult
+	if err := s.rpc.Call("account_signTransaction", &res, args); err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+		return nil, err
+	}
+
+	// 签名服务返回的交易需要与请求的交易一致，且确实由当前账户签名
+	signer := types.NewEIP155Signer(new(big.Int).SetUint64(chainID))
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, errno.InvalidSignature.Add(err.Error())
+	}
+	if web3.Address(sender) != s.address {
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("signed by %s, expect %s", sender.Hex(), s.address))
+	}
+	// clef 允许用户在确认时修改交易，只有签名内容与请求完全一致时签名才能用于请求的交易
+	if !bytes.Equal(signer.Hash(signed).Bytes(), signHash(tx, chainID)) {
+		return nil, errno.InvalidSignature.Add("signed tx does not match request")
+	}
+
+	v, r, ss := signed.RawSignatureValues()
+	tx.V, tx.R, tx.S = v.Bytes(), r.Bytes(), ss.Bytes()
+	return tx, nil
+}
+
+// SignerService 使用 Signer 提供 clef 风格的 JSON-RPC 签名服务
+// 方法以 account 为命名空间注册，即 account_list 和 account_signTransaction
+type SignerService struct {
+	signer  Signer
+	approve func(SignTxArgs) error
+}
+
+// NewSignerServer 新建一个签名服务，返回值实现了 http.Handler，可直接用于 http.ListenAndServe
+// 通常在独立的进程或机器上使用 LocalSigner 运行，应用进程使用 ExternalSigner 连接
+// 签名服务本身不做身份认证，每笔交易签名前都会调用 approve，approve 返回错误时拒绝签名，
+// approve 相当于 clef 的人工确认或规则引擎，负责校验调用方与交易内容 (收款地址、金额、gas 等)，不能为空
+// 没有在 approve 或反向代理中认证调用方时，签名服务只能监听 127.0.0.1 等回环地址，否则任何能访问该端口的人都能使用私钥签名
+func NewSignerServer(signer Signer, approve func(SignTxArgs) error) (*rpc.Server, error) {
+	if approve == nil {
+		return nil, errno.Unauthorized.Add("approve of signer server not set")
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &SignerService{signer: signer, approve: approve}); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// List 返回签名服务管理的账户
+func (s *SignerService) List(ctx context.Context) ([]common.Address, error) {
+	return []common.Address{common.Address(s.signer.Address())}, nil
+}
+
+// SignTransaction 经 approve 确认后对交易签名
+func (s *SignerService) SignTransaction(ctx context.Context, args SignTxArgs, methodSelector *string) (*SignTxResult, error) {
+	if args.From != common.Address(s.signer.Address()) {
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("unknown account %s", args.From.Hex()))
+	}
+	if args.ChainID == nil {
+		return nil, errno.InvalidSignature.Add("chainId not set")
+	}
+	if err := s.approve(args); err != nil {
+		return nil, errno.Unauthorized.Add(err.Error())
+	}
+
+	tx := &web3.Transaction{
+		Nonce:    uint64(args.Nonce),
+		Gas:      uint64(args.Gas),
+		GasPrice: args.GasPrice.ToInt().Uint64(),
+		Value:    args.Value.ToInt(),
+	}
+	if args.To != nil {
+		to := web3.Address(*args.To)
+		tx.To = &to
+	}
+	if args.Data != nil {
+		tx.Input = *args.Data
+	}
+
+	signed, err := s.signer.SignTx(tx, args.ChainID.ToInt().Uint64())
+	if err != nil {
+		return nil, err
+	}
+	raw := marshalTx(signed)
+
+	decoded := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, decoded); err != nil {
+		return nil, err
+	}
+	return &SignTxResult{Raw: raw, Tx: decoded}, nil
+}
@@ -0,0 +1,144 @@
+package ethereum
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestSignerServer(t *testing.T, signer Signer, approve func(SignTxArgs) error) string {
+	rpcServer, err := NewSignerServer(signer, approve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(rpcServer)
+	t.Cleanup(func() {
+		server.Close()
+		rpcServer.Stop()
+	})
+	return server.URL
+}
+
+func testSignerTx() *web3.Transaction {
+	to := web3.HexToAddress("0x3535353535353535353535353535353535353535")
+	return &web3.Transaction{
+		Nonce:    9,
+		GasPrice: 20000000000,
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1000000000000000000),
+		Input:    []byte{0x01, 0x02},
+	}
+}
+
+func TestExternalSignerRoundTrip(t *testing.T) {
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := NewLocalSigner(private)
+	var approved []SignTxArgs
+	url := newTestSignerServer(t, local, func(args SignTxArgs) error {
+		approved = append(approved, args)
+		return nil
+	})
+
+	external, err := NewExternalSigner(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if external.Address() != local.Address() {
+		t.Fatalf("expected account %s, got %s", local.Address(), external.Address())
+	}
+
+	signed, err := external.SignTx(testSignerTx(), 56)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approved) != 1 || approved[0].To == nil || approved[0].To.Hex() != testSignerTx().To.String() {
+		t.Fatalf("expected the tx to be approved once, got %+v", approved)
+	}
+	expected, err := local.SignTx(testSignerTx(), 56)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(marshalTx(signed), marshalTx(expected)) {
+		t.Fatalf("expected signed tx %x, got %x", marshalTx(expected), marshalTx(signed))
+	}
+
+	from, err := RecoverSender(marshalTx(signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if web3.HexToAddress(from) != local.Address() {
+		t.Fatalf("expected sender %s, got %s", local.Address(), from)
+	}
+
+	if _, err := external.SignHash(make([]byte, 32)); !errors.Is(err, errno.SignerNotSupport) {
+		t.Fatalf("expected SignerNotSupport, got %v", err)
+	}
+}
+
+// editingSigner 模拟用户在 clef 中修改了交易的收款地址和金额后再签名
+type editingSigner struct {
+	Signer
+}
+
+func (s editingSigner) SignTx(tx *web3.Transaction, chainID uint64) (*web3.Transaction, error) {
+	to := web3.HexToAddress("0x0000000000000000000000000000000000000001")
+	tx.To = &to
+	tx.Value = big.NewInt(1)
+	return s.Signer.SignTx(tx, chainID)
+}
+
+func TestExternalSignerRejectsEditedTx(t *testing.T) {
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := newTestSignerServer(t, editingSigner{NewLocalSigner(private)}, func(SignTxArgs) error { return nil })
+
+	external, err := NewExternalSigner(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := external.SignTx(testSignerTx(), 56); !errors.Is(err, errno.InvalidSignature) {
+		t.Fatalf("expected InvalidSignature, got %v", err)
+	}
+}
+
+func TestSignerServerApprove(t *testing.T) {
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSignerServer(NewLocalSigner(private), nil); !errors.Is(err, errno.Unauthorized) {
+		t.Fatalf("expected Unauthorized without approve, got %v", err)
+	}
+
+	url := newTestSignerServer(t, NewLocalSigner(private), func(args SignTxArgs) error {
+		if args.Value.ToInt().Cmp(big.NewInt(1e17)) > 0 {
+			return errors.New("value exceeds 0.1 ether")
+		}
+		return nil
+	})
+	external, err := NewExternalSigner(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = external.SignTx(testSignerTx(), 56)
+	if err == nil || !strings.Contains(err.Error(), "value exceeds 0.1 ether") {
+		t.Fatalf("expected the tx to be rejected, got %v", err)
+	}
+	tx := testSignerTx()
+	tx.Value = big.NewInt(1e17)
+	if _, err := external.SignTx(tx, 56); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,13 +1,8 @@
 package ethereum
 
 import (
-	"crypto/ecdsa"
-	"encoding/hex"
 	"fmt"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mgintoki/go-web3"
-	"github.com/mgintoki/go-web3/abi"
-	"github.com/mgintoki/go-web3/jsonrpc"
 	"github.com/mgintoki/multichain/api/client"
 	"github.com/mgintoki/multichain/api/fee"
 	"github.com/mgintoki/multichain/api/provider"
@@ -16,10 +11,7 @@ import (
 	"github.com/mgintoki/multichain/common"
 	"github.com/mgintoki/multichain/errno"
 	"github.com/mgintoki/multichain/tools"
-	"github.com/umbracle/ethgo"
-	ej "github.com/umbracle/ethgo/jsonrpc"
 	"math/big"
-	"strings"
 	"time"
 )
 
@@ -28,8 +20,8 @@ const (
 )
 
 type Client struct {
-	provider *jsonrpc.Client
-	private  *ecdsa.PrivateKey
+	provider *RPCClient
+	signer   Signer
 	nodeUrl  string
 	ctb      *ContractTxBuilder
 	tb       *TxBuilder
@@ -45,7 +37,7 @@ func NewClient(provider provider.CommonProvider) (*Client, error) {
 }
 
 func (c *Client) SetProvider(provider provider.CommonProvider) (err error) {
-	web3Provider, err := jsonrpc.NewClient(provider.ProviderUrl)
+	web3Provider, err := NewRPCClient(provider)
 	if err != nil {
 		return err
 	}
@@ -65,18 +57,30 @@ func (c *Client) SetProvider(provider provider.CommonProvider) (err error) {
 }
 
 func (c *Client) SetPrivate(hexPrivate string) (err error) {
-	private, err := crypto.HexToECDSA(hexPrivate)
+	signer, err := NewLocalSignerFromHex(hexPrivate)
 	if err != nil {
 		return err
 	}
-	c.private = private
+	c.signer = signer
 	return nil
 }
+
+// SetSigner 设置 Client 使用的签名者
+// 与 SetPrivate 的作用相同，但私钥可以不出现在当前进程中，参考 ExternalSigner
+func (c *Client) SetSigner(signer Signer) {
+	c.signer = signer
+}
+
+// GetSigner 获取 Client 当前使用的签名者，未设置时返回nil
+func (c *Client) GetSigner() Signer {
+	return c.signer
+}
+
 func (c *Client) GetAccount() string {
-	if c.private == nil {
+	if c.signer == nil {
 		return ""
 	}
-	return crypto.PubkeyToAddress(c.private.PublicKey).String()
+	return checksumAddress(c.signer.Address())
 }
 
 func (c *Client) GetChainID() (string, error) {
@@ -94,8 +98,8 @@ func (c *Client) BalanceOf(address string, optionAsset *client.OptionAsset) (amo
 
 func (c *Client) Transfer(to string, amount *big.Int, optionAsset *client.OptionAsset, optionFee *fee.OptionFee) (txHash string, err error) {
 
-	if c.private == nil {
-		return "", fmt.Errorf("need private key")
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
 	}
 
 	txn, err := c.tb.BuildTx(txbuilder.BuildTxParam{
@@ -113,76 +117,63 @@ func (c *Client) Transfer(to string, amount *big.Int, optionAsset *client.Option
 }
 
 func (c *Client) QueryTx(txHash string, isWait bool) (txData *tx.TxData, err error) {
+	return c.QueryTxWithAbi(txHash, isWait, "")
+}
+
+// QueryTxWithAbi 查询交易详情，与 QueryTx 相同，abi 不为空时按照 abi 中的事件定义解析交易日志
+// abi 支持 json 与 human-readable 格式，可以同时包含多个合约的事件定义
+func (c *Client) QueryTxWithAbi(txHash string, isWait bool, abiStr string) (txData *tx.TxData, err error) {
 
 	if txHash == "0x0000000000000000000000000000000000000000000000000000000000000000" || txHash == "" {
 		return nil, fmt.Errorf("invalid tx hash:[%v]", txHash)
 	}
 
-	ethGoCli, err := ej.NewClient(c.nodeUrl)
-	if err != nil {
-		return nil, err
+	var contractAbi *ContractABI
+	if abiStr != "" {
+		contractAbi, err = ParseContractABI(abiStr)
+		if err != nil {
+			return nil, err
+		}
 	}
-	ethGoHash := ethgo.HexToHash(txHash)
 	web3Hash := web3.HexToHash(txHash)
 
 	ticker := time.NewTicker(time.Second * 5)
 	defer ticker.Stop()
 
-	txData = &tx.TxData{
-		TxHash: txHash,
-		Status: common.TxStatusPending,
-	}
+	var receipt *rpcReceipt
 	for {
-		receipt, err := ethGoCli.Eth().GetTransactionReceipt(ethGoHash)
+		receipt, err = c.getReceipt(web3Hash)
 		if err != nil {
-			if err.Error() == "not found" {
-
-			} else {
-				//todo 以太坊不同版本返回的txIndex分别是uint64和string,临时这样处理错误，后面改以太坊环境测试
-				if err.Error() == "field 'removed' not found" && receipt.BlockNumber != 0 && receipt.TransactionIndex == 0 {
-					txData.Status = common.TxStatusSuccess
-				} else {
-					return nil, err
-				}
-			}
-		}
-		if receipt != nil {
-			if receipt.Status == 1 {
-				txData.Status = common.TxStatusSuccess
-			} else {
-				txData.Status = common.TxStatusFailed
-			}
-			txData.ContractAddress = receipt.ContractAddress.String()
-			txData.GasUsed = receipt.GasUsed
-		} else {
-			txData.Status = common.TxStatusPending
+			return nil, err
 		}
-
-		if !isWait {
-			if txData.Status == common.TxStatusPending {
-				return txData, nil
-			} else {
-				web3Tx, err := c.provider.Eth().GetTransactionByHash(web3Hash)
-				if err != nil {
-					return nil, err
-				}
-				convertTxInfo(txData, *web3Tx)
-				return txData, nil
-			}
+		if receipt != nil || !isWait {
+			break
 		}
+		<-ticker.C
+	}
 
-		if txData.Status != common.TxStatusPending {
-			web3Tx, err := c.provider.Eth().GetTransactionByHash(web3Hash)
-			if err != nil {
-				return nil, err
-			}
-			convertTxInfo(txData, *web3Tx)
-			return txData, nil
+	txData = &tx.TxData{
+		TxHash: txHash,
+		Status: common.TxStatusPending,
+	}
+	if receipt == nil {
+		if err := c.fillPendingTx(txData, web3Hash); err != nil {
+			return nil, err
 		}
+		return txData, nil
+	}
 
-		<-ticker.C
-
+	web3Tx, err := c.provider.Eth().GetTransactionByHash(web3Hash)
+	if err != nil {
+		return nil, err
+	}
+	if web3Tx != nil {
+		convertTxInfo(txData, *web3Tx)
+	}
+	if err := c.fillReceipt(txData, receipt, contractAbi); err != nil {
+		return nil, err
 	}
+	return txData, nil
 }
 
 func convertTxInfo(txData *tx.TxData, web3Tx web3.Transaction) {
@@ -190,6 +181,8 @@ func convertTxInfo(txData *tx.TxData, web3Tx web3.Transaction) {
 	txData.From = web3Tx.From.String()
 	if web3Tx.To != nil {
 		txData.To = web3Tx.To.String()
+	} else {
+		txData.ContractAddress = createAddress(web3Tx.From, web3Tx.Nonce)
 	}
 
 	txData.Data = web3Tx.Input
@@ -203,8 +196,8 @@ func convertTxInfo(txData *tx.TxData, web3Tx web3.Transaction) {
 
 func (c *Client) SendTx(tx tx.Tx, feeOption *fee.OptionFee) (txHash string, err error) {
 
-	if c.private == nil {
-		return "", fmt.Errorf("need private key")
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
 	}
 
 	t, ok := tx.(*Txn)
@@ -212,7 +205,7 @@ func (c *Client) SendTx(tx tx.Tx, feeOption *fee.OptionFee) (txHash string, err
 		return "", errno.InvalidTxType
 	}
 
-	t.From = web3.HexToAddress(crypto.PubkeyToAddress(c.private.PublicKey).String())
+	t.From = c.signer.Address()
 
 	var gasLimit, gasPrice uint64
 
@@ -223,9 +216,13 @@ func (c *Client) SendTx(tx tx.Tx, feeOption *fee.OptionFee) (txHash string, err
 
 	if gasPrice != 0 {
 		t.GasPrice = gasPrice
+	} else if feeOption != nil && feeOption.Speed != "" {
+		if err := c.tb.feeOracle().setFee(t, feeOption.Speed); err != nil {
+			return "", err
+		}
 	} else {
 		if t.GasPrice == 0 {
-			t.GasPrice, err = c.provider.Eth().GasPrice()
+			t.GasPrice, err = c.tb.feeOracle().GasPrice("")
 			if err != nil {
 				return "", err
 			}
@@ -257,11 +254,11 @@ func (c *Client) SendTx(tx tx.Tx, feeOption *fee.OptionFee) (txHash string, err
 		return "", err
 	}
 
-	if err := t.SignTx(hex.EncodeToString(crypto.FromECDSA(c.private)), chainID); err != nil {
+	if err := t.SignWithSigner(c.signer, chainID); err != nil {
 		return "", err
 	}
 
-	return c.SendSignedTx(t)
+	return c.sendSignedTx(t, chainID)
 }
 
 func (c *Client) SendSignedTx(signedTx tx.Tx) (txHash string, err error) {
@@ -271,28 +268,55 @@ func (c *Client) SendSignedTx(signedTx tx.Tx) (txHash string, err error) {
 		return "", errno.InvalidTxType
 	}
 
+	chainID, err := c.GetChainID()
+	if err != nil {
+		return "", err
+	}
+
+	return c.sendSignedTx(t, chainID)
+}
+
+// sendSignedTx 在广播之前校验签名，避免签名不规范、链ID不符或签名者不符的交易发送到链上才失败
+func (c *Client) sendSignedTx(t *Txn, chainID string) (txHash string, err error) {
+
+	if err := t.VerifySignature(chainID); err != nil {
+		return "", err
+	}
+
 	web3Hash, err := c.provider.Eth().SendRawTransaction(t.SignedTx)
 	if err != nil {
 		return "", err
-	} else {
-		return web3Hash.String(), nil
 	}
+	// 广播后才能使用 Wait 等待交易上链
+	t.sent = true
+	if t.Provider == nil {
+		t.Provider = c.provider
+	}
+	return web3Hash.String(), nil
 
 }
 
+// SuggestFee 使用费用预言机返回 slow、standard、fast 三个档位的推荐费用，参考 FeeOracle
+func (c *Client) SuggestFee() (*fee.Recommendation, error) {
+	return c.tb.feeOracle().Suggest()
+}
+
 func (c *Client) QueryContract(req client.CallContractParam) (res *client.CallContractRes, err error) {
-	abiIns, err := abi.NewABI(req.Abi)
+	abiIns, err := ParseContractABI(req.Abi)
+	if err != nil {
+		return nil, err
+	}
+	method, err := abiIns.Method(req.CalledFunc)
 	if err != nil {
 		return nil, err
 	}
-	contractIns := NewContract(web3.HexToAddress(req.ContractAddress), abiIns, c.provider)
+	contractIns := NewContract(web3.HexToAddress(req.ContractAddress), abiIns.ABI, c.provider)
 	from := req.From
 	if from == "" {
 		from = DefaultAddress
 	}
 	contractIns.SetFrom(web3.HexToAddress(from))
-	calledFunc := strings.Trim(req.CalledFunc, "()")
-	rawRes, contractRes, err := contractIns.Call(calledFunc, web3.Latest, req.Params...)
+	rawRes, contractRes, err := contractIns.CallMethod(method, web3.Latest, req.Params...)
 	if err != nil {
 		return nil, err
 	} else {
@@ -312,12 +336,15 @@ func (c *Client) EstimateGas(tx tx.Tx) (feeRes *fee.OptionFee, err error) {
 
 	var gasPrice, gasLimit uint64
 
-	gasPrice, err = c.provider.Eth().GasPrice()
+	gasPrice, err = c.tb.feeOracle().GasPrice("")
 	if err != nil {
 		return nil, err
 	}
 	//如果目标地址为空，说明为部署合约交易,否则为普通交易类型
 	gasLimit, err = ethTx.EstimateGas()
+	if err != nil {
+		return nil, err
+	}
 
 	feeRes = &fee.OptionFee{
 		GasPrice: gasPrice,
@@ -0,0 +1,334 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/multichain/errno"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CoerceArgs 按照方法参数的 abi 类型转换参数，使其可以被 abi 编码
+// 除了 abi 编码本身支持的 Go 类型外，还支持:
+//
+//	address          16进制字符串 (大小写混合时校验 EIP-55 checksum)、[20]byte、common.Address
+//	uintN/intN       10进制或0x开头的16进制字符串、json.Number、整数类型、整数值的 float64、*big.Int
+//	bool             bool、"true"/"false"
+//	bytes/bytesN     0x开头的16进制字符串、[]byte，bytesN 的长度必须一致
+//	T[]/T[k]         任意切片或数组、json数组字符串，元素递归转换
+//	tuple            以参数名为key的 map、按顺序的切片、json字符串，字段递归转换
+//
+// 转换失败时返回的错误中包含参数的位置、名称和类型
+func CoerceArgs(inputs *abi.Type, args []interface{}) ([]interface{}, error) {
+	elems := inputs.TupleElems()
+	if len(args) != len(elems) {
+		return nil, errno.InvalidContractArg.Add(fmt.Sprintf("expect %d arguments, got %d", len(elems), len(args)))
+	}
+	coerced := make([]interface{}, len(args))
+	for i, elem := range elems {
+		v, err := coerceValue(elem.Elem, args[i])
+		if err != nil {
+			name := elem.Name
+			if name == "" {
+				name = "#" + strconv.Itoa(i)
+			}
+			return nil, errno.InvalidContractArg.Add(fmt.Sprintf("argument %d (%s %s): %v", i, elem.Elem, name, err))
+		}
+		coerced[i] = v
+	}
+	return coerced, nil
+}
+
+func coerceValue(t *abi.Type, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("value is nil")
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, fmt.Errorf("value is a nil %T", value)
+	}
+	switch t.Kind() {
+	case abi.KindAddress:
+		return coerceAddress(value)
+	case abi.KindUInt, abi.KindInt:
+		return coerceInteger(t, value)
+	case abi.KindBool:
+		return coerceBool(value)
+	case abi.KindString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case abi.KindBytes:
+		return coerceBytes(value)
+	case abi.KindFixedBytes:
+		b, err := coerceBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != t.Size() {
+			return nil, fmt.Errorf("expect %d bytes, got %d", t.Size(), len(b))
+		}
+		v := reflect.New(t.GoType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.KindSlice, abi.KindArray:
+		return coerceList(t, value)
+	case abi.KindTuple:
+		return coerceTuple(t, value)
+	}
+
+	if reflect.TypeOf(value).AssignableTo(t.GoType()) {
+		return value, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as %s", value, t)
+}
+
+func coerceAddress(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case web3.Address:
+		return v, nil
+	case *web3.Address:
+		return *v, nil
+	case common.Address:
+		return web3.Address(v), nil
+	case [20]byte:
+		return web3.Address(v), nil
+	case string:
+		if !common.IsHexAddress(v) {
+			return nil, fmt.Errorf("invalid address %q", v)
+		}
+		hexPart := strings.TrimPrefix(strings.TrimPrefix(v, "0x"), "0X")
+		if hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart) {
+			if common.HexToAddress(v).Hex() != "0x"+hexPart {
+				return nil, fmt.Errorf("invalid checksum of address %q", v)
+			}
+		}
+		return web3.Address(common.HexToAddress(v)), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as address", value)
+}
+
+func coerceInteger(t *abi.Type, value interface{}) (interface{}, error) {
+	var i *big.Int
+	switch v := value.(type) {
+	case *big.Int:
+		i = v
+	case big.Int:
+		i = &v
+	case string:
+		s := strings.TrimSpace(v)
+		neg := strings.HasPrefix(s, "-")
+		parsed, err := parseTypedIntegerString(strings.TrimPrefix(s, "-"))
+		if err != nil {
+			return nil, err
+		}
+		if neg {
+			parsed.Neg(parsed)
+		}
+		i = parsed
+	case json.Number:
+		parsed, ok := new(big.Int).SetString(v.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", v)
+		}
+		i = parsed
+	case float64:
+		// 超过 2^53 的 float64 已经丢失精度，例如 float64(1e18+1) 与 1e18 相同
+		if math.Abs(v) > 1<<53 {
+			return nil, fmt.Errorf("float %v exceeds 2^53 and may have lost precision, use a string or json.Number", v)
+		}
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		i = big.NewInt(int64(v))
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = big.NewInt(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			i = new(big.Int).SetUint64(rv.Uint())
+		default:
+			return nil, fmt.Errorf("cannot use %T as %s", value, t)
+		}
+	}
+
+	bits := uint(t.Size())
+	min, max := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), bits)
+	if t.Kind() == abi.KindInt {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if i.Cmp(min) < 0 || i.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("value %s out of range for %s", i, t)
+	}
+
+	target := t.GoType()
+	if target == reflect.TypeOf(i) {
+		return new(big.Int).Set(i), nil
+	}
+	v := reflect.New(target).Elem()
+	if t.Kind() == abi.KindInt {
+		v.SetInt(i.Int64())
+	} else {
+		v.SetUint(i.Uint64())
+	}
+	return v.Interface(), nil
+}
+
+func coerceBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return nil, fmt.Errorf("cannot use %T as bool", value)
+}
+
+func coerceBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, "0x") && !strings.HasPrefix(v, "0X") {
+			return nil, fmt.Errorf("bytes must be 0x prefixed hex, got %q", v)
+		}
+		return hex.DecodeString(v[2:])
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as bytes", value)
+}
+
+func coerceList(t *abi.Type, value interface{}) (interface{}, error) {
+	value, err := decodeJSONArg(value)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot use %T as %s", value, t)
+	}
+
+	var list reflect.Value
+	if t.Kind() == abi.KindArray {
+		if rv.Len() != t.Size() {
+			return nil, fmt.Errorf("expect %d items, got %d", t.Size(), rv.Len())
+		}
+		list = reflect.New(t.GoType()).Elem()
+	} else {
+		list = reflect.MakeSlice(t.GoType(), rv.Len(), rv.Len())
+	}
+	for i := 0; i < rv.Len(); i++ {
+		item, err := coerceValue(t.Elem(), rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %v", i, err)
+		}
+		list.Index(i).Set(reflect.ValueOf(item))
+	}
+	return list.Interface(), nil
+}
+
+func coerceTuple(t *abi.Type, value interface{}) (interface{}, error) {
+	value, err := decodeJSONArg(value)
+	if err != nil {
+		return nil, err
+	}
+	elems := t.TupleElems()
+	rv := reflect.ValueOf(value)
+
+	names := make([]string, len(elems))
+	for i, elem := range elems {
+		names[i] = elem.Name
+		if names[i] == "" {
+			names[i] = strconv.Itoa(i)
+		}
+	}
+
+	fields := make([]interface{}, len(elems))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() != len(elems) {
+			return nil, fmt.Errorf("expect %d fields, got %d", len(elems), rv.Len())
+		}
+		for i := range elems {
+			fields[i] = rv.Index(i).Interface()
+		}
+	case reflect.Map:
+		for i, name := range names {
+			field := rv.MapIndex(reflect.ValueOf(name))
+			if !field.IsValid() {
+				return nil, fmt.Errorf("missing field %s", name)
+			}
+			fields[i] = field.Interface()
+		}
+	case reflect.Ptr:
+		if rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("cannot use %T as %s", value, t)
+		}
+		return coerceTuple(t, rv.Elem().Interface())
+	case reflect.Struct:
+		// 结构体字段按照 abi 标签匹配，没有标签时按字段名匹配 (不区分大小写)，mcbind 生成的结构体带有 abi 标签
+		for i, name := range names {
+			field, ok := structField(rv, name)
+			if !ok {
+				return nil, fmt.Errorf("missing field %s", name)
+			}
+			fields[i] = field.Interface()
+		}
+	default:
+		return nil, fmt.Errorf("cannot use %T as %s", value, t)
+	}
+
+	// 与 abi 的 GoType 一致，tuple 转换为以字段名为key的map，匿名字段使用其序号，tuple[] 等列表才能容纳转换结果
+	res := make(map[string]interface{}, len(elems))
+	for i, elem := range elems {
+		v, err := coerceValue(elem.Elem, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf(".%s: %v", names[i], err)
+		}
+		res[names[i]] = v
+	}
+	return res, nil
+}
+
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if f := typ.Field(i); f.PkgPath == "" && f.Tag.Get("abi") == name {
+			return rv.Field(i), true
+		}
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if f := typ.Field(i); f.PkgPath == "" && f.Tag.Get("abi") == "" && strings.EqualFold(f.Name, name) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// decodeJSONArg 将json字符串解析为数组或对象，数字保存为 json.Number
+func decodeJSONArg(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid json %q: %v", s, err)
+	}
+	return v, nil
+}
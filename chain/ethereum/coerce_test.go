@@ -0,0 +1,134 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const coerceTestABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"mixed","inputs":[
+		{"name":"flag","type":"bool"},
+		{"name":"delta","type":"int8"},
+		{"name":"ids","type":"uint64[]"},
+		{"name":"key","type":"bytes4"},
+		{"name":"order","type":"tuple","components":[{"name":"maker","type":"address"},{"name":"amounts","type":"uint256[2]"}]}
+	],"outputs":[]},
+	{"type":"function","name":"batch","inputs":[{"name":"points","type":"tuple[]","components":[{"name":"x","type":"uint256"},{"name":"","type":"address"}]}],"outputs":[]}
+]`
+
+func TestCoerceArgs(t *testing.T) {
+	to := "0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c"
+
+	typed, err := EncodeInvokeData(coerceTestABI, "transfer", []interface{}{web3.HexToAddress(to), big.NewInt(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, amount := range []interface{}{"1000", "0x3e8", json.Number("1000"), float64(1000), 1000, uint16(1000)} {
+		data, err := EncodeInvokeData(coerceTestABI, "transfer", []interface{}{to, amount})
+		if err != nil {
+			t.Fatalf("%T: %v", amount, err)
+		}
+		if !bytes.Equal(data, typed) {
+			t.Fatalf("%T: calldata mismatch", amount)
+		}
+	}
+
+	var params []interface{}
+	d := json.NewDecoder(strings.NewReader(`[true, "-5", [1, "0x2"], "0xdeadbeef", {"maker": "` + to + `", "amounts": ["1", 2]}]`))
+	d.UseNumber()
+	if err := d.Decode(&params); err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := EncodeInvokeData(coerceTestABI, "mixed", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromString, err := EncodeInvokeData(coerceTestABI, "mixed", []interface{}{
+		"true", -5, `[1, 2]`, [4]byte{0xde, 0xad, 0xbe, 0xef}, `["` + to + `", ["1", "2"]]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fromJSON, fromString) {
+		t.Fatal("calldata mismatch")
+	}
+
+	m := abi.MustNewABI(coerceTestABI).Methods["mixed"]
+	decoded, err := abi.Decode(m.Inputs, fromJSON[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := decoded.(map[string]interface{})
+	if res["delta"].(int8) != -5 || len(res["ids"].([]uint64)) != 2 {
+		t.Fatalf("unexpected decoded %v", res)
+	}
+}
+
+func TestCoerceTupleList(t *testing.T) {
+	to := "0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c"
+
+	fromJSON, err := EncodeInvokeData(coerceTestABI, "batch", []interface{}{`[{"x":"1","1":"` + to + `"},{"x":2,"1":"` + to + `"}]`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromList, err := EncodeInvokeData(coerceTestABI, "batch", []interface{}{[]interface{}{[]interface{}{1, to}, []interface{}{"0x2", to}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mcbind 生成的结构体，匿名字段使用序号作为 abi 标签
+	type point struct {
+		X     *big.Int     `abi:"x"`
+		Owner web3.Address `abi:"1"`
+	}
+	fromStruct, err := EncodeInvokeData(coerceTestABI, "batch", []interface{}{
+		[]point{{big.NewInt(1), web3.HexToAddress(to)}, {big.NewInt(2), web3.HexToAddress(to)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fromJSON, fromList) || !bytes.Equal(fromJSON, fromStruct) {
+		t.Fatal("calldata mismatch")
+	}
+
+	m := abi.MustNewABI(coerceTestABI).Methods["batch"]
+	decoded, err := abi.Decode(m.Inputs, fromJSON[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := decoded.(map[string]interface{})["points"].([]map[string]interface{})
+	if len(points) != 2 || points[1]["x"].(*big.Int).Int64() != 2 || points[1]["1"].(web3.Address) != web3.HexToAddress(to) {
+		t.Fatalf("unexpected decoded %v", points)
+	}
+}
+
+func TestCoerceArgsError(t *testing.T) {
+	cases := []struct {
+		method string
+		params []interface{}
+		expect string
+	}{
+		{"transfer", []interface{}{"0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3C", "1"}, "argument 0 (address to): invalid checksum"},
+		{"transfer", []interface{}{"0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c", "-1"}, "argument 1 (uint256 amount): value -1 out of range"},
+		{"transfer", []interface{}{"0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c"}, "expect 2 arguments, got 1"},
+		{"mixed", []interface{}{true, "128", "[]", "0x00000000", "[]"}, "argument 1 (int8 delta): value 128 out of range"},
+		{"mixed", []interface{}{true, 0, "[]", "0x00", "[]"}, "argument 3 (bytes4 key): expect 4 bytes, got 1"},
+		{"mixed", []interface{}{true, 0, "[1, 1.5]", "0x00000000", "[]"}, "argument 2 (uint64[] ids): [1]: invalid integer 1.5"},
+		{"mixed", []interface{}{true, 0, "[]", "0x00000000", `{"maker": "0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c"}`}, "missing field amounts"},
+		{"transfer", []interface{}{"0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c", (*big.Int)(nil)}, "argument 1 (uint256 amount): value is a nil *big.Int"},
+		{"transfer", []interface{}{(*web3.Address)(nil), "1"}, "argument 0 (address to): value is a nil *web3.Address"},
+		{"transfer", []interface{}{"0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c", float64(1e18 + 1)}, "exceeds 2^53"},
+		{"batch", []interface{}{`[{"x":"1"}]`}, "points): [0]: missing field 1"},
+	}
+	for _, c := range cases {
+		_, err := EncodeInvokeData(coerceTestABI, c.method, c.params)
+		if err == nil || !strings.Contains(err.Error(), c.expect) {
+			t.Fatalf("expect error %q, got %v", c.expect, err)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/mgintoki/go-web3"
 	"github.com/mgintoki/go-web3/abi"
-	"github.com/mgintoki/go-web3/jsonrpc"
 )
 
 const (
@@ -17,11 +16,11 @@ type Contract struct {
 	Addr     web3.Address
 	From     *web3.Address
 	Abi      *abi.ABI
-	Provider *jsonrpc.Client
+	Provider *RPCClient
 }
 
 // NewContract creates a new contract instance
-func NewContract(addr web3.Address, abi *abi.ABI, provider *jsonrpc.Client) *Contract {
+func NewContract(addr web3.Address, abi *abi.ABI, provider *RPCClient) *Contract {
 	return &Contract{
 		Addr:     addr,
 		Abi:      abi,
@@ -50,8 +49,16 @@ func (c *Contract) Call(method string, block web3.BlockNumber, args ...interface
 	if !ok {
 		return "", nil, fmt.Errorf("method %s not found", method)
 	}
+	return c.CallMethod(m, block, args...)
+}
 
+// CallMethod calls the given method in the contract, it is used to call overloaded methods
+func (c *Contract) CallMethod(m *abi.Method, block web3.BlockNumber, args ...interface{}) (string, map[string]interface{}, error) {
 	// Encode input
+	args, err := CoerceArgs(m.Inputs, args)
+	if err != nil {
+		return "", nil, err
+	}
 	data, err := abi.Encode(args, m.Inputs)
 	if err != nil {
 		return "", nil, err
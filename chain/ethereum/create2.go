@@ -0,0 +1,138 @@
+package ethereum
+
+import (
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"strings"
+)
+
+// DeterministicDeployer 是 deterministic-deployment-proxy 的地址
+// 该合约在 Ethereum、BSC、OKC 等链上地址相同，calldata 为 salt ‖ initcode，返回部署的合约地址
+const DeterministicDeployer = "0x4e59b44847b379578588920cA78FbF26c0B4956C"
+
+// BuildCreate2DeployTxReq 是通过 CREATE2 工厂合约部署合约的参数
+// 相同的工厂合约、salt 与 initcode (字节码 + 构造函数参数) 在任意链上部署得到的合约地址相同
+type BuildCreate2DeployTxReq struct {
+	From     string        // 交易的发起方，不影响部署得到的合约地址
+	Abi      string        // 智能合约的 abi，用于编码构造函数参数
+	ByteCode string        // 智能合约编译后得到的16进制字节码
+	Params   []interface{} // 部署合约的参数
+	Salt     string        // 16进制的 salt，不足32字节时左侧补0
+	Factory  string        // 可选，CREATE2 工厂合约地址，不传则使用 DeterministicDeployer
+	// 可选，工厂合约的部署方法签名，参数必须为 (bytes32 salt, bytes initCode)，如 deploy(bytes32,bytes)
+	// 不传则使用 DeterministicDeployer 的 calldata 格式 salt ‖ initcode
+	FactoryMethod string
+	Nonce         uint64   // 可选，不传则内部计算nonce
+	Value         *big.Int // 可选，部署时转入合约的原生币数量
+	GasLimit      uint64   // 可选，不传则内部计算推荐值并使用
+	GasPrice      uint64   // 可选，不传则内部计算推荐值并使用
+}
+
+// Create2Address 计算 CREATE2 部署得到的合约地址
+// keccak256(0xff ‖ factory ‖ salt ‖ keccak256(initCode))[12:]
+func Create2Address(factory web3.Address, salt web3.Hash, initCode []byte) web3.Address {
+	var addr web3.Address
+	copy(addr[:], crypto.Keccak256([]byte{0xff}, factory[:], salt[:], crypto.Keccak256(initCode))[12:])
+	return addr
+}
+
+// PredictCreate2Address 计算按照 req 部署得到的合约地址，不需要访问链
+func PredictCreate2Address(req BuildCreate2DeployTxReq) (web3.Address, error) {
+	factory, salt, initCode, err := req.parse()
+	if err != nil {
+		return web3.Address{}, err
+	}
+	return Create2Address(factory, salt, initCode), nil
+}
+
+// BuildCreate2DeployTx 构建通过 CREATE2 工厂合约部署合约的交易，并返回部署得到的合约地址
+// 工厂合约在当前链上不存在，或者该地址上已经部署了合约时返回错误
+func (b *ContractTxBuilder) BuildCreate2DeployTx(req BuildCreate2DeployTxReq) (tx.Tx, web3.Address, error) {
+	factory, salt, initCode, err := req.parse()
+	if err != nil {
+		return nil, web3.Address{}, err
+	}
+	addr := Create2Address(factory, salt, initCode)
+
+	code, err := b.provider.Eth().GetCode(factory)
+	if err != nil {
+		return nil, web3.Address{}, err
+	}
+	if code == "0x" || code == "" {
+		return nil, web3.Address{}, errno.FactoryNotDeployed.Add(factory.String())
+	}
+	code, err = b.provider.Eth().GetCode(addr)
+	if err != nil {
+		return nil, web3.Address{}, err
+	}
+	if code != "0x" && code != "" {
+		return nil, addr, errno.AlreadyDeployed.Add(addr.String())
+	}
+
+	var data []byte
+	if req.FactoryMethod == "" {
+		data = append(salt[:], initCode...)
+	} else {
+		m, err := parseMethodFragment(req.FactoryMethod)
+		if err != nil {
+			return nil, web3.Address{}, errno.InvalidABI.Add(fmt.Sprintf("%q: %v", req.FactoryMethod, err))
+		}
+		args, err := abi.Encode([]interface{}{salt, initCode}, m.Inputs)
+		if err != nil {
+			return nil, web3.Address{}, errno.InvalidABI.Add(fmt.Sprintf("%q: %v", req.FactoryMethod, err))
+		}
+		data = append(m.ID(), args...)
+	}
+
+	txn, err := b.txBuilder().BuildTx(txbuilder.BuildTxParam{
+		From:     req.From,
+		To:       factory.String(),
+		Payload:  data,
+		Nonce:    req.Nonce,
+		Value:    req.Value,
+		GasPrice: req.GasPrice,
+		GasLimit: req.GasLimit,
+	})
+	if err != nil {
+		return nil, web3.Address{}, err
+	}
+	return txn, addr, nil
+}
+
+func (req *BuildCreate2DeployTxReq) parse() (web3.Address, web3.Hash, []byte, error) {
+	factory := req.Factory
+	if factory == "" {
+		factory = DeterministicDeployer
+	}
+	if !common.IsHexAddress(factory) {
+		return web3.Address{}, web3.Hash{}, nil, errno.InvalidContractArg.Add("invalid factory address " + factory)
+	}
+
+	var salt web3.Hash
+	saltStr := req.Salt
+	if !strings.HasPrefix(saltStr, "0x") {
+		saltStr = "0x" + saltStr
+	}
+	if len(saltStr)%2 == 1 {
+		saltStr = "0x0" + saltStr[2:]
+	}
+	b, err := hexutil.Decode(saltStr)
+	if err != nil || len(b) > 32 {
+		return web3.Address{}, web3.Hash{}, nil, errno.InvalidContractArg.Add(fmt.Sprintf("invalid salt %q", req.Salt))
+	}
+	copy(salt[32-len(b):], b)
+
+	initCode, err := EncodeDeployData(req.Abi, req.ByteCode, req.Params)
+	if err != nil {
+		return web3.Address{}, web3.Hash{}, nil, err
+	}
+	return web3.HexToAddress(factory), salt, initCode, nil
+}
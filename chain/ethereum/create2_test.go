@@ -0,0 +1,109 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/api/provider"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreate2Address(t *testing.T) {
+	// EIP-1014 中的示例
+	cases := []struct {
+		factory, salt, initCode, expect string
+	}{
+		{"0x0000000000000000000000000000000000000000", "0x00", "00", "0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x00", "00", "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x000000000000000000000000feed000000000000000000000000000000000000", "00", "0xD04116cDd17beBE565EB2422F2497E06cC1C9833"},
+		{"0x00000000000000000000000000000000deadbeef", "0xcafebabe", "deadbeef", "0x60f3f640a8508fC6a86d45DF051962668E1e8AC7"},
+	}
+	for _, c := range cases {
+		addr, err := PredictCreate2Address(BuildCreate2DeployTxReq{Factory: c.factory, Salt: c.salt, ByteCode: c.initCode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr != web3.HexToAddress(c.expect) {
+			t.Fatalf("expect %s, got %s", c.expect, addr)
+		}
+	}
+	if _, err := PredictCreate2Address(BuildCreate2DeployTxReq{Salt: "0x" + strings.Repeat("00", 33)}); err == nil {
+		t.Fatal("expect invalid salt")
+	}
+}
+
+func TestBuildCreate2DeployTx(t *testing.T) {
+	req := BuildCreate2DeployTxReq{
+		From:     "0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c",
+		Abi:      `constructor(address owner)`,
+		ByteCode: "0x6080604052",
+		Params:   []interface{}{"0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c"},
+		Salt:     "0x01",
+		Nonce:    1,
+		GasLimit: 100000,
+		GasPrice: 1,
+	}
+	predicted, err := PredictCreate2Address(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var rpcReq struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &rpcReq); err != nil {
+			t.Fatal(err)
+		}
+		result := `"0x1"`
+		if rpcReq.Method == "eth_getCode" {
+			result = `"0x"`
+			switch web3.HexToAddress(rpcReq.Params[0].(string)) {
+			case web3.HexToAddress(DeterministicDeployer):
+				result = `"0x7fff"`
+			case predicted:
+				if deployed {
+					result = `"0x6080"`
+				}
+			}
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+	}))
+	defer server.Close()
+
+	builder, err := NewContractTxBuilder(provider.CommonProvider{ProviderUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, addr, err := builder.BuildCreate2DeployTx(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != predicted {
+		t.Fatalf("expect %s, got %s", predicted, addr)
+	}
+	ethTx := txn.(*Txn)
+	initCode, _ := EncodeDeployData(req.Abi, req.ByteCode, req.Params)
+	salt, _ := hex.DecodeString(strings.Repeat("00", 31) + "01")
+	if *ethTx.Addr != web3.HexToAddress(DeterministicDeployer) || !bytes.Equal(ethTx.Data, append(salt, initCode...)) {
+		t.Fatalf("unexpected tx to %s data %x", ethTx.Addr, ethTx.Data)
+	}
+
+	req.Factory = "0x00000000000000000000000000000000deadbeef"
+	if _, _, err := builder.BuildCreate2DeployTx(req); err == nil || !strings.Contains(err.Error(), "factory not deployed") {
+		t.Fatalf("expect factory not deployed, got %v", err)
+	}
+	req.Factory = ""
+	deployed = true
+	if _, _, err := builder.BuildCreate2DeployTx(req); err == nil || !strings.Contains(err.Error(), "already deployed") {
+		t.Fatalf("expect already deployed, got %v", err)
+	}
+}
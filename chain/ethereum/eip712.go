@@ -0,0 +1,469 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const eip712DomainType = "EIP712Domain"
+
+var typedArrayRegexp = regexp.MustCompile(`^(.*)\[(\d*)\]$`)
+
+// TypedDataField 是 EIP-712 结构体类型中的一个字段
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataDomain 是 EIP-712 的域，用于计算域分隔符 (domain separator)
+// 未设置的字段不参与计算
+type TypedDataDomain struct {
+	Name              string   `json:"name,omitempty"`
+	Version           string   `json:"version,omitempty"`
+	ChainID           *big.Int `json:"chainId,omitempty"`
+	VerifyingContract string   `json:"verifyingContract,omitempty"`
+	Salt              string   `json:"salt,omitempty"`
+}
+
+// UnmarshalJSON 兼容数字和字符串 (10进制或0x开头的16进制) 两种格式的 chainId
+func (d *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type domain TypedDataDomain
+	var dec struct {
+		domain
+		ChainID json.RawMessage `json:"chainId,omitempty"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*d = TypedDataDomain(dec.domain)
+	if len(dec.ChainID) != 0 && string(dec.ChainID) != "null" {
+		chainID, err := parseTypedIntegerString(strings.Trim(string(dec.ChainID), `"`))
+		if err != nil {
+			return err
+		}
+		d.ChainID = chainID
+	}
+	return nil
+}
+
+// TypedData 是 EIP-712 结构化数据，json格式与 eth_signTypedData_v4 的参数一致
+// Types 中可以不定义 EIP712Domain, 此时根据 Domain 中已设置的字段生成
+// Message 中的整数可以是 json数字、10进制或0x开头的16进制字符串、*big.Int 等，bytes 类型为0x开头的16进制字符串
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      TypedDataDomain             `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// ParseTypedData 解析 eth_signTypedData_v4 格式的json
+// 数字以 json.Number 保存，避免大整数丢失精度
+func ParseTypedData(typedDataJSON string) (*TypedData, error) {
+	d := json.NewDecoder(strings.NewReader(typedDataJSON))
+	d.UseNumber()
+	var td TypedData
+	if err := d.Decode(&td); err != nil {
+		return nil, errno.InvalidTypedData.Add(err.Error())
+	}
+	return &td, nil
+}
+
+// Hash 计算待签名的hash: keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func (td *TypedData) Hash() ([]byte, error) {
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	if td.PrimaryType == eip712DomainType {
+		return crypto.Keccak256([]byte("\x19\x01"), domainSeparator), nil
+	}
+	messageHash, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash), nil
+}
+
+// DomainSeparator 计算域分隔符 hashStruct(domain)
+func (td *TypedData) DomainSeparator() ([]byte, error) {
+	return td.HashStruct(eip712DomainType, td.domainMessage())
+}
+
+// HashStruct 计算结构体的hash: keccak256(typeHash ‖ encodeData(s))
+func (td *TypedData) HashStruct(primaryType string, data map[string]interface{}) ([]byte, error) {
+	encoded, err := td.EncodeData(primaryType, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash 计算类型的hash: keccak256(encodeType(primaryType))
+func (td *TypedData) TypeHash(primaryType string) []byte {
+	return crypto.Keccak256([]byte(td.EncodeType(primaryType)))
+}
+
+// EncodeType 将类型编码为 name ‖ "(" ‖ member₁ ‖ "," ‖ … ‖ memberₙ ")"
+// 引用到的结构体类型按名称排序后追加在后面
+func (td *TypedData) EncodeType(primaryType string) string {
+	deps := td.dependencies(primaryType, map[string]bool{})
+	delete(deps, primaryType)
+	sorted := make([]string, 0, len(deps))
+	for dep := range deps {
+		sorted = append(sorted, dep)
+	}
+	sort.Strings(sorted)
+
+	var buf strings.Builder
+	for _, t := range append([]string{primaryType}, sorted...) {
+		buf.WriteString(t)
+		buf.WriteString("(")
+		for i, field := range td.types()[t] {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(field.Type)
+			buf.WriteString(" ")
+			buf.WriteString(field.Name)
+		}
+		buf.WriteString(")")
+	}
+	return buf.String()
+}
+
+// EncodeData 将结构体编码为 typeHash ‖ enc(value₁) ‖ … ‖ enc(valueₙ)
+func (td *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := td.types()[primaryType]
+	if !ok {
+		return nil, errno.InvalidTypedData.Add(fmt.Sprintf("unknown type %s", primaryType))
+	}
+	if len(data) > len(fields) {
+		return nil, errno.InvalidTypedData.Add(fmt.Sprintf("extra data provided for type %s", primaryType))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(td.TypeHash(primaryType))
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, errno.InvalidTypedData.Add(fmt.Sprintf("missing value for field %s.%s", primaryType, field.Name))
+		}
+		encoded, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, errno.InvalidTypedData.Add(fmt.Sprintf("%s.%s: %v", primaryType, field.Name, err))
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// SignTypedData 使用 Signer 对 EIP-712 结构化数据签名
+// 返回 [R || S || V] 格式的65字节签名，V为27或28，与钱包 eth_signTypedData_v4 的返回一致
+func SignTypedData(signer Signer, td *TypedData) ([]byte, error) {
+	hash, err := td.Hash()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// RecoverTypedDataSigner 根据 EIP-712 结构化数据和签名恢复签名者地址
+func RecoverTypedDataSigner(td *TypedData, sig []byte) (string, error) {
+	hash, err := td.Hash()
+	if err != nil {
+		return "", err
+	}
+	addr, err := recoverSigner(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	return checksumAddress(addr), nil
+}
+
+// VerifyTypedData 校验签名是否由 address 对 EIP-712 结构化数据签出
+func VerifyTypedData(td *TypedData, sig []byte, address string) (bool, error) {
+	signer, err := RecoverTypedDataSigner(td, sig)
+	if err != nil {
+		return false, err
+	}
+	return web3.HexToAddress(signer) == web3.HexToAddress(address), nil
+}
+
+// SignTypedData 使用 Client 的签名者对 eth_signTypedData_v4 格式的json签名，返回16进制格式的签名
+func (c *Client) SignTypedData(typedDataJSON string) (hexSignature string, err error) {
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
+	}
+	td, err := ParseTypedData(typedDataJSON)
+	if err != nil {
+		return "", err
+	}
+	sig, err := SignTypedData(c.signer, td)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(sig), nil
+}
+
+// types 返回包含 EIP712Domain 定义的类型表
+func (td *TypedData) types() map[string][]TypedDataField {
+	if _, ok := td.Types[eip712DomainType]; ok {
+		return td.Types
+	}
+	types := make(map[string][]TypedDataField, len(td.Types)+1)
+	for k, v := range td.Types {
+		types[k] = v
+	}
+	var domain []TypedDataField
+	if td.Domain.Name != "" {
+		domain = append(domain, TypedDataField{Name: "name", Type: "string"})
+	}
+	if td.Domain.Version != "" {
+		domain = append(domain, TypedDataField{Name: "version", Type: "string"})
+	}
+	if td.Domain.ChainID != nil {
+		domain = append(domain, TypedDataField{Name: "chainId", Type: "uint256"})
+	}
+	if td.Domain.VerifyingContract != "" {
+		domain = append(domain, TypedDataField{Name: "verifyingContract", Type: "address"})
+	}
+	if td.Domain.Salt != "" {
+		domain = append(domain, TypedDataField{Name: "salt", Type: "bytes32"})
+	}
+	types[eip712DomainType] = domain
+	return types
+}
+
+func (td *TypedData) domainMessage() map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, field := range td.types()[eip712DomainType] {
+		switch field.Name {
+		case "name":
+			m[field.Name] = td.Domain.Name
+		case "version":
+			m[field.Name] = td.Domain.Version
+		case "chainId":
+			if td.Domain.ChainID != nil {
+				m[field.Name] = td.Domain.ChainID
+			}
+		case "verifyingContract":
+			m[field.Name] = td.Domain.VerifyingContract
+		case "salt":
+			m[field.Name] = td.Domain.Salt
+		}
+	}
+	return m
+}
+
+func (td *TypedData) dependencies(t string, found map[string]bool) map[string]bool {
+	t = baseType(t)
+	if found[t] {
+		return found
+	}
+	fields, ok := td.types()[t]
+	if !ok {
+		return found
+	}
+	found[t] = true
+	for _, field := range fields {
+		td.dependencies(field.Type, found)
+	}
+	return found
+}
+
+func (td *TypedData) encodeValue(t string, value interface{}) ([]byte, error) {
+	if m := typedArrayRegexp.FindStringSubmatch(t); m != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expect array for type %s, got %T", t, value)
+		}
+		if m[2] != "" {
+			if n, _ := strconv.Atoi(m[2]); n != len(items) {
+				return nil, fmt.Errorf("expect %d items for type %s, got %d", n, t, len(items))
+			}
+		}
+		var buf bytes.Buffer
+		for _, item := range items {
+			encoded, err := td.encodeValue(m[1], item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(encoded)
+		}
+		return crypto.Keccak256(buf.Bytes()), nil
+	}
+
+	if _, ok := td.types()[t]; ok {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expect object for type %s, got %T", t, value)
+		}
+		return td.HashStruct(t, m)
+	}
+
+	return encodePrimitiveValue(t, value)
+}
+
+func encodePrimitiveValue(t string, value interface{}) ([]byte, error) {
+	switch t {
+	case "address":
+		var addr common.Address
+		switch v := value.(type) {
+		case string:
+			if !common.IsHexAddress(v) {
+				return nil, fmt.Errorf("invalid address %s", v)
+			}
+			addr = common.HexToAddress(v)
+		case web3.Address:
+			addr = common.Address(v)
+		case common.Address:
+			addr = v
+		default:
+			return nil, fmt.Errorf("expect address, got %T", value)
+		}
+		return common.LeftPadBytes(addr.Bytes(), 32), nil
+	case "bool":
+		var b bool
+		switch v := value.(type) {
+		case bool:
+			b = v
+		case string:
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, err
+			}
+			b = parsed
+		default:
+			return nil, fmt.Errorf("expect bool, got %T", value)
+		}
+		if b {
+			return math.PaddedBigBytes(big.NewInt(1), 32), nil
+		}
+		return make([]byte, 32), nil
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expect string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(s)), nil
+	case "bytes":
+		b, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(b), nil
+	}
+
+	if strings.HasPrefix(t, "bytes") {
+		size, err := strconv.Atoi(strings.TrimPrefix(t, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid type %s", t)
+		}
+		b, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > size {
+			return nil, fmt.Errorf("expect at most %d bytes for type %s, got %d", size, t, len(b))
+		}
+		return common.RightPadBytes(b, 32), nil
+	}
+
+	if strings.HasPrefix(t, "uint") || strings.HasPrefix(t, "int") {
+		signed := strings.HasPrefix(t, "int")
+		size := 256
+		if s := strings.TrimPrefix(strings.TrimPrefix(t, "u"), "int"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 8 || n > 256 || n%8 != 0 {
+				return nil, fmt.Errorf("invalid type %s", t)
+			}
+			size = n
+		}
+		i, err := typedInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		min, max := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(size))
+		if signed {
+			max.Rsh(max, 1)
+			min.Neg(max)
+		}
+		if i.Cmp(min) < 0 || i.Cmp(max) >= 0 {
+			return nil, fmt.Errorf("value %s out of range for type %s", i, t)
+		}
+		return math.U256Bytes(new(big.Int).Set(i)), nil
+	}
+
+	return nil, fmt.Errorf("unknown type %s", t)
+}
+
+func typedBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("expect hex string, got %T", value)
+	}
+}
+
+func typedInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case json.Number:
+		return parseTypedIntegerString(v.String())
+	case string:
+		return parseTypedIntegerString(v)
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	default:
+		return nil, fmt.Errorf("expect integer, got %T", value)
+	}
+}
+
+func parseTypedIntegerString(s string) (*big.Int, error) {
+	var i math.HexOrDecimal256
+	if err := i.UnmarshalText([]byte(s)); err != nil {
+		return nil, fmt.Errorf("invalid integer %s", s)
+	}
+	return (*big.Int)(&i), nil
+}
+
+func baseType(t string) string {
+	if i := strings.Index(t, "["); i >= 0 {
+		return t[:i]
+	}
+	return t
+}
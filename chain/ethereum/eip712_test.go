@@ -0,0 +1,86 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/crypto"
+	"testing"
+)
+
+// EIP-712 规范中的示例
+const testTypedDataJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestTypedData(t *testing.T) {
+	td, err := ParseTypedData(testTypedDataJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if td.EncodeType("Mail") != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Fatalf("unexpected encodeType: %s", td.EncodeType("Mail"))
+	}
+
+	domainSeparator, err := td.DomainSeparator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(domainSeparator) != "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f" {
+		t.Fatalf("unexpected domain separator: %x", domainSeparator)
+	}
+
+	hash, err := td.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(hash) != "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2" {
+		t.Fatalf("unexpected hash: %x", hash)
+	}
+
+	signer := NewLocalSigner(crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow"))))
+	sig, err := SignTypedData(signer, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562" + "1c"
+	if hex.EncodeToString(sig) != expected {
+		t.Fatalf("unexpected signature: %x", sig)
+	}
+
+	ok, err := VerifyTypedData(td, sig, "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("signature verification failed")
+	}
+}
@@ -0,0 +1,201 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+)
+
+const (
+	// TxEnvelopeVersion 是 EncodeTx 输出的交易信封的当前版本
+	TxEnvelopeVersion = 1
+	// TxEnvelopeChain 是以太坊系 (ETH/BSC/OKC 等) 交易信封的链标识
+	TxEnvelopeChain = "ethereum"
+)
+
+// TxEnvelope 是 EncodeTx 序列化交易使用的格式，EncodeTx 的输出为该结构json的16进制编码
+//
+// 信封只包含交易本身的字段，不会包含私钥、Provider、ABI 等运行时数据，
+// 可以在不同进程、不同版本的SDK之间传递 (例如 构建 -> 签名 -> 广播 分别由不同服务完成)
+// 数值与字节字段使用与以太坊 JSON-RPC 一致的16进制编码:
+//
+//	{
+//	  "version": 1,                         // 信封版本，解析时高于当前支持的版本会报错
+//	  "chain": "ethereum",                  // 链标识
+//	  "chainId": "0x38",                    // 可选，交易所在链的链ID，已签名交易由签名推导，签名时链ID不一致会报错
+//	  "type": "0x2",                        // 交易类型，见 LegacyTxType 等定义
+//	  "from": "0x...",                      // 交易发起方
+//	  "to": "0x...",                        // 可选，部署合约交易为空
+//	  "nonce": "0x7",
+//	  "value": "0x3039",
+//	  "gas": "0xc350",
+//	  "gasPrice": "0x3",                    // 传统交易与 EIP-2930 交易使用
+//	  "maxPriorityFeePerGas": "0x2",        // EIP-1559 交易使用
+//	  "maxFeePerGas": "0x64",               // EIP-1559 交易使用
+//	  "input": "0x...",
+//	  "accessList": [{"address": "0x...", "storageKeys": ["0x..."]}],
+//	  "signedTx": "0x...",                  // 可选，签名交易原文
+//	  "hash": "0x..."                       // 可选，已签名交易的hash
+//	}
+type TxEnvelope struct {
+	Version              int             `json:"version"`
+	Chain                string          `json:"chain"`
+	ChainID              *hexutil.Uint64 `json:"chainId,omitempty"`
+	Type                 hexutil.Uint64  `json:"type"`
+	From                 web3.Address    `json:"from"`
+	To                   *web3.Address   `json:"to,omitempty"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Value                *hexutil.Big    `json:"value"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             hexutil.Uint64  `json:"gasPrice"`
+	MaxPriorityFeePerGas hexutil.Uint64  `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         hexutil.Uint64  `json:"maxFeePerGas"`
+	Input                hexutil.Bytes   `json:"input"`
+	AccessList           AccessList      `json:"accessList,omitempty"`
+	SignedTx             hexutil.Bytes   `json:"signedTx,omitempty"`
+	Hash                 *web3.Hash      `json:"hash,omitempty"`
+}
+
+// Envelope 返回交易的信封
+func (t *Txn) Envelope() (*TxEnvelope, error) {
+	if t.Data == nil && (t.Method != nil || len(t.Bin) != 0) {
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &TxEnvelope{
+		Version:              TxEnvelopeVersion,
+		Chain:                TxEnvelopeChain,
+		Type:                 hexutil.Uint64(t.Type),
+		From:                 t.From,
+		To:                   t.Addr,
+		Nonce:                hexutil.Uint64(t.Nonce),
+		Value:                (*hexutil.Big)(new(big.Int)),
+		Gas:                  hexutil.Uint64(t.GasLimit),
+		GasPrice:             hexutil.Uint64(t.GasPrice),
+		MaxPriorityFeePerGas: hexutil.Uint64(t.GasTipCap),
+		MaxFeePerGas:         hexutil.Uint64(t.GasFeeCap),
+		Input:                t.Data,
+		AccessList:           t.AccessList,
+	}
+	if t.Value != nil {
+		e.Value = (*hexutil.Big)(new(big.Int).Set(t.Value))
+	}
+	if e.Input == nil {
+		e.Input = []byte{}
+	}
+	if t.ChainID != 0 {
+		chainID := hexutil.Uint64(t.ChainID)
+		e.ChainID = &chainID
+	}
+
+	if len(t.SignedTx) != 0 {
+		signed, err := DecodeSignedTx(t.SignedTx)
+		if err != nil {
+			return nil, err
+		}
+		chainID := hexutil.Uint64(signed.ChainID)
+		e.ChainID = &chainID
+		e.SignedTx = t.SignedTx
+		e.Hash = &signed.Hash
+	}
+	return e, nil
+}
+
+// Txn 将信封转换为交易
+// 若信封带有签名交易，签名交易的内容必须与信封中的字段一致
+func (e *TxEnvelope) Txn() (*Txn, error) {
+	if e.Version <= 0 || e.Version > TxEnvelopeVersion {
+		return nil, errno.UnsupportedTxEnvelope.Add(fmt.Sprintf("version %d", e.Version))
+	}
+	if e.Chain != TxEnvelopeChain {
+		return nil, errno.UnsupportedTxEnvelope.Add(fmt.Sprintf("chain %q", e.Chain))
+	}
+	if e.Type > DynamicFeeTxType {
+		return nil, errno.UnsupportedTxEnvelope.Add(fmt.Sprintf("tx type %d", e.Type))
+	}
+
+	t := &Txn{
+		Type:       uint8(e.Type),
+		From:       e.From,
+		Addr:       e.To,
+		Nonce:      uint64(e.Nonce),
+		Value:      new(big.Int),
+		GasLimit:   uint64(e.Gas),
+		GasPrice:   uint64(e.GasPrice),
+		GasTipCap:  uint64(e.MaxPriorityFeePerGas),
+		GasFeeCap:  uint64(e.MaxFeePerGas),
+		Data:       e.Input,
+		AccessList: e.AccessList,
+	}
+	if e.Value != nil {
+		t.Value = e.Value.ToInt()
+	}
+	if t.Data == nil {
+		t.Data = []byte{}
+	}
+	if e.ChainID != nil {
+		t.ChainID = uint64(*e.ChainID)
+	}
+
+	if len(e.SignedTx) != 0 {
+		signed, err := DecodeSignedTx(e.SignedTx)
+		if err != nil {
+			return nil, err
+		}
+		if e.ChainID != nil && uint64(*e.ChainID) != signed.ChainID {
+			return nil, errno.ParseTxError.Add(fmt.Sprintf("envelope chain id %d does not match signed tx chain id %d", *e.ChainID, signed.ChainID))
+		}
+		t.SignedTx = e.SignedTx
+		if err := t.VerifySignature(fmt.Sprint(signed.ChainID)); err != nil {
+			return nil, errno.ParseTxError.Add("signed tx does not match envelope: " + err.Error())
+		}
+		t.Hash = signed.Hash
+		t.ChainID = signed.ChainID
+	}
+	return t, nil
+}
+
+// EncodeTx 序列化交易，输出为 TxEnvelope json 的16进制编码
+func (t *Txn) EncodeTx() (txEncoded string, err error) {
+	e, err := t.Envelope()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// decodeEnvelope 解析 EncodeTx 输出的json
+// 不带 version 字段的json是旧版本SDK直接序列化 Txn 的结果，按旧格式解析 (其中的私钥字段会被丢弃)
+func decodeEnvelope(b []byte) (*Txn, error) {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+
+	if probe.Version == nil {
+		var txn *Txn
+		if err := json.Unmarshal(b, &txn); err != nil {
+			return nil, errno.ParseTxError.Add(err.Error())
+		}
+		txn.Private = nil
+		return txn, nil
+	}
+
+	var e TxEnvelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+	return e.Txn()
+}
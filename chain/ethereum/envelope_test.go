@@ -0,0 +1,132 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTxEnvelope(t *testing.T) {
+	signer, err := NewLocalSignerFromHex("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	txn := &Txn{
+		Private:    signer.PrivateKey(),
+		Type:       DynamicFeeTxType,
+		From:       signer.Address(),
+		Addr:       &to,
+		Nonce:      7,
+		Value:      big.NewInt(12345),
+		GasTipCap:  2,
+		GasFeeCap:  100,
+		GasLimit:   50000,
+		Data:       []byte{0xde, 0xad},
+		AccessList: AccessList{{Address: to, StorageKeys: []web3.Hash{{31: 1}}}},
+	}
+
+	builder := &TxBuilder{}
+	encoded, err := txn.EncodeTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := hex.DecodeString(encoded)
+	if strings.Contains(string(b), "private") {
+		t.Fatalf("envelope contains private key: %s", b)
+	}
+	decoded, err := builder.DecodeTx(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decoded.(*Txn)
+	if !reflect.DeepEqual(got.AccessList, txn.AccessList) || got.Value.Cmp(txn.Value) != 0 ||
+		got.GasFeeCap != txn.GasFeeCap || got.GasTipCap != txn.GasTipCap || *got.Addr != to {
+		t.Fatalf("unexpected decoded tx %+v", got)
+	}
+
+	if err := got.SignWithSigner(signer, "56"); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err = got.EncodeTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = builder.DecodeTx(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.(*Txn).VerifySignature("56"); err != nil {
+		t.Fatal(err)
+	}
+	if hash := decoded.(*Txn).Hash; hex.EncodeToString(hash[:]) != hex.EncodeToString(keccak256(got.SignedTx)) {
+		t.Fatalf("unexpected hash %s", hash)
+	}
+
+	future := hex.EncodeToString([]byte(`{"version":2,"chain":"ethereum"}`))
+	if _, err := builder.DecodeTx(future); err == nil {
+		t.Fatal("expect unsupported version error")
+	}
+}
+
+func TestTxEnvelopeChainID(t *testing.T) {
+	signer, err := NewLocalSignerFromHex("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	txn := &Txn{
+		ChainID:  56,
+		From:     signer.Address(),
+		Addr:     &to,
+		Nonce:    7,
+		Value:    big.NewInt(12345),
+		GasPrice: 3,
+		GasLimit: 21000,
+	}
+
+	builder := &TxBuilder{}
+	encoded, err := txn.EncodeTx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := hex.DecodeString(encoded)
+	if !strings.Contains(string(b), `"chainId":"0x38"`) {
+		t.Fatalf("unsigned envelope does not contain chain id: %s", b)
+	}
+
+	decoded, err := builder.DecodeTx(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decoded.(*Txn)
+	if got.ChainID != 56 {
+		t.Fatalf("expect chain id 56, got %d", got.ChainID)
+	}
+	if err := got.SignWithSigner(signer, "1"); !errors.Is(err, errno.ChainIDMismatch) {
+		t.Fatalf("expect ChainIDMismatch when signing for chain 1, got %v", err)
+	}
+	if _, err := got.GetTxHash("1"); !errors.Is(err, errno.ChainIDMismatch) {
+		t.Fatalf("expect ChainIDMismatch for tx hash of chain 1, got %v", err)
+	}
+	if err := got.SignWithSigner(signer, "56"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 签名交易的链ID与信封中的链ID不一致
+	e, err := got.Envelope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := hexutil.Uint64(1)
+	e.ChainID = &wrong
+	if _, err := e.Txn(); !errors.Is(err, errno.ParseTxError) {
+		t.Fatalf("expect ParseTxError for mismatched chain id, got %v", err)
+	}
+}
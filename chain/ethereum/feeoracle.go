@@ -0,0 +1,310 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mgintoki/go-web3/jsonrpc/codec"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/errno"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultFeeHistoryBlocks = 20 // FeeOracle 默认统计的区块数
+	fallbackFeeBlocks       = 5  // 节点不支持 eth_feeHistory 时读取的区块数
+)
+
+var (
+	// feeSpeeds 是费用档位，与 feePercentiles、feeWaitBlocks 一一对应
+	feeSpeeds = []fee.Speed{fee.SpeedSlow, fee.SpeedStandard, fee.SpeedFast}
+	// feePercentiles 是各档位使用的近期区块小费的百分位
+	feePercentiles = []float64{10, 50, 90}
+	// feeWaitBlocks 是各档位预计的上链区块数
+	feeWaitBlocks = []uint64{6, 3, 1}
+)
+
+// FeeOracle 是费用预言机，根据 eth_feeHistory 统计的近期区块小费给出 slow、standard、fast 三个档位的推荐费用
+// 节点不支持 eth_feeHistory 时 (如部分 OKC 节点)，使用近期区块中交易的 gas 价格统计
+// 推荐的 gas 价格受 provider 中 FeeCaps 的限制
+type FeeOracle struct {
+	provider *RPCClient
+	caps     fee.Caps
+	// Blocks 是统计的区块数，为0时使用 DefaultFeeHistoryBlocks
+	Blocks uint64
+}
+
+// NewFeeOracle 新建一个费用预言机
+func NewFeeOracle(provider provider.CommonProvider) (*FeeOracle, error) {
+	p, err := NewRPCClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	return &FeeOracle{provider: p, caps: provider.FeeCaps}, nil
+}
+
+type rpcFeeHistory struct {
+	OldestBlock   rpcBig     `json:"oldestBlock"`
+	BaseFeePerGas []rpcBig   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64  `json:"gasUsedRatio"`
+	Reward        [][]rpcBig `json:"reward"`
+}
+
+type rpcFeeHeader struct {
+	Number        rpcBig  `json:"number"`
+	Timestamp     rpcBig  `json:"timestamp"`
+	BaseFeePerGas *rpcBig `json:"baseFeePerGas"`
+}
+
+type rpcFeeBlock struct {
+	rpcFeeHeader
+	Transactions []struct {
+		GasPrice rpcBig `json:"gasPrice"`
+	} `json:"transactions"`
+}
+
+// GasPrice 返回 speed 档位的传统交易 gas 价格，speed 为空时使用节点的 eth_gasPrice，结果受 FeeCaps 限制
+func (o *FeeOracle) GasPrice(speed fee.Speed) (uint64, error) {
+	if speed == "" {
+		gasPrice, err := o.provider.Eth().GasPrice()
+		if err != nil {
+			return 0, err
+		}
+		return o.caps.Apply(gasPrice), nil
+	}
+	if err := checkSpeed(speed); err != nil {
+		return 0, err
+	}
+	r, err := o.Suggest()
+	if err != nil {
+		return 0, err
+	}
+	return r.Get(speed).GasPrice, nil
+}
+
+// setFee 按照 speed 档位设置交易的费用，EIP-1559 交易设置 GasFeeCap 与 GasTipCap
+func (o *FeeOracle) setFee(t *Txn, speed fee.Speed) error {
+	if err := checkSpeed(speed); err != nil {
+		return err
+	}
+	r, err := o.Suggest()
+	if err != nil {
+		return err
+	}
+	e := r.Get(speed)
+	t.GasPrice = e.GasPrice
+	if t.Type == DynamicFeeTxType {
+		t.GasFeeCap, t.GasTipCap = e.MaxFeePerGas, e.MaxPriorityFeePerGas
+	}
+	return nil
+}
+
+func checkSpeed(speed fee.Speed) error {
+	for _, s := range feeSpeeds {
+		if s == speed {
+			return nil
+		}
+	}
+	return errno.InvalidFeeSpeed.Add(string(speed))
+}
+
+// Suggest 返回各档位的推荐费用
+func (o *FeeOracle) Suggest() (*fee.Recommendation, error) {
+	blocks := o.Blocks
+	if blocks == 0 {
+		blocks = DefaultFeeHistoryBlocks
+	}
+	var history rpcFeeHistory
+	err := o.provider.Call("eth_feeHistory", &history, hexutil.Uint64(blocks), "latest", feePercentiles)
+	if err != nil {
+		if isMethodUnsupported(err) {
+			return o.suggestFromBlocks()
+		}
+		return nil, err
+	}
+	if len(history.GasUsedRatio) == 0 || len(history.BaseFeePerGas) == 0 {
+		return o.suggestFromBlocks()
+	}
+
+	// baseFeePerGas 比区块多一项，最后一项是下一个区块的 base fee
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1].Int().Uint64()
+	tips := make([][]uint64, len(feeSpeeds))
+	for i, ratio := range history.GasUsedRatio {
+		// 空区块的小费为0，不参与统计
+		if ratio == 0 || i >= len(history.Reward) || len(history.Reward[i]) < len(feeSpeeds) {
+			continue
+		}
+		for j := range feeSpeeds {
+			tips[j] = append(tips[j], history.Reward[i][j].Int().Uint64())
+		}
+	}
+	suggested := make([]uint64, len(feeSpeeds))
+	if len(tips[0]) == 0 {
+		if suggested, err = o.nodeTips(baseFee); err != nil {
+			return nil, err
+		}
+	} else {
+		for j := range feeSpeeds {
+			suggested[j] = percentile(tips[j], 50)
+		}
+	}
+
+	oldest := history.OldestBlock.Int().Uint64()
+	blockTime, err := o.blockTime(oldest, oldest+uint64(len(history.GasUsedRatio))-1)
+	if err != nil {
+		return nil, err
+	}
+	return o.recommend(baseFee, suggested, blockTime), nil
+}
+
+// suggestFromBlocks 使用近期区块中交易的 gas 价格计算推荐费用
+func (o *FeeOracle) suggestFromBlocks() (*fee.Recommendation, error) {
+	latest, err := o.provider.Eth().BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	var baseFee uint64
+	var tips []uint64
+	var first, last *rpcFeeBlock
+	for i := uint64(0); i < fallbackFeeBlocks && i <= latest; i++ {
+		var b *rpcFeeBlock
+		if err := o.provider.Call("eth_getBlockByNumber", &b, hexutil.Uint64(latest-i).String(), true); err != nil {
+			return nil, err
+		}
+		if b == nil {
+			continue
+		}
+		if last == nil {
+			last = b
+			if b.BaseFeePerGas != nil {
+				baseFee = b.BaseFeePerGas.Int().Uint64()
+			}
+		}
+		first = b
+		for _, t := range b.Transactions {
+			if price := t.GasPrice.Int().Uint64(); price > baseFee {
+				tips = append(tips, price-baseFee)
+			}
+		}
+	}
+
+	suggested := make([]uint64, len(feeSpeeds))
+	if len(tips) == 0 {
+		if suggested, err = o.nodeTips(baseFee); err != nil {
+			return nil, err
+		}
+	} else {
+		for j, p := range feePercentiles {
+			suggested[j] = percentile(tips, p)
+		}
+	}
+
+	var blockTime time.Duration
+	if first != nil && last != nil {
+		blockTime = averageBlockTime(&first.rpcFeeHeader, &last.rpcFeeHeader)
+	}
+	return o.recommend(baseFee, suggested, blockTime), nil
+}
+
+// nodeTips 在近期区块没有交易时使用节点的 eth_gasPrice 作为所有档位的小费
+func (o *FeeOracle) nodeTips(baseFee uint64) ([]uint64, error) {
+	gasPrice, err := o.provider.Eth().GasPrice()
+	if err != nil {
+		return nil, err
+	}
+	var tip uint64
+	if gasPrice > baseFee {
+		tip = gasPrice - baseFee
+	}
+	tips := make([]uint64, len(feeSpeeds))
+	for j := range tips {
+		tips[j] = tip
+	}
+	return tips, nil
+}
+
+// blockTime 根据区块 from 与 to 的时间戳计算平均出块时间
+func (o *FeeOracle) blockTime(from, to uint64) (time.Duration, error) {
+	if to <= from {
+		if to == 0 {
+			return 0, nil
+		}
+		from = to - 1
+	}
+	var headers [2]*rpcFeeHeader
+	for i, n := range []uint64{from, to} {
+		if err := o.provider.Call("eth_getBlockByNumber", &headers[i], hexutil.Uint64(n).String(), false); err != nil {
+			return 0, err
+		}
+		if headers[i] == nil {
+			return 0, nil
+		}
+	}
+	return averageBlockTime(headers[0], headers[1]), nil
+}
+
+func averageBlockTime(first, last *rpcFeeHeader) time.Duration {
+	from, to := first.Number.Int().Uint64(), last.Number.Int().Uint64()
+	start, end := first.Timestamp.Int().Uint64(), last.Timestamp.Int().Uint64()
+	if to <= from || end < start {
+		return 0
+	}
+	return time.Duration(end-start) * time.Second / time.Duration(to-from)
+}
+
+// recommend 根据下一个区块的 base fee 与各档位的小费计算推荐费用
+// 传统交易的 gas 价格为 base fee 的 9/8 (一个满区块之后的 base fee) 加上小费，
+// EIP-1559 交易的 MaxFeePerGas 为 base fee 的两倍加上小费
+func (o *FeeOracle) recommend(baseFee uint64, tips []uint64, blockTime time.Duration) *fee.Recommendation {
+	r := &fee.Recommendation{BaseFee: baseFee, BlockTime: blockTime}
+	estimates := []*fee.Estimate{&r.Slow, &r.Standard, &r.Fast}
+	for i, speed := range feeSpeeds {
+		tip := tips[i]
+		// 更快的档位的小费不低于更慢的档位
+		if i > 0 && tip < tips[i-1] {
+			tip = tips[i-1]
+			tips[i] = tip
+		}
+		e := estimates[i]
+		e.Speed = speed
+		e.WaitTime = blockTime * time.Duration(feeWaitBlocks[i])
+		if baseFee == 0 {
+			price := o.caps.Apply(tip)
+			e.GasPrice, e.MaxFeePerGas, e.MaxPriorityFeePerGas = price, price, price
+			continue
+		}
+		e.GasPrice = o.caps.Apply(baseFee + baseFee/8 + tip)
+		e.MaxFeePerGas = o.caps.Apply(2*baseFee + tip)
+		e.MaxPriorityFeePerGas = tip
+		if e.MaxPriorityFeePerGas > e.MaxFeePerGas {
+			e.MaxPriorityFeePerGas = e.MaxFeePerGas
+		}
+	}
+	return r
+}
+
+// percentile 按最近秩法 (nearest-rank) 返回 values 的第 p 百分位数，
+// 样本较少时 (如只有3到5笔交易) 不同的百分位也能取到不同的值
+func percentile(values []uint64, p float64) uint64 {
+	sorted := append([]uint64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(float64(len(sorted)) * p / 100))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// isMethodUnsupported 判断错误是否代表节点不支持该方法
+func isMethodUnsupported(err error) bool {
+	var obj *codec.ErrorObject
+	if !errors.As(err, &obj) {
+		return false
+	}
+	msg := strings.ToLower(obj.Message)
+	return obj.Code == -32601 || strings.Contains(msg, "not supported") || strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not available") || strings.Contains(msg, "method not found")
+}
@@ -0,0 +1,139 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/testkit"
+	"testing"
+	"time"
+)
+
+// respondBlocks 使用 blocks 中对应区块号的数据响应 eth_getBlockByNumber
+func respondBlocks(server *testkit.Server, blocks map[string]interface{}) {
+	server.RespondFunc("eth_getBlockByNumber", func(params []json.RawMessage) (interface{}, error) {
+		var number string
+		if err := json.Unmarshal(params[0], &number); err != nil {
+			return nil, err
+		}
+		return blocks[number], nil
+	})
+}
+
+func TestFeeOracleFeeHistory(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_feeHistory", map[string]interface{}{
+		"oldestBlock":   "0x64",
+		"baseFeePerGas": []string{"0xa", "0xa", "0xc", "0xc", "0x10"},
+		"gasUsedRatio":  []float64{0.5, 0, 0.9, 0.7},
+		"reward":        [][]string{{"0x1", "0x2", "0x3"}, {"0x0", "0x0", "0x0"}, {"0x3", "0x5", "0x9"}, {"0x2", "0x4", "0x8"}},
+	})
+	respondBlocks(server, map[string]interface{}{
+		"0x64": map[string]string{"number": "0x64", "timestamp": "0x3e8"},
+		"0x67": map[string]string{"number": "0x67", "timestamp": "0x3f1"},
+	})
+
+	p := server.Provider()
+	p.FeeCaps = fee.Caps{Max: 30}
+	oracle, err := NewFeeOracle(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := oracle.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BaseFee != 16 || r.BlockTime != 3*time.Second {
+		t.Fatalf("unexpected recommendation %+v", r)
+	}
+	// 空区块不参与统计，各档位取非空区块对应百分位的中位数
+	expect := []fee.Estimate{
+		{Speed: fee.SpeedSlow, GasPrice: 20, MaxFeePerGas: 30, MaxPriorityFeePerGas: 2, WaitTime: 18 * time.Second},
+		{Speed: fee.SpeedStandard, GasPrice: 22, MaxFeePerGas: 30, MaxPriorityFeePerGas: 4, WaitTime: 9 * time.Second},
+		{Speed: fee.SpeedFast, GasPrice: 26, MaxFeePerGas: 30, MaxPriorityFeePerGas: 8, WaitTime: 3 * time.Second},
+	}
+	for i, e := range []fee.Estimate{r.Slow, r.Standard, r.Fast} {
+		if e != expect[i] {
+			t.Fatalf("expect %+v, got %+v", expect[i], e)
+		}
+	}
+
+	var req []json.RawMessage
+	if err := server.Requests("eth_feeHistory")[0].Param(2, &req); err != nil || len(req) != 3 {
+		t.Fatalf("unexpected percentiles %s %v", req, err)
+	}
+}
+
+func TestFeeOracleFallback(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.RespondError("eth_feeHistory", -32601, "the method eth_feeHistory does not exist/is not available").
+		Respond("eth_blockNumber", "0x2")
+	tx := func(gasPrice string) map[string]string { return map[string]string{"gasPrice": gasPrice} }
+	respondBlocks(server, map[string]interface{}{
+		"0x2": map[string]interface{}{"number": "0x2", "timestamp": "0x14", "transactions": []interface{}{tx("0x5"), tx("0x7")}},
+		"0x1": map[string]interface{}{"number": "0x1", "timestamp": "0x11", "transactions": []interface{}{tx("0x6")}},
+		"0x0": map[string]interface{}{"number": "0x0", "timestamp": "0xe", "transactions": []interface{}{}},
+	})
+
+	oracle, err := NewFeeOracle(server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := oracle.Suggest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BaseFee != 0 || r.BlockTime != 3*time.Second {
+		t.Fatalf("unexpected recommendation %+v", r)
+	}
+	// 不支持 EIP-1559 的链 MaxFeePerGas 与 gas 价格相同，3笔交易的第10、50、90百分位分别是 5、6、7
+	if r.Slow.GasPrice != 5 || r.Slow.MaxFeePerGas != 5 || r.Standard.GasPrice != 6 || r.Fast.GasPrice != 7 {
+		t.Fatalf("unexpected estimates %+v", r)
+	}
+}
+
+func TestBuildTxWithSpeed(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_feeHistory", map[string]interface{}{
+		"oldestBlock":   "0x1",
+		"baseFeePerGas": []string{"0x0", "0x0"},
+		"gasUsedRatio":  []float64{0.5},
+		"reward":        [][]string{{"0x1", "0x2", "0x3"}},
+	}).Respond("eth_gasPrice", "0x1").Respond("eth_chainId", "0x38")
+	respondBlocks(server, map[string]interface{}{
+		"0x0": map[string]string{"number": "0x0", "timestamp": "0x0"},
+		"0x1": map[string]string{"number": "0x1", "timestamp": "0x3"},
+	})
+
+	p := server.Provider()
+	p.FeeCaps = fee.Caps{Min: 2}
+	builder, err := NewTxBuilder(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	param := txbuilder.BuildTxParam{From: "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", Nonce: 1, GasLimit: 21000}
+
+	built, err := builder.BuildTx(param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := built.(*Txn).GasPrice; price != 2 {
+		t.Fatalf("expect eth_gasPrice raised to the min cap, got %d", price)
+	}
+	param.Speed = fee.SpeedFast
+	if built, err = builder.BuildTx(param); err != nil {
+		t.Fatal(err)
+	}
+	if price := built.(*Txn).GasPrice; price != 3 {
+		t.Fatalf("expect fast gas price 3, got %d", price)
+	}
+	param.Speed = "instant"
+	if _, err := builder.BuildTx(param); !errors.Is(err, errno.InvalidFeeSpeed) {
+		t.Fatalf("expect invalid speed, got %v", err)
+	}
+}
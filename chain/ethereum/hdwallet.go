@@ -0,0 +1,306 @@
+package ethereum
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/tyler-smith/go-bip39"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDerivationPath 是以太坊第一个账户的 BIP-44 派生路径，与 MetaMask 和 Ledger Live 一致
+	DefaultDerivationPath = "m/44'/60'/0'/0/0"
+	// DefaultBaseDerivationPath 是以太坊账户的 BIP-44 基础路径，在其后追加账户序号即可得到第 i 个账户
+	DefaultBaseDerivationPath = "m/44'/60'/0'/0"
+
+	hardenedKeyStart = hdkeychain.HardenedKeyStart
+)
+
+// HDWallet 是一个分层确定性钱包 (BIP-32/BIP-39/BIP-44)
+// 由助记词或扩展私钥创建的钱包可以派生私钥和地址；
+// 由扩展公钥 (xpub) 创建的钱包只能派生非强化路径下的地址，适用于只生成收款地址的场景
+type HDWallet struct {
+	root *hdNode
+}
+
+// hdNode 是 BIP-32 派生树上的一个节点
+// 这里没有直接使用 hdkeychain.ExtendedKey.Child, 因为其在父私钥有前导0时派生结果不符合 BIP-32
+type hdNode struct {
+	key       []byte // 私钥为32字节，公钥为33字节的压缩格式
+	chainCode []byte
+	parentFP  []byte
+	depth     uint8
+	childNum  uint32
+	private   bool
+}
+
+// NewMnemonic 生成一个新的 BIP-39 英文助记词
+// bitSize 为熵的位数，必须是[128, 256]之间32的倍数，128 对应12个单词，256 对应24个单词
+func NewMnemonic(bitSize int) (string, error) {
+	entropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewHDWalletFromMnemonic 由 BIP-39 助记词和可选的密码 (BIP-39 passphrase) 创建钱包
+func NewHDWalletFromMnemonic(mnemonic string, password string) (*HDWallet, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err != nil {
+		return nil, errno.InvalidMnemonic.Add(err.Error())
+	}
+	return NewHDWalletFromSeed(seed)
+}
+
+// NewHDWalletFromSeed 由 BIP-32 种子创建钱包
+func NewHDWalletFromSeed(seed []byte) (*HDWallet, error) {
+	if len(seed) < hdkeychain.MinSeedBytes || len(seed) > hdkeychain.MaxSeedBytes {
+		return nil, hdkeychain.ErrInvalidSeedLen
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	lr := mac.Sum(nil)
+
+	keyNum := new(big.Int).SetBytes(lr[:32])
+	if keyNum.Sign() == 0 || keyNum.Cmp(btcec.S256().N) >= 0 {
+		return nil, hdkeychain.ErrUnusableSeed
+	}
+	return &HDWallet{root: &hdNode{
+		key:       lr[:32],
+		chainCode: lr[32:],
+		parentFP:  []byte{0, 0, 0, 0},
+		private:   true,
+	}}, nil
+}
+
+// NewHDWalletFromExtendedKey 由 base58 格式的扩展私钥 (xprv) 或扩展公钥 (xpub) 创建钱包
+// 此时派生路径为相对于该扩展密钥的路径，如 "0/1"
+func NewHDWalletFromExtendedKey(extendedKey string) (*HDWallet, error) {
+	k, err := hdkeychain.NewKeyFromString(extendedKey)
+	if err != nil {
+		return nil, err
+	}
+	payload := base58.Decode(extendedKey)
+	node := &hdNode{
+		depth:     k.Depth(),
+		parentFP:  payload[5:9],
+		childNum:  binary.BigEndian.Uint32(payload[9:13]),
+		chainCode: payload[13:45],
+		key:       payload[45:78],
+		private:   k.IsPrivate(),
+	}
+	if node.private {
+		node.key = node.key[1:]
+	}
+	return &HDWallet{root: node}, nil
+}
+
+// IsPublicOnly 返回钱包是否只能派生地址
+func (w *HDWallet) IsPublicOnly() bool {
+	return !w.root.private
+}
+
+// DerivePrivate 按照派生路径派生私钥
+func (w *HDWallet) DerivePrivate(path string) (*ecdsa.PrivateKey, error) {
+	if w.IsPublicOnly() {
+		return nil, errno.HDWalletPublicOnly
+	}
+	node, err := w.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(node.key)
+}
+
+// DeriveAddress 按照派生路径派生账户地址
+func (w *HDWallet) DeriveAddress(path string) (string, error) {
+	node, err := w.derive(path)
+	if err != nil {
+		return "", err
+	}
+	pub, err := btcec.ParsePubKey(node.publicKey(), btcec.S256())
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*pub.ToECDSA()).String(), nil
+}
+
+// DeriveAddresses 批量派生 basePath/start 至 basePath/(start+count-1) 的账户地址
+// 例如 basePath 为 DefaultBaseDerivationPath 时，得到与 MetaMask 一致的前 count 个账户
+func (w *HDWallet) DeriveAddresses(basePath string, start uint32, count uint32) ([]string, error) {
+	base, err := w.derive(basePath)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		node, err := base.child(start + i)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := btcec.ParsePubKey(node.publicKey(), btcec.S256())
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, crypto.PubkeyToAddress(*pub.ToECDSA()).String())
+	}
+	return addresses, nil
+}
+
+// ExtendedPublicKey 导出派生路径对应节点的扩展公钥 (xpub)
+// 通常导出 DefaultBaseDerivationPath 节点的 xpub，交给只负责生成地址的服务使用
+func (w *HDWallet) ExtendedPublicKey(path string) (string, error) {
+	node, err := w.derive(path)
+	if err != nil {
+		return "", err
+	}
+	return hdkeychain.NewExtendedKey(chaincfg.MainNetParams.HDPublicKeyID[:], node.publicKey(), node.chainCode,
+		node.parentFP, node.depth, node.childNum, false).String(), nil
+}
+
+// SetMnemonic 使用 BIP-39 助记词、可选的密码和派生路径设置 Client 的私钥
+// path 为空时使用 DefaultDerivationPath
+func (c *Client) SetMnemonic(mnemonic string, password string, path string) (err error) {
+	if path == "" {
+		path = DefaultDerivationPath
+	}
+	w, err := NewHDWalletFromMnemonic(mnemonic, password)
+	if err != nil {
+		return err
+	}
+	private, err := w.DerivePrivate(path)
+	if err != nil {
+		return err
+	}
+	c.signer = NewLocalSigner(private)
+	return nil
+}
+
+func (w *HDWallet) derive(path string) (*hdNode, error) {
+	indexes, absolute, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if absolute && w.root.depth != 0 {
+		return nil, errno.InvalidDerivationPath.Add("absolute path on a non-master key")
+	}
+	node := w.root
+	for _, i := range indexes {
+		node, err = node.child(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// parseDerivationPath 解析形如 m/44'/60'/0'/0/0 的绝对路径或 0/1 的相对路径
+func parseDerivationPath(path string) (indexes []uint32, absolute bool, err error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] == "m" {
+		absolute = true
+		parts = parts[1:]
+	}
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			if len(parts) == 1 {
+				break
+			}
+			return nil, false, errno.InvalidDerivationPath.Add(path)
+		}
+		var offset uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") {
+			offset = hardenedKeyStart
+			p = p[:len(p)-1]
+		}
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || uint32(i) >= hardenedKeyStart {
+			return nil, false, errno.InvalidDerivationPath.Add(path)
+		}
+		indexes = append(indexes, uint32(i)+offset)
+	}
+	return indexes, absolute, nil
+}
+
+func (n *hdNode) publicKey() []byte {
+	if !n.private {
+		return n.key
+	}
+	_, pub := btcec.PrivKeyFromBytes(btcec.S256(), n.key)
+	return pub.SerializeCompressed()
+}
+
+func (n *hdNode) child(i uint32) (*hdNode, error) {
+	hardened := i >= hardenedKeyStart
+	if hardened && !n.private {
+		return nil, hdkeychain.ErrDeriveHardFromPublic
+	}
+
+	data := make([]byte, 37)
+	if hardened {
+		copy(data[1:33], paddedKey(n.key))
+	} else {
+		copy(data, n.publicKey())
+	}
+	binary.BigEndian.PutUint32(data[33:], i)
+
+	mac := hmac.New(sha512.New, n.chainCode)
+	mac.Write(data)
+	lr := mac.Sum(nil)
+
+	curve := btcec.S256()
+	il := new(big.Int).SetBytes(lr[:32])
+	if il.Cmp(curve.N) >= 0 || il.Sign() == 0 {
+		return nil, hdkeychain.ErrInvalidChild
+	}
+
+	child := &hdNode{
+		chainCode: lr[32:],
+		parentFP:  btcutil.Hash160(n.publicKey())[:4],
+		depth:     n.depth + 1,
+		childNum:  i,
+		private:   n.private,
+	}
+	if n.private {
+		il.Add(il, new(big.Int).SetBytes(n.key))
+		il.Mod(il, curve.N)
+		if il.Sign() == 0 {
+			return nil, hdkeychain.ErrInvalidChild
+		}
+		child.key = paddedKey(il.Bytes())
+	} else {
+		pub, err := btcec.ParsePubKey(n.key, curve)
+		if err != nil {
+			return nil, err
+		}
+		x, y := curve.ScalarBaseMult(lr[:32])
+		x, y = curve.Add(x, y, pub.X, pub.Y)
+		if x.Sign() == 0 && y.Sign() == 0 {
+			return nil, hdkeychain.ErrInvalidChild
+		}
+		child.key = (&btcec.PublicKey{Curve: curve, X: x, Y: y}).SerializeCompressed()
+	}
+	return child, nil
+}
+
+func paddedKey(key []byte) []byte {
+	if len(key) >= 32 {
+		return key
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(key):], key)
+	return padded
+}
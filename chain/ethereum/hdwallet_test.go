@@ -0,0 +1,51 @@
+package ethereum
+
+import (
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestHDWalletDeriveAddresses(t *testing.T) {
+	w, err := NewHDWalletFromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MetaMask 使用该助记词得到的前两个账户
+	expected := []string{
+		"0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+		"0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0",
+	}
+	addresses, err := w.DeriveAddresses(DefaultBaseDerivationPath, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range expected {
+		if addresses[i] != expected[i] {
+			t.Fatalf("account %d: expected %s, got %s", i, expected[i], addresses[i])
+		}
+	}
+
+	xpub, err := w.ExtendedPublicKey(DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubWallet, err := NewHDWalletFromExtendedKey(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pubWallet.IsPublicOnly() {
+		t.Fatal("xpub wallet should be public only")
+	}
+	addr, err := pubWallet.DeriveAddress("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != expected[1] {
+		t.Fatalf("xpub account 1: expected %s, got %s", expected[1], addr)
+	}
+	if _, err := pubWallet.DerivePrivate("0"); err == nil {
+		t.Fatal("xpub wallet should not derive private keys")
+	}
+}
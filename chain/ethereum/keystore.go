@@ -0,0 +1,87 @@
+package ethereum
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/mgintoki/multichain/errno"
+)
+
+const (
+	// KeystoreStandardScryptN 和 KeystoreStandardScryptP 是 geth 默认的 scrypt 参数，加密强度高，解密约需1秒
+	KeystoreStandardScryptN = keystore.StandardScryptN
+	KeystoreStandardScryptP = keystore.StandardScryptP
+	// KeystoreLightScryptN 和 KeystoreLightScryptP 是轻量的 scrypt 参数，适用于测试或移动端
+	KeystoreLightScryptN = keystore.LightScryptN
+	KeystoreLightScryptP = keystore.LightScryptP
+)
+
+// DecryptKeystore 使用密码解密 keystore V3 格式的json，返回其中的私钥
+// 支持 scrypt 和 pbkdf2 两种 kdf
+func DecryptKeystore(keyJSON []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, errno.InvalidKeystore.Add(err.Error())
+	}
+	return key.PrivateKey, nil
+}
+
+// EncryptKeystore 使用密码将私钥加密为 keystore V3 格式的json
+// scryptN 和 scryptP 为 scrypt 的参数，可使用 KeystoreStandardScryptN 等预置值
+func EncryptKeystore(private *ecdsa.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	if private == nil {
+		return nil, errno.PrivateNotSet
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	key := &keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(private.PublicKey),
+		PrivateKey: private,
+	}
+	return keystore.EncryptKey(key, passphrase, scryptN, scryptP)
+}
+
+// NewKeystore 随机生成一个新账户，并使用密码将其加密为 keystore V3 格式的json
+// 返回值 address 为新账户的地址
+func NewKeystore(passphrase string) (keyJSON []byte, address string, err error) {
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	keyJSON, err = EncryptKeystore(private, passphrase, KeystoreStandardScryptN, KeystoreStandardScryptP)
+	if err != nil {
+		return nil, "", err
+	}
+	return keyJSON, crypto.PubkeyToAddress(private.PublicKey).String(), nil
+}
+
+// SetKeystore 使用 keystore V3 格式的json和密码设置 Client 的私钥
+func (c *Client) SetKeystore(keyJSON string, passphrase string) (err error) {
+	private, err := DecryptKeystore([]byte(keyJSON), passphrase)
+	if err != nil {
+		return err
+	}
+	c.signer = NewLocalSigner(private)
+	return nil
+}
+
+// ExportKeystore 使用密码将 Client 当前的私钥导出为 keystore V3 格式的json
+// 只有使用 LocalSigner 时才能导出
+func (c *Client) ExportKeystore(passphrase string) (keyJSON string, err error) {
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
+	}
+	local, ok := c.signer.(*LocalSigner)
+	if !ok {
+		return "", errno.SignerNotSupport
+	}
+	b, err := EncryptKeystore(local.PrivateKey(), passphrase, KeystoreStandardScryptN, KeystoreStandardScryptP)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
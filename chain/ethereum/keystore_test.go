@@ -0,0 +1,67 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/multichain/errno"
+	"testing"
+)
+
+// Web3 Secret Storage Definition 中的标准测试向量，密码为 testpassword
+const (
+	testKeystorePassword = "testpassword"
+	testKeystorePrivate  = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+
+	testKeystoreScrypt = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"r":1,"p":8,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+
+	testKeystorePbkdf2 = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+)
+
+func TestDecryptKeystore(t *testing.T) {
+	for name, keyJSON := range map[string]string{
+		"scrypt": testKeystoreScrypt,
+		"pbkdf2": testKeystorePbkdf2,
+	} {
+		private, err := DecryptKeystore([]byte(keyJSON), testKeystorePassword)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := hex.EncodeToString(crypto.FromECDSA(private)); got != testKeystorePrivate {
+			t.Fatalf("%s: expected private %s, got %s", name, testKeystorePrivate, got)
+		}
+	}
+}
+
+func TestDecryptKeystoreWrongPassphrase(t *testing.T) {
+	_, err := DecryptKeystore([]byte(testKeystorePbkdf2), "wrong")
+	if err == nil {
+		t.Fatal("expected error for wrong passphrase")
+	}
+	var en *errno.Errno
+	if !errors.As(err, &en) || en.State != errno.InvalidKeystore.State {
+		t.Fatalf("expected InvalidKeystore, got %v", err)
+	}
+}
+
+func TestEncryptKeystoreRoundTrip(t *testing.T) {
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := EncryptKeystore(private, "secret", KeystoreLightScryptN, KeystoreLightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptKeystore(keyJSON, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(crypto.FromECDSA(decrypted)) != hex.EncodeToString(crypto.FromECDSA(private)) {
+		t.Fatal("decrypted private key does not match")
+	}
+	if _, err := DecryptKeystore(keyJSON, "other"); err == nil {
+		t.Fatal("expected error for wrong passphrase")
+	}
+}
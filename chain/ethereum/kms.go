@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"bytes"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/asn1"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// DERSigner 将返回 ASN.1 DER 格式 ECDSA 签名的 crypto.Signer 适配为 Signer
+// 云KMS、HSM 等服务的签名结果不带 recovery id，DERSigner 会将其规范化为 low-S，
+// 并根据已知的公钥还原出 V，得到与 Sign 相同的65字节签名
+type DERSigner struct {
+	signer  gocrypto.Signer
+	pub     *ecdsa.PublicKey
+	address web3.Address
+}
+
+// NewDERSigner 使用 crypto.Signer 新建一个 DERSigner
+// signer.Public() 必须返回 secp256k1 曲线上的 *ecdsa.PublicKey
+func NewDERSigner(signer gocrypto.Signer) (*DERSigner, error) {
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok || pub.X == nil {
+		return nil, errno.InvalidTypeAssert.Add("signer public key is not an ecdsa public key")
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) || pub.Curve.Params().N.Cmp(secp256k1N) != 0 {
+		return nil, errno.InvalidSignature.Add("signer public key is not on secp256k1")
+	}
+	return &DERSigner{
+		signer:  signer,
+		pub:     pub,
+		address: web3.Address(crypto.PubkeyToAddress(*pub)),
+	}, nil
+}
+
+func (s *DERSigner) Address() web3.Address {
+	return s.address
+}
+
+// SignHash 对32字节的hash签名
+// opts 传入 crypto.SHA256 仅用于声明摘要长度为32字节 (与KMS的 ECDSA_SHA_256 摘要签名一致)，hash 本身不会被再次哈希
+func (s *DERSigner) SignHash(hash []byte) ([]byte, error) {
+	der, err := s.signer.Sign(rand.Reader, hash, gocrypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertDERSignature(der, hash, s.pub)
+}
+
+func (s *DERSigner) SignTx(tx *web3.Transaction, chainID uint64) (*web3.Transaction, error) {
+	sig, err := s.SignHash(signHash(tx, chainID))
+	if err != nil {
+		return nil, err
+	}
+	return injectSignature(tx, sig, chainID)
+}
+
+// ConvertDERSignature 将 ASN.1 DER 格式的 ECDSA 签名转换为 [R || S || V] 格式的65字节签名
+// S 会被规范化为 low-S (EIP-2)，V 通过对 hash 恢复公钥并与 pub 比较得到
+// 转换结果可以直接用于 Txn.InjectSignature
+func ConvertDERSignature(der []byte, hash []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var rs struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &rs)
+	if err != nil {
+		return nil, errno.InvalidSignature.Add(err.Error())
+	}
+	if len(rest) != 0 {
+		return nil, errno.InvalidSignature.Add("trailing data after DER signature")
+	}
+	if rs.R.Sign() <= 0 || rs.S.Sign() <= 0 || rs.R.Cmp(secp256k1N) >= 0 || rs.S.Cmp(secp256k1N) >= 0 {
+		return nil, errno.InvalidSignature.Add("signature values out of range")
+	}
+	if rs.S.Cmp(secp256k1HalfN) > 0 {
+		rs.S = new(big.Int).Sub(secp256k1N, rs.S)
+	}
+
+	sig := make([]byte, 65)
+	copy(sig[32-len(rs.R.Bytes()):32], rs.R.Bytes())
+	copy(sig[64-len(rs.S.Bytes()):64], rs.S.Bytes())
+
+	expected := crypto.FromECDSAPub(pub)
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		recovered, err := crypto.Ecrecover(hash, sig)
+		if err == nil && bytes.Equal(recovered, expected) {
+			return sig, nil
+		}
+	}
+	return nil, errno.InvalidSignature.Add("signature does not match public key")
+}
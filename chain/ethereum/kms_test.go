@@ -0,0 +1,58 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mgintoki/go-web3"
+	"math/big"
+	"testing"
+)
+
+func TestDERSigner(t *testing.T) {
+	// *ecdsa.PrivateKey 实现了 crypto.Signer, 其签名结果为 ASN.1 DER 格式，与KMS一致
+	private, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewDERSigner(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Address() != NewLocalSigner(private).Address() {
+		t.Fatal("address mismatch")
+	}
+
+	to := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	txn := &Txn{
+		Nonce:    1,
+		Addr:     &to,
+		Value:    big.NewInt(1),
+		GasLimit: 21000,
+		GasPrice: 1,
+	}
+	for i := 0; i < 16; i++ {
+		txn.Nonce = uint64(i)
+		hexHash, err := txn.GetTxHash("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, _ := hex.DecodeString(hexHash)
+		sig, err := signer.SignHash(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if new(big.Int).SetBytes(sig[32:64]).Cmp(secp256k1HalfN) > 0 {
+			t.Fatal("signature is not low-S")
+		}
+		pub, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(private.PublicKey) {
+			t.Fatal("recovered address mismatch")
+		}
+		if err := txn.InjectSignature(hex.EncodeToString(sig), "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
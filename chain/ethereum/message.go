@@ -0,0 +1,61 @@
+package ethereum
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+)
+
+// HashMessage 按照 EIP-191 (personal_sign) 计算消息的hash:
+// keccak256("\x19Ethereum Signed Message:\n" ‖ len(message) ‖ message)
+func HashMessage(message []byte) []byte {
+	return keccak256(append([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))), message...))
+}
+
+// SignMessage 使用私钥按照 EIP-191 对消息签名
+// 返回 [R || S || V] 格式的65字节签名，V为27或28，与钱包 personal_sign 的返回一致
+func SignMessage(private *ecdsa.PrivateKey, message []byte) ([]byte, error) {
+	return signMessage(NewLocalSigner(private), message)
+}
+
+// RecoverAddress 根据消息和 EIP-191 签名恢复签名者地址
+// 签名的V可以是27、28，也可以是0、1
+func RecoverAddress(message []byte, sig []byte) (string, error) {
+	addr, err := recoverSigner(HashMessage(message), sig)
+	if err != nil {
+		return "", err
+	}
+	return checksumAddress(addr), nil
+}
+
+// VerifyMessage 校验签名是否由 address 对消息按照 EIP-191 签出
+func VerifyMessage(message []byte, sig []byte, address string) (bool, error) {
+	signer, err := RecoverAddress(message, sig)
+	if err != nil {
+		return false, err
+	}
+	return web3.HexToAddress(signer) == web3.HexToAddress(address), nil
+}
+
+// SignMessage 使用 Client 的签名者按照 EIP-191 对消息签名，返回16进制格式的签名
+func (c *Client) SignMessage(message []byte) (hexSignature string, err error) {
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
+	}
+	sig, err := signMessage(c.signer, message)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(sig), nil
+}
+
+func signMessage(signer Signer, message []byte) ([]byte, error) {
+	sig, err := signer.SignHash(HashMessage(message))
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
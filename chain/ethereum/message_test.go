@@ -0,0 +1,72 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/crypto"
+	"strings"
+	"testing"
+)
+
+// web3.js 文档中 eth.accounts.sign 的示例
+const (
+	testMessagePrivate   = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testMessageAddress   = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	testMessage          = "Some data"
+	testMessageHash      = "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655"
+	testMessageSignature = "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+)
+
+func TestHashMessage(t *testing.T) {
+	if got := hex.EncodeToString(HashMessage([]byte(testMessage))); got != testMessageHash {
+		t.Fatalf("expected hash %s, got %s", testMessageHash, got)
+	}
+}
+
+func TestSignMessage(t *testing.T) {
+	private, err := crypto.HexToECDSA(testMessagePrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignMessage(private, []byte(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(sig); got != testMessageSignature {
+		t.Fatalf("expected signature %s, got %s", testMessageSignature, got)
+	}
+}
+
+func TestRecoverAddress(t *testing.T) {
+	sig, _ := hex.DecodeString(testMessageSignature)
+
+	// 钱包返回的签名V为27或28，部分签名服务返回0或1，两者都需要支持
+	raw := make([]byte, 65)
+	copy(raw, sig)
+	raw[64] -= 27
+
+	for _, s := range [][]byte{sig, raw} {
+		addr, err := RecoverAddress([]byte(testMessage), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.EqualFold(addr, testMessageAddress) {
+			t.Fatalf("v=%d: expected address %s, got %s", s[64], testMessageAddress, addr)
+		}
+
+		ok, err := VerifyMessage([]byte(testMessage), s, strings.ToLower(testMessageAddress))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("v=%d: signature should verify", s[64])
+		}
+	}
+
+	ok, err := VerifyMessage([]byte("Other data"), sig, testMessageAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("signature should not verify for another message")
+	}
+}
@@ -0,0 +1,99 @@
+package ethereum
+
+import (
+	"errors"
+	"github.com/mgintoki/go-web3/jsonrpc/codec"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/middleware"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nodeErrors 是节点错误信息中的关键字与对应的错误，按顺序匹配
+// 包括 geth 与 BSC 的交易池与 EVM 错误，以及 OKC (ethermint/cosmos-sdk) 的错误
+var nodeErrors = []struct {
+	err      *errno.Errno
+	keywords []string
+}{
+	{errno.NonceTooLow, []string{"nonce too low", "nonce is too low"}},
+	{errno.ReplacementUnderpriced, []string{"replacement transaction underpriced", "replacement underpriced"}},
+	{errno.InsufficientFunds, []string{"insufficient funds", "insufficient balance"}},
+	{errno.GasTooLow, []string{"intrinsic gas too low", "gas too low", "out of gas"}},
+	{errno.ExceedsBlockGasLimit, []string{"exceeds block gas limit", "exceeds the block gas limit", "gas limit reached"}},
+	// hardhat、ganache 等开发节点的错误为 VM Exception while processing transaction: revert ...
+	{errno.ExecutionReverted, []string{"execution reverted", "transaction reverted", "vm exception while processing transaction: revert"}},
+	{errno.RateLimited, []string{"rate limit", "too many requests", "request limit exceeded", "request count exceeded", "exceeded its compute units", "request rate exceeded"}},
+	{errno.ChainIDMismatch, []string{"invalid chain id", "chain id mismatch", "incorrect chain id", "wrong chain id"}},
+	// geth 无法由签名恢复出发起方时返回 invalid sender，链ID不一致、签名损坏都会导致该错误，无法区分
+	{errno.InvalidSignature, []string{"invalid sender"}},
+}
+
+// sequencePattern 匹配 OKC 的 nonce 错误，如 invalid nonce; got 3, expected 5 与 account sequence mismatch, expected 5, got 3
+var sequencePattern = regexp.MustCompile(`(?:got (\d+), expected (\d+))|(?:expected (\d+), got (\d+))`)
+
+// ParseNodeError 将节点返回的错误转换为 errno 中的错误，如 errno.NonceTooLow，可以使用 errors.Is 判断
+// 无法识别的错误原样返回，已经转换过的错误不会重复转换
+func ParseNodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var en *errno.Errno
+	if errors.As(err, &en) {
+		return err
+	}
+
+	msg := err.Error()
+	var obj *codec.ErrorObject
+	if errors.As(err, &obj) {
+		msg = obj.Message
+		// -32005 是 infura 等服务商的请求超限错误码
+		if obj.Code == -32005 || obj.Code == 429 {
+			return errno.RateLimited.Wrap(&nodeError{msg, err})
+		}
+	}
+	lower := strings.ToLower(msg)
+	if lower == "not found" {
+		return errno.NotFound.Wrap(&nodeError{msg, err})
+	}
+	if m := sequencePattern.FindStringSubmatch(lower); m != nil && (strings.Contains(lower, "nonce") || strings.Contains(lower, "sequence")) {
+		got, expected := m[1], m[2]
+		if got == "" {
+			got, expected = m[4], m[3]
+		}
+		g, _ := strconv.ParseUint(got, 10, 64)
+		e, _ := strconv.ParseUint(expected, 10, 64)
+		if g < e {
+			return errno.NonceTooLow.Wrap(&nodeError{msg, err})
+		}
+		return err
+	}
+	for _, ne := range nodeErrors {
+		for _, keyword := range ne.keywords {
+			if strings.Contains(lower, keyword) {
+				return ne.err.Wrap(&nodeError{msg, err})
+			}
+		}
+	}
+	return err
+}
+
+// nodeError 使用节点返回的错误信息作为错误信息，并保留原始错误
+// go-web3 的 codec.ErrorObject 的错误信息是 json 格式的，直接使用不便于阅读
+type nodeError struct {
+	msg string
+	err error
+}
+
+func (e *nodeError) Error() string {
+	return e.msg
+}
+
+func (e *nodeError) Unwrap() error {
+	return e.err
+}
+
+// parseNodeErrors 是将节点错误转换为 errno 错误的拦截器，作为最内层的拦截器使用
+func parseNodeErrors(call *middleware.Call, next func() error) error {
+	return ParseNodeError(next())
+}
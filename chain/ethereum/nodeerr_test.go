@@ -0,0 +1,95 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mgintoki/go-web3/jsonrpc/codec"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/testkit"
+	"testing"
+)
+
+func TestParseNodeError(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect *errno.Errno
+	}{
+		// geth 与 BSC
+		{&codec.ErrorObject{Code: -32000, Message: "nonce too low"}, errno.NonceTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "replacement transaction underpriced"}, errno.ReplacementUnderpriced},
+		{&codec.ErrorObject{Code: -32000, Message: "insufficient funds for gas * price + value"}, errno.InsufficientFunds},
+		{&codec.ErrorObject{Code: -32000, Message: "intrinsic gas too low"}, errno.GasTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "exceeds block gas limit"}, errno.ExceedsBlockGasLimit},
+		{&codec.ErrorObject{Code: 3, Message: "execution reverted: not owner", Data: "0x08c379a0"}, errno.ExecutionReverted},
+		{&codec.ErrorObject{Code: -32000, Message: "invalid sender"}, errno.InvalidSignature},
+		{&codec.ErrorObject{Code: -32000, Message: "not found"}, errno.NotFound},
+		// OKC
+		{&codec.ErrorObject{Code: -32000, Message: "invalid nonce; got 3, expected 5: invalid sequence"}, errno.NonceTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "account sequence mismatch, expected 5, got 3: incorrect account sequence"}, errno.NonceTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "insufficient balance for transfer"}, errno.InsufficientFunds},
+		{&codec.ErrorObject{Code: -32000, Message: "out of gas in location: WriteFlat; gasWanted: 21000"}, errno.GasTooLow},
+		{&codec.ErrorObject{Code: -32000, Message: "invalid chain id for signer"}, errno.ChainIDMismatch},
+		// 服务商限流
+		{&codec.ErrorObject{Code: -32005, Message: "daily request count exceeded"}, errno.RateLimited},
+		{&codec.ErrorObject{Code: 429, Message: "Your app has exceeded its compute units per second capacity"}, errno.RateLimited},
+		{errors.New("Too Many Requests"), errno.RateLimited},
+		// 开发节点
+		{&codec.ErrorObject{Code: -32603, Message: "VM Exception while processing transaction: reverted with reason string 'not owner'"}, errno.ExecutionReverted},
+	}
+	for _, c := range cases {
+		err := ParseNodeError(c.err)
+		if !errors.Is(err, c.expect) {
+			t.Fatalf("expect %v for %q, got %v", c.expect, c.err, err)
+		}
+		if !errors.Is(err, c.err) {
+			t.Fatalf("original error of %q is lost", c.err)
+		}
+		var en *errno.Errno
+		if !errors.As(err, &en) || en.State != c.expect.State {
+			t.Fatalf("expect errno %d for %q", c.expect.State, c.err)
+		}
+	}
+
+	// 无法识别或已经转换过的错误原样返回
+	for _, msg := range []string{
+		"invalid nonce; got 6, expected 5",
+		"log response size limit exceeded",
+		"tx already reverted by the relayer",
+	} {
+		unknown := &codec.ErrorObject{Code: -32000, Message: msg}
+		if err := ParseNodeError(unknown); err != unknown {
+			t.Fatalf("expect unknown error %q unchanged, got %v", msg, err)
+		}
+	}
+	mapped := ParseNodeError(&codec.ErrorObject{Code: -32000, Message: "nonce too low"})
+	if err := ParseNodeError(mapped); err != mapped {
+		t.Fatalf("expect mapped error unchanged, got %v", err)
+	}
+	if errors.Is(mapped, errno.GasTooLow) {
+		t.Fatal("errors.Is should compare the errno state")
+	}
+}
+
+func TestClientNodeErrors(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_gasPrice", "0x1").
+		RespondError("eth_estimateGas", 3, "execution reverted: not owner")
+
+	c, err := NewClient(server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := &Txn{Provider: c.provider}
+	_, err = c.EstimateGas(txn)
+	if !errors.Is(err, errno.ExecutionReverted) {
+		t.Fatalf("expect execution reverted, got %v", err)
+	}
+	var obj *codec.ErrorObject
+	if !errors.As(err, &obj) || obj.Code != 3 {
+		t.Fatalf("expect the json-rpc error to be kept, got %v", err)
+	}
+	if msg := fmt.Sprint(err); msg != "Execution reverted: execution reverted: not owner" {
+		t.Fatalf("unexpected error message %q", msg)
+	}
+}
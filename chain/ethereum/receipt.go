@@ -0,0 +1,142 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/bind"
+	"github.com/mgintoki/multichain/common"
+	"time"
+)
+
+// ContractAddress 返回部署合约交易将要部署的合约地址，由交易的 from 与 nonce 计算得到
+// 非部署合约交易返回空字符串
+func (t *Txn) ContractAddress() string {
+	if !t.isContractDeployment() {
+		return ""
+	}
+	return createAddress(t.From, t.Nonce)
+}
+
+// rpcReceipt 是 eth_getTransactionReceipt 的返回值
+// 不同链与不同版本的节点返回的数字可能是16进制字符串或者数字，统一使用 rpcBig 解析
+type rpcReceipt struct {
+	TransactionIndex  rpcBig        `json:"transactionIndex"`
+	BlockHash         web3.Hash     `json:"blockHash"`
+	BlockNumber       rpcBig        `json:"blockNumber"`
+	GasUsed           rpcBig        `json:"gasUsed"`
+	CumulativeGasUsed rpcBig        `json:"cumulativeGasUsed"`
+	EffectiveGasPrice *rpcBig       `json:"effectiveGasPrice"`
+	ContractAddress   *web3.Address `json:"contractAddress"`
+	Status            *rpcBig       `json:"status"` // 拜占庭分叉之前的收据没有 status
+	Logs              []*rpcLog     `json:"logs"`
+}
+
+type rpcLog struct {
+	Address  web3.Address  `json:"address"`
+	Topics   []web3.Hash   `json:"topics"`
+	Data     hexutil.Bytes `json:"data"`
+	LogIndex rpcBig        `json:"logIndex"`
+}
+
+// rpcPendingTx 是尚未上链的交易，go-web3 无法解析 blockHash 为 null 的交易
+type rpcPendingTx struct {
+	From     web3.Address  `json:"from"`
+	To       *web3.Address `json:"to"`
+	Nonce    rpcBig        `json:"nonce"`
+	Gas      rpcBig        `json:"gas"`
+	GasPrice rpcBig        `json:"gasPrice"`
+	Value    rpcBig        `json:"value"`
+	Input    hexutil.Bytes `json:"input"`
+}
+
+// fillPendingTx 将节点交易池中的交易信息填入交易详情，节点中不存在该交易时不做修改
+func (c *Client) fillPendingTx(txData *tx.TxData, hash web3.Hash) error {
+	var raw json.RawMessage
+	if err := c.provider.Call("eth_getTransactionByHash", &raw, hash); err != nil {
+		return err
+	}
+	var pending *rpcPendingTx
+	if err := json.Unmarshal(raw, &pending); err != nil {
+		return err
+	}
+	if pending == nil {
+		return nil
+	}
+
+	txData.From = pending.From.String()
+	if pending.To != nil {
+		txData.To = pending.To.String()
+	} else {
+		txData.ContractAddress = createAddress(pending.From, pending.Nonce.Int().Uint64())
+	}
+	txData.Data = pending.Input
+	txData.GasPrice = pending.GasPrice.Int().Uint64()
+	txData.GasLimit = pending.Gas.Int().Uint64()
+	txData.Nonce = pending.Nonce.Int().Uint64()
+	txData.Value = pending.Value.Int()
+	txData.Raw = raw
+	return nil
+}
+
+func (c *Client) getReceipt(hash web3.Hash) (*rpcReceipt, error) {
+	var receipt *rpcReceipt
+	if err := c.provider.Call("eth_getTransactionReceipt", &receipt, hash); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// fillReceipt 将收据与区块中的信息填入交易详情，contractAbi 不为nil时解析日志
+func (c *Client) fillReceipt(txData *tx.TxData, receipt *rpcReceipt, contractAbi *ContractABI) error {
+	if receipt.Status == nil || receipt.Status.Int().Uint64() == 1 {
+		txData.Status = common.TxStatusSuccess
+	} else {
+		txData.Status = common.TxStatusFailed
+	}
+	if receipt.ContractAddress != nil {
+		txData.ContractAddress = checksumAddress(*receipt.ContractAddress)
+	}
+	txData.GasUsed = receipt.GasUsed.Int().Uint64()
+	txData.BlockNumber = receipt.BlockNumber.Int().Uint64()
+	txData.BlockHash = receipt.BlockHash.String()
+	txData.TxIndex = receipt.TransactionIndex.Int().Uint64()
+	txData.CumulativeGasUsed = receipt.CumulativeGasUsed.Int().Uint64()
+	txData.EffectiveGasPrice = txData.GasPrice
+	if receipt.EffectiveGasPrice != nil {
+		txData.EffectiveGasPrice = receipt.EffectiveGasPrice.Int().Uint64()
+	}
+
+	txData.Logs = make([]*tx.Log, 0, len(receipt.Logs))
+	for _, l := range receipt.Logs {
+		log := &tx.Log{
+			Address:  checksumAddress(l.Address),
+			Data:     l.Data,
+			LogIndex: l.LogIndex.Int().Uint64(),
+		}
+		for _, topic := range l.Topics {
+			log.Topics = append(log.Topics, topic.String())
+		}
+		if contractAbi != nil && len(l.Topics) != 0 {
+			if event, ok := contractAbi.EventByID(l.Topics[0]); ok {
+				//不同合约的事件可能有相同的 topic0 但 indexed 参数不同 (如 ERC20 与 ERC721 的 Transfer)，解析失败时保留原始日志
+				decoded, err := bind.ParseLog(event, &web3.Log{Address: l.Address, Topics: l.Topics, Data: l.Data})
+				if err == nil {
+					log.Event = event.Sig()
+					log.Decoded = decoded
+				}
+			}
+		}
+		txData.Logs = append(txData.Logs, log)
+	}
+
+	block, err := c.provider.Eth().GetBlockByHash(receipt.BlockHash, false)
+	if err != nil {
+		return err
+	}
+	if block != nil {
+		txData.Date = time.Unix(int64(block.Timestamp), 0).UTC().Format(time.RFC3339)
+	}
+	return nil
+}
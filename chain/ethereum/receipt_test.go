@@ -0,0 +1,98 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/common"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTxnContractAddress(t *testing.T) {
+	txn := &Txn{From: web3.HexToAddress("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"), Nonce: 1}
+	if addr := txn.ContractAddress(); addr != "0x343c43A37D37dfF08AE8C4A11544c718AbB4fCF8" {
+		t.Fatalf("unexpected contract address %s", addr)
+	}
+	to := web3.HexToAddress(DefaultAddress)
+	txn.Addr = &to
+	if addr := txn.ContractAddress(); addr != "" {
+		t.Fatalf("expect empty contract address, got %s", addr)
+	}
+}
+
+func TestQueryTxWithAbi(t *testing.T) {
+	const (
+		txHash    = "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+		blockHash = "0x1d59ff54b1eb26b013ce3cb5fc9dab3705b415a67127a003c3e61eb445bb8df2"
+		sender    = "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"
+		token     = "0x3f43e75aaba2c2fd6e227c10c6e7dc125a93de3c"
+	)
+	mined := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		result := `null`
+		switch req.Method {
+		case "eth_getTransactionReceipt":
+			if mined {
+				result = `{"transactionIndex":"0x2","blockHash":"` + blockHash + `","blockNumber":"0x10","gasUsed":"0x5208","cumulativeGasUsed":"0x10000",
+					"effectiveGasPrice":"0x3b9aca00","contractAddress":null,"status":"0x1","logs":[{"address":"` + token + `","logIndex":"0x5","data":"0x00000000000000000000000000000000000000000000000000000000000003e8",
+					"topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef","0x0000000000000000000000006ac7ea33f8831ea9dcc53393aaa88b25a785dbf0","0x0000000000000000000000003f43e75aaba2c2fd6e227c10c6e7dc125a93de3c"]}]}`
+			}
+		case "eth_getTransactionByHash":
+			blockFields := `"blockHash":null,"blockNumber":null,"transactionIndex":null`
+			if mined {
+				blockFields = `"blockHash":"` + blockHash + `","blockNumber":"0x10","transactionIndex":"0x2"`
+			}
+			result = `{"hash":"` + txHash + `","from":"` + sender + `","to":null,"nonce":"0x1","gas":"0x10000","gasPrice":"0x77359400","value":"0x0","input":"0x6080",
+				"v":"0x25","r":"0x1","s":"0x1",` + blockFields + `}`
+		case "eth_getBlockByHash":
+			result = `{"hash":"` + blockHash + `","parentHash":"` + blockHash + `","sha3Uncles":"` + blockHash + `","transactionsRoot":"` + blockHash + `","stateRoot":"` + blockHash + `",
+				"receiptsRoot":"` + blockHash + `","miner":"` + token + `","number":"0x10","gasLimit":"0x1000000","gasUsed":"0x10000","timestamp":"0x61f4b3c0","difficulty":"0x1","extraData":"0x","transactions":[],"uncles":[]}`
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient(provider.CommonProvider{ProviderUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := c.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Status != common.TxStatusPending || pending.Nonce != 1 || pending.ContractAddress != "0x343c43A37D37dfF08AE8C4A11544c718AbB4fCF8" {
+		t.Fatalf("unexpected pending tx %+v", pending)
+	}
+
+	mined = true
+	txData, err := c.QueryTxWithAbi(txHash, false, "event Transfer(address indexed from, address indexed to, uint256 value)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || txData.BlockHash != blockHash || txData.TxIndex != 2 || txData.GasUsed != 21000 ||
+		txData.CumulativeGasUsed != 0x10000 || txData.GasPrice != 2e9 || txData.EffectiveGasPrice != 1e9 || txData.Date != "2022-01-29T03:25:52Z" {
+		t.Fatalf("unexpected tx data %+v", txData)
+	}
+	if len(txData.Logs) != 1 {
+		t.Fatalf("expect 1 log, got %d", len(txData.Logs))
+	}
+	log := txData.Logs[0]
+	if log.LogIndex != 5 || log.Event != "Transfer(address,address,uint256)" {
+		t.Fatalf("unexpected log %+v", log)
+	}
+	if log.Decoded["from"] != web3.HexToAddress(sender) || log.Decoded["value"].(*big.Int).Int64() != 1000 {
+		t.Fatalf("unexpected decoded log %v", log.Decoded)
+	}
+}
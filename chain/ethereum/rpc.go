@@ -0,0 +1,193 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/jsonrpc/transport"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/middleware"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RPCClient 是节点的 JSON-RPC 客户端，与 go-web3 的 jsonrpc.Client 用法相同
+// 所有请求都经过 provider 中的拦截器，节点返回的错误会经过 ParseNodeError 转换
+type RPCClient struct {
+	transport transport.Transport
+	eth       *RPCEth
+}
+
+// NewRPCClient 创建连接到 p.ProviderUrl 的 JSON-RPC 客户端，并设置 provider 中的拦截器
+// provider 中的拦截器得到的是经过 ParseNodeError 转换后的错误
+func NewRPCClient(p provider.CommonProvider) (*RPCClient, error) {
+	t, err := transport.NewTransport(p.ProviderUrl)
+	if err != nil {
+		return nil, err
+	}
+	chain := p.Chain
+	if chain == "" {
+		chain = "ethereum"
+	}
+	interceptors := append(p.Interceptors[:len(p.Interceptors):len(p.Interceptors)], parseNodeErrors)
+	return NewRPCClientWithTransport(middleware.WrapTransport(t, chain, p.ProviderUrl, interceptors...)), nil
+}
+
+// NewRPCClientWithTransport 使用 t 创建 JSON-RPC 客户端，t 可以是 middleware.WrapTransport 包装后的 transport
+func NewRPCClientWithTransport(t transport.Transport) *RPCClient {
+	c := &RPCClient{transport: t}
+	c.eth = &RPCEth{c}
+	return c
+}
+
+// Call 发送 JSON-RPC 请求，结果解析到 out
+func (c *RPCClient) Call(method string, out interface{}, params ...interface{}) error {
+	return c.transport.Call(method, out, params...)
+}
+
+// Close 关闭连接
+func (c *RPCClient) Close() error {
+	return c.transport.Close()
+}
+
+// Eth 返回 eth 命名空间
+func (c *RPCClient) Eth() *RPCEth {
+	return c.eth
+}
+
+// RPCEth 是 eth 命名空间的 JSON-RPC 方法
+type RPCEth struct {
+	c *RPCClient
+}
+
+// ChainID 返回链ID
+func (e *RPCEth) ChainID() (*big.Int, error) {
+	var out string
+	if err := e.c.Call("eth_chainId", &out); err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(out, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid chain id %q", out)
+	}
+	return n, nil
+}
+
+// BlockNumber 返回最新区块号
+func (e *RPCEth) BlockNumber() (uint64, error) {
+	var out string
+	if err := e.c.Call("eth_blockNumber", &out); err != nil {
+		return 0, err
+	}
+	return parseUint64OrHex(out)
+}
+
+// GetBlockByHash 按区块hash查询区块
+func (e *RPCEth) GetBlockByHash(hash web3.Hash, full bool) (*web3.Block, error) {
+	var b *web3.Block
+	if err := e.c.Call("eth_getBlockByHash", &b, hash, full); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetTransactionByHash 按交易hash查询交易
+func (e *RPCEth) GetTransactionByHash(hash web3.Hash) (*web3.Transaction, error) {
+	var txn *web3.Transaction
+	err := e.c.Call("eth_getTransactionByHash", &txn, hash)
+	return txn, err
+}
+
+// GetTransactionReceipt 按交易hash查询交易回执
+func (e *RPCEth) GetTransactionReceipt(hash web3.Hash) (*web3.Receipt, error) {
+	var receipt *web3.Receipt
+	err := e.c.Call("eth_getTransactionReceipt", &receipt, hash)
+	return receipt, err
+}
+
+// SendRawTransaction 广播签名交易
+func (e *RPCEth) SendRawTransaction(data []byte) (web3.Hash, error) {
+	var hash web3.Hash
+	err := e.c.Call("eth_sendRawTransaction", &hash, "0x"+hex.EncodeToString(data))
+	return hash, err
+}
+
+// GetNonce 返回账户的 nonce
+func (e *RPCEth) GetNonce(addr web3.Address, blockNumber web3.BlockNumber) (uint64, error) {
+	var out string
+	if err := e.c.Call("eth_getTransactionCount", &out, addr, blockNumber.String()); err != nil {
+		return 0, err
+	}
+	return parseUint64OrHex(out)
+}
+
+// GetBalance 返回账户余额
+func (e *RPCEth) GetBalance(addr web3.Address, blockNumber web3.BlockNumber) (*big.Int, error) {
+	var out string
+	if err := e.c.Call("eth_getBalance", &out, addr, blockNumber.String()); err != nil {
+		return nil, err
+	}
+	b, ok := new(big.Int).SetString(strings.TrimPrefix(out, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %q", out)
+	}
+	return b, nil
+}
+
+// GetCode 返回合约代码
+func (e *RPCEth) GetCode(addr web3.Address) (string, error) {
+	var out string
+	if err := e.c.Call("eth_getCode", &out, addr, "latest"); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// GasPrice 返回节点建议的 gas price
+func (e *RPCEth) GasPrice() (uint64, error) {
+	var out string
+	if err := e.c.Call("eth_gasPrice", &out); err != nil {
+		return 0, err
+	}
+	return parseUint64OrHex(out)
+}
+
+// Call 执行 eth_call，返回16进制格式的结果
+func (e *RPCEth) Call(msg *web3.CallMsg, block web3.BlockNumber) (string, error) {
+	var out string
+	if err := e.c.Call("eth_call", &out, msg, block.String()); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// EstimateGas 估算交易需要的 gas
+func (e *RPCEth) EstimateGas(msg *web3.CallMsg) (uint64, error) {
+	var out string
+	if err := e.c.Call("eth_estimateGas", &out, msg); err != nil {
+		return 0, err
+	}
+	return parseUint64OrHex(out)
+}
+
+// EstimateGasContractWithFrom 估算 from 部署合约需要的 gas
+func (e *RPCEth) EstimateGasContractWithFrom(from web3.Address, bin []byte) (uint64, error) {
+	var out string
+	msg := map[string]interface{}{
+		"data": "0x" + hex.EncodeToString(bin),
+		"from": from,
+	}
+	if err := e.c.Call("eth_estimateGas", &out, msg); err != nil {
+		return 0, err
+	}
+	return parseUint64OrHex(out)
+}
+
+// parseUint64OrHex 解析节点返回的数字，带0x前缀的按16进制解析，否则按10进制解析
+func parseUint64OrHex(s string) (uint64, error) {
+	if strings.HasPrefix(s, "0x") {
+		return strconv.ParseUint(s[2:], 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
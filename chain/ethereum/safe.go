@@ -0,0 +1,380 @@
+package ethereum
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"sort"
+)
+
+// SafeABI 是 Safe (Gnosis Safe) 多签钱包合约中本包使用到的方法的 ABI
+const SafeABI = `[
+{"type":"function","name":"execTransaction","stateMutability":"payable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},{"name":"signatures","type":"bytes"}],"outputs":[{"name":"success","type":"bool"}]},
+{"type":"function","name":"approveHash","stateMutability":"nonpayable","inputs":[{"name":"hashToApprove","type":"bytes32"}],"outputs":[]},
+{"type":"function","name":"approvedHashes","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"hash","type":"bytes32"}],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"getThreshold","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"getOwners","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+{"type":"function","name":"nonce","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"VERSION","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]}
+]`
+
+// Safe 交易的操作类型
+const (
+	SafeCall         = 0
+	SafeDelegateCall = 1
+)
+
+// Safe 签名的类型，见 Safe 合约 checkNSignatures 的定义
+const (
+	SafeSignatureEOA          = iota // EOA 对 SafeTx EIP-712 hash 的签名，v 为27或28
+	SafeSignatureEthSign             // EOA 对 SafeTx hash 的 eth_sign 签名，v 为31或32
+	SafeSignatureApprovedHash        // owner 已在链上调用 approveHash，或 owner 即为 execTransaction 的发起方
+	SafeSignatureContract            // 合约 owner 的 EIP-1271 签名
+)
+
+// SafeTx 是 Safe 多签钱包要执行的交易
+type SafeTx struct {
+	Safe           web3.Address // Safe 合约地址
+	ChainID        *big.Int     // 链ID，Safe 1.3.0 之前的版本的域中不包含链ID，此时应为nil
+	To             web3.Address
+	Value          *big.Int
+	Data           []byte
+	Operation      uint8 // SafeCall 或 SafeDelegateCall
+	SafeTxGas      *big.Int
+	BaseGas        *big.Int
+	GasPrice       *big.Int
+	GasToken       web3.Address
+	RefundReceiver web3.Address
+	Nonce          *big.Int // Safe 的nonce，可以通过 Client.GetSafeInfo 获得
+}
+
+// SafeInfo 是 Safe 多签钱包的链上状态
+type SafeInfo struct {
+	Address   string
+	Version   string
+	Threshold uint64
+	Owners    []string
+	Nonce     *big.Int
+}
+
+// SafeSignature 是 Safe owner 对 SafeTx 的一个签名
+type SafeSignature struct {
+	Type   int          // 签名类型，如 SafeSignatureEOA
+	Signer web3.Address // 签名的 owner
+	Data   []byte       // EOA 签名为65字节的 [R || S || V]，合约签名为 EIP-1271 签名，approved hash 签名为空
+}
+
+// TypedData 返回 SafeTx 对应的 EIP-712 结构化数据
+func (st *SafeTx) TypedData() *TypedData {
+	domain := []TypedDataField{{Name: "verifyingContract", Type: "address"}}
+	if st.ChainID != nil {
+		domain = []TypedDataField{{Name: "chainId", Type: "uint256"}, {Name: "verifyingContract", Type: "address"}}
+	}
+	return &TypedData{
+		Types: map[string][]TypedDataField{
+			eip712DomainType: domain,
+			"SafeTx": {
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "data", Type: "bytes"},
+				{Name: "operation", Type: "uint8"},
+				{Name: "safeTxGas", Type: "uint256"},
+				{Name: "baseGas", Type: "uint256"},
+				{Name: "gasPrice", Type: "uint256"},
+				{Name: "gasToken", Type: "address"},
+				{Name: "refundReceiver", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SafeTx",
+		Domain: TypedDataDomain{
+			ChainID:           st.ChainID,
+			VerifyingContract: st.Safe.String(),
+		},
+		Message: map[string]interface{}{
+			"to":             st.To,
+			"value":          bigOrZero(st.Value),
+			"data":           st.data(),
+			"operation":      uint64(st.Operation),
+			"safeTxGas":      bigOrZero(st.SafeTxGas),
+			"baseGas":        bigOrZero(st.BaseGas),
+			"gasPrice":       bigOrZero(st.GasPrice),
+			"gasToken":       st.GasToken,
+			"refundReceiver": st.RefundReceiver,
+			"nonce":          bigOrZero(st.Nonce),
+		},
+	}
+}
+
+// Hash 计算 SafeTx 的 EIP-712 hash，与 Safe 合约 getTransactionHash 的返回一致
+func (st *SafeTx) Hash() ([]byte, error) {
+	return st.TypedData().Hash()
+}
+
+// SignSafeTx 使用 Signer 对 SafeTx 的 EIP-712 hash 签名
+func SignSafeTx(signer Signer, st *SafeTx) (*SafeSignature, error) {
+	sig, err := SignTypedData(signer, st.TypedData())
+	if err != nil {
+		return nil, err
+	}
+	return &SafeSignature{Type: SafeSignatureEOA, Signer: signer.Address(), Data: sig}, nil
+}
+
+// SignSafeTxEthSign 使用 Signer 按照 eth_sign (EIP-191) 对 SafeTx hash 签名
+// 用于不支持 EIP-712 的硬件钱包，签名的 V 会加4以便 Safe 合约区分
+func SignSafeTxEthSign(signer Signer, st *SafeTx) (*SafeSignature, error) {
+	hash, err := st.Hash()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signMessage(signer, hash)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 4
+	return &SafeSignature{Type: SafeSignatureEthSign, Signer: signer.Address(), Data: sig}, nil
+}
+
+// NewSafeEOASignature 使用外部收集到的 EIP-712 或 eth_sign 签名新建 SafeSignature
+// 会根据V判断签名类型，并由签名恢复出签名的 owner
+func NewSafeEOASignature(st *SafeTx, sig []byte) (*SafeSignature, error) {
+	if len(sig) != 65 {
+		return nil, errno.InvalidSignature.Add("signature must be 65 bytes")
+	}
+	hash, err := st.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SafeSignature{Type: SafeSignatureEOA, Data: append([]byte{}, sig...)}
+	switch v := sig[64]; {
+	case v == 0 || v == 1:
+		s.Data[64] += 27
+	case v == 27 || v == 28:
+	case v == 31 || v == 32:
+		s.Type = SafeSignatureEthSign
+		hash = HashMessage(hash)
+	default:
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("invalid v %d", v))
+	}
+
+	recovered := append([]byte{}, s.Data...)
+	recovered[64] = (recovered[64] - 27) % 4
+	if s.Signer, err = recoverSigner(hash, recovered); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSafeApprovedHashSignature 新建 approved hash 类型的签名
+// owner 需要已经在链上对 SafeTx hash 调用过 approveHash，或者 owner 就是 execTransaction 交易的发起方
+func NewSafeApprovedHashSignature(owner web3.Address) *SafeSignature {
+	return &SafeSignature{Type: SafeSignatureApprovedHash, Signer: owner}
+}
+
+// NewSafeContractSignature 新建合约 owner 的 EIP-1271 签名
+// Safe 会调用 owner 合约的 isValidSignature 校验 data
+func NewSafeContractSignature(owner web3.Address, data []byte) *SafeSignature {
+	return &SafeSignature{Type: SafeSignatureContract, Signer: owner, Data: data}
+}
+
+// PackSafeSignatures 将签名按 owner 地址升序排序并打包为 execTransaction 的 signatures 参数
+// 每个签名在静态部分占65字节，合约签名的数据追加在动态部分，静态部分记录其偏移量
+// 同一 owner 的多个签名只保留第一个
+func PackSafeSignatures(sigs []*SafeSignature) ([]byte, error) {
+	sorted := make([]*SafeSignature, 0, len(sigs))
+	seen := map[web3.Address]bool{}
+	for _, s := range sigs {
+		if seen[s.Signer] {
+			continue
+		}
+		seen[s.Signer] = true
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Signer[:], sorted[j].Signer[:]) < 0
+	})
+
+	var static, dynamic []byte
+	for _, s := range sorted {
+		switch s.Type {
+		case SafeSignatureEOA, SafeSignatureEthSign:
+			if len(s.Data) != 65 {
+				return nil, errno.InvalidSignature.Add(fmt.Sprintf("signature of %s must be 65 bytes", s.Signer))
+			}
+			static = append(static, s.Data...)
+		case SafeSignatureApprovedHash:
+			static = append(static, leftPad32(s.Signer[:])...)
+			static = append(static, make([]byte, 32)...)
+			static = append(static, 1)
+		case SafeSignatureContract:
+			offset := big.NewInt(int64(65*len(sorted) + len(dynamic)))
+			static = append(static, leftPad32(s.Signer[:])...)
+			static = append(static, leftPad32(offset.Bytes())...)
+			static = append(static, 0)
+			dynamic = append(dynamic, leftPad32(big.NewInt(int64(len(s.Data))).Bytes())...)
+			dynamic = append(dynamic, s.Data...)
+		default:
+			return nil, errno.InvalidSignature.Add(fmt.Sprintf("unknown safe signature type %d", s.Type))
+		}
+	}
+	return append(static, dynamic...), nil
+}
+
+// BuildSafeExecTxReq 是构建 Safe execTransaction 交易的参数
+type BuildSafeExecTxReq struct {
+	From       string           // execTransaction 交易的发起方，可以不是 owner
+	SafeTx     *SafeTx          // 要执行的 SafeTx
+	Signatures []*SafeSignature // 收集到的 owner 签名，数量需要达到 Safe 的阈值
+	Nonce      uint64           // 可选，不传则内部计算nonce
+	GasLimit   uint64           // 可选，不传则内部计算推荐值并使用
+	GasPrice   uint64           // 可选，不传则内部计算推荐值并使用
+}
+
+// BuildSafeExecTx 构建调用 Safe execTransaction 执行 SafeTx 的交易
+func (b *ContractTxBuilder) BuildSafeExecTx(req BuildSafeExecTxReq) (tx.Tx, error) {
+	st := req.SafeTx
+	if st == nil {
+		return nil, errno.InvalidTx.Add("safe tx not set")
+	}
+	signatures, err := PackSafeSignatures(req.Signatures)
+	if err != nil {
+		return nil, err
+	}
+	return b.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            req.From,
+		Abi:             SafeABI,
+		Method:          "execTransaction",
+		ContractAddress: st.Safe.String(),
+		Params: []interface{}{
+			st.To, bigOrZero(st.Value), st.data(), st.Operation, bigOrZero(st.SafeTxGas), bigOrZero(st.BaseGas),
+			bigOrZero(st.GasPrice), st.GasToken, st.RefundReceiver, signatures,
+		},
+		Nonce:    req.Nonce,
+		GasLimit: req.GasLimit,
+		GasPrice: req.GasPrice,
+	})
+}
+
+// BuildSafeApproveHashTx 构建 owner 在链上调用 approveHash 批准 SafeTx 的交易
+// 交易上链后，可以使用 NewSafeApprovedHashSignature 作为该 owner 的签名
+func (b *ContractTxBuilder) BuildSafeApproveHashTx(owner string, st *SafeTx) (tx.Tx, error) {
+	hash, err := st.Hash()
+	if err != nil {
+		return nil, err
+	}
+	var h [32]byte
+	copy(h[:], hash)
+	return b.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            owner,
+		Abi:             SafeABI,
+		Method:          "approveHash",
+		ContractAddress: st.Safe.String(),
+		Params:          []interface{}{h},
+	})
+}
+
+// GetSafeInfo 查询 Safe 多签钱包的版本、阈值、owner 列表和nonce
+func (c *Client) GetSafeInfo(safeAddress string) (*SafeInfo, error) {
+	contract, err := c.safeContract(safeAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SafeInfo{Address: checksumAddress(contract.Addr)}
+	if _, res, err := contract.Call("VERSION", web3.Latest); err == nil {
+		info.Version, _ = res["0"].(string)
+	}
+
+	_, res, err := contract.Call("getThreshold", web3.Latest)
+	if err != nil {
+		return nil, err
+	}
+	threshold, ok := res["0"].(*big.Int)
+	if !ok {
+		return nil, errno.InvalidTypeAssert.Add("getThreshold")
+	}
+	info.Threshold = threshold.Uint64()
+
+	_, res, err = contract.Call("getOwners", web3.Latest)
+	if err != nil {
+		return nil, err
+	}
+	owners, ok := res["0"].([]web3.Address)
+	if !ok {
+		return nil, errno.InvalidTypeAssert.Add("getOwners")
+	}
+	for _, owner := range owners {
+		info.Owners = append(info.Owners, checksumAddress(owner))
+	}
+
+	_, res, err = contract.Call("nonce", web3.Latest)
+	if err != nil {
+		return nil, err
+	}
+	if info.Nonce, ok = res["0"].(*big.Int); !ok {
+		return nil, errno.InvalidTypeAssert.Add("nonce")
+	}
+	return info, nil
+}
+
+// IsSafeHashApproved 查询 owner 是否已经在链上批准了 SafeTx
+func (c *Client) IsSafeHashApproved(st *SafeTx, owner string) (bool, error) {
+	contract, err := c.safeContract(st.Safe.String())
+	if err != nil {
+		return false, err
+	}
+	hash, err := st.Hash()
+	if err != nil {
+		return false, err
+	}
+	var h [32]byte
+	copy(h[:], hash)
+	_, res, err := contract.Call("approvedHashes", web3.Latest, web3.HexToAddress(owner), h)
+	if err != nil {
+		return false, err
+	}
+	approved, ok := res["0"].(*big.Int)
+	if !ok {
+		return false, errno.InvalidTypeAssert.Add("approvedHashes")
+	}
+	return approved.Sign() != 0, nil
+}
+
+func (c *Client) safeContract(safeAddress string) (*Contract, error) {
+	if c.provider == nil {
+		return nil, errno.ProviderNotSet
+	}
+	abiIns, err := abi.NewABI(SafeABI)
+	if err != nil {
+		return nil, err
+	}
+	contract := NewContract(web3.HexToAddress(safeAddress), abiIns, c.provider)
+	contract.SetFrom(web3.HexToAddress(DefaultAddress))
+	return contract, nil
+}
+
+func (st *SafeTx) data() []byte {
+	if st.Data == nil {
+		return []byte{}
+	}
+	return st.Data
+}
+
+func bigOrZero(i *big.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+	return i
+}
+
+func leftPad32(b []byte) []byte {
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
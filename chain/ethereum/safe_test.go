@@ -0,0 +1,73 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/mgintoki/go-web3"
+	"math/big"
+	"testing"
+)
+
+func TestSafeTx(t *testing.T) {
+	st := &SafeTx{
+		Safe:    web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c"),
+		ChainID: big.NewInt(1),
+		To:      web3.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"),
+		Value:   big.NewInt(1000),
+		Nonce:   big.NewInt(3),
+	}
+
+	// 与 Safe 合约中的 SAFE_TX_TYPEHASH、DOMAIN_SEPARATOR_TYPEHASH 一致
+	td := st.TypedData()
+	if h := hex.EncodeToString(td.TypeHash("SafeTx")); h != "bb8310d486368db6bd6f849402fdd73ad53d316b5a4b2644ad6efe0f941286d8" {
+		t.Fatalf("unexpected SafeTx type hash %s", h)
+	}
+	if h := hex.EncodeToString(td.TypeHash(eip712DomainType)); h != "47e79534a245952e8b16893a336b85a3d9ea9fa8c573f3d803afb92a79469218" {
+		t.Fatalf("unexpected domain type hash %s", h)
+	}
+
+	owner1, _ := NewLocalSignerFromHex("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	owner2, _ := NewLocalSignerFromHex("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sig1, err := SignSafeTx(owner1, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := SignSafeTxEthSign(owner2, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*SafeSignature{sig1, sig2} {
+		collected, err := NewSafeEOASignature(st, s.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if collected.Signer != s.Signer || collected.Type != s.Type {
+			t.Fatalf("unexpected collected signature %+v", collected)
+		}
+	}
+
+	approver := web3.HexToAddress("0x0000000000000000000000000000000000000001")
+	contractOwner := web3.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff")
+	packed, err := PackSafeSignatures([]*SafeSignature{
+		NewSafeContractSignature(contractOwner, []byte{0xab, 0xcd}),
+		sig1, sig2, sig1,
+		NewSafeApprovedHashSignature(approver),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packed) != 4*65+32+2 {
+		t.Fatalf("unexpected packed length %d", len(packed))
+	}
+	// 第一个为地址最小的 approved hash 签名，最后一个为地址最大的合约签名
+	if !bytes.Equal(packed[12:32], approver[:]) || packed[64] != 1 {
+		t.Fatalf("unexpected approved hash signature %x", packed[:65])
+	}
+	contractSig := packed[3*65 : 4*65]
+	if !bytes.Equal(contractSig[12:32], contractOwner[:]) || new(big.Int).SetBytes(contractSig[32:64]).Int64() != 4*65 || contractSig[64] != 0 {
+		t.Fatalf("unexpected contract signature %x", contractSig)
+	}
+	if !bytes.Equal(packed[4*65+32:], []byte{0xab, 0xcd}) {
+		t.Fatalf("unexpected dynamic part %x", packed[4*65:])
+	}
+}
@@ -4,23 +4,81 @@ import (
 	"crypto/ecdsa"
 	"encoding/hex"
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mgintoki/go-web3"
-	"github.com/umbracle/fastrlp"
+	"github.com/mgintoki/multichain/errno"
 	"golang.org/x/crypto/sha3"
 	"math/big"
 	"strings"
 )
 
-func SignTx(tx *web3.Transaction, private *ecdsa.PrivateKey, chainID uint64) (*web3.Transaction, error) {
-	hash := signHash(tx, chainID)
+// Signer 定义了以太坊账户的签名者
+// Client 和 Txn 只通过该接口签名，私钥可以保存在进程内 (LocalSigner)，
+// 也可以交给外部的签名服务 (ExternalSigner)
+type Signer interface {
+	// Address 返回签名者的账户地址
+	Address() web3.Address
+
+	// SignHash 对32字节的hash签名，返回 [R || S || V] 格式的65字节签名，其中V为0或1
+	SignHash(hash []byte) ([]byte, error)
+
+	// SignTx 按照 EIP-155 对交易签名，返回填充了 V、R、S 的交易
+	SignTx(tx *web3.Transaction, chainID uint64) (*web3.Transaction, error)
+}
+
+// LocalSigner 是使用进程内私钥签名的 Signer
+type LocalSigner struct {
+	private *ecdsa.PrivateKey
+}
 
-	sig, err := Sign(private, hash)
+// NewLocalSigner 使用私钥新建一个 LocalSigner
+func NewLocalSigner(private *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{private: private}
+}
+
+// NewLocalSignerFromHex 使用16进制格式的私钥新建一个 LocalSigner
+func NewLocalSignerFromHex(hexPrivate string) (*LocalSigner, error) {
+	private, err := crypto.HexToECDSA(strings.TrimPrefix(hexPrivate, "0x"))
 	if err != nil {
 		return nil, err
 	}
+	return NewLocalSigner(private), nil
+}
+
+func (s *LocalSigner) Address() web3.Address {
+	return web3.Address(crypto.PubkeyToAddress(s.private.PublicKey))
+}
+
+func (s *LocalSigner) SignHash(hash []byte) ([]byte, error) {
+	return Sign(s.private, hash)
+}
+
+func (s *LocalSigner) SignTx(tx *web3.Transaction, chainID uint64) (*web3.Transaction, error) {
+	sig, err := s.SignHash(signHash(tx, chainID))
+	if err != nil {
+		return nil, err
+	}
+	return injectSignature(tx, sig, chainID)
+}
+
+// PrivateKey 返回 LocalSigner 使用的私钥
+func (s *LocalSigner) PrivateKey() *ecdsa.PrivateKey {
+	return s.private
+}
+
+func SignTx(tx *web3.Transaction, private *ecdsa.PrivateKey, chainID uint64) (*web3.Transaction, error) {
+	return NewLocalSigner(private).SignTx(tx, chainID)
+}
+
+// injectSignature 将 [R || S || V] 格式的签名按照 EIP-155 填充到交易中
+func injectSignature(tx *web3.Transaction, sig []byte, chainID uint64) (*web3.Transaction, error) {
+	if len(sig) != 65 {
+		return nil, errno.InvalidSignature.Add("signature must be 65 bytes")
+	}
 
 	vv := uint64(sig[64]) + 35 + chainID*2
 
+	var err error
 	tx.R, tx.S, err = trimLeadingZero(sig[:32], sig[32:64])
 	if err != nil {
 		return nil, err
@@ -31,7 +89,7 @@ func SignTx(tx *web3.Transaction, private *ecdsa.PrivateKey, chainID uint64) (*w
 }
 
 func signHash(tx *web3.Transaction, chainID uint64) []byte {
-	a := fastrlp.DefaultArenaPool.Get()
+	a := txArenaPool.Get()
 
 	v := a.NewArray()
 	v.Set(a.NewUint(tx.Nonce))
@@ -53,10 +111,33 @@ func signHash(tx *web3.Transaction, chainID uint64) []byte {
 	}
 
 	hash := keccak256(v.MarshalTo(nil))
-	fastrlp.DefaultArenaPool.Put(a)
+	txArenaPool.Put(a)
 	return hash
 }
 
+// marshalTx 返回签名后的传统交易的RLP编码
+// 不使用 web3.Transaction.MarshalRLP: 其引用 To 的内存而非拷贝，Arena 被复用时会改写已编码交易的 To
+func marshalTx(tx *web3.Transaction) []byte {
+	a := txArenaPool.Get()
+	defer txArenaPool.Put(a)
+
+	v := a.NewArray()
+	v.Set(a.NewUint(tx.Nonce))
+	v.Set(a.NewUint(tx.GasPrice))
+	v.Set(a.NewUint(tx.Gas))
+	if tx.To == nil {
+		v.Set(a.NewNull())
+	} else {
+		v.Set(a.NewCopyBytes((*tx.To)[:]))
+	}
+	v.Set(a.NewBigInt(tx.Value))
+	v.Set(a.NewCopyBytes(tx.Input))
+	v.Set(a.NewCopyBytes(tx.V))
+	v.Set(a.NewCopyBytes(tx.R))
+	v.Set(a.NewCopyBytes(tx.S))
+	return v.MarshalTo(nil)
+}
+
 func Sign(private *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
 	var S256 = btcec.S256()
 	sig, err := btcec.SignCompact(S256, (*btcec.PrivateKey)(private), hash, false)
@@ -70,6 +151,24 @@ func Sign(private *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
 	return append(sig, term)[1:], nil
 }
 
+// recoverSigner 根据hash和 [R || S || V] 格式的65字节签名恢复签名者地址
+// V 可以是0、1，也可以是钱包常用的27、28
+func recoverSigner(hash []byte, sig []byte) (web3.Address, error) {
+	if len(sig) != 65 {
+		return web3.Address{}, errno.InvalidSignature.Add("signature must be 65 bytes")
+	}
+	normalized := make([]byte, 65)
+	copy(normalized, sig)
+	if normalized[64] >= 27 {
+		normalized[64] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, normalized)
+	if err != nil {
+		return web3.Address{}, errno.InvalidSignature.Add(err.Error())
+	}
+	return web3.Address(crypto.PubkeyToAddress(*pub)), nil
+}
+
 // trimLeadingZero 去掉交易签名R、S开头的0x00
 // 见 https://github.com/MOACChain/moac-core/issues/24
 // 用来避免 rlp: non-canonical integer (leading zero bytes) for *big.Int, decoding into (types.Transaction)(types.txdata).R
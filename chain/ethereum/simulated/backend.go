@@ -0,0 +1,197 @@
+// Package simulated 提供进程内的模拟 EVM 链，用于在不依赖 geth、anvil 等节点的情况下测试 chain/ethereum
+//
+// Backend 实现了 chain/ethereum 使用的 JSON-RPC 接口，可以通过 Provider 得到的 provider.CommonProvider 创建
+// Client、TxBuilder 与 ContractTxBuilder，也可以作为 http.Handler 挂载到其他 http 服务中
+//
+// 模拟链基于 go-ethereum 的 SimulatedBackend，链ID固定为 ChainID，从创世区块起启用 London 升级，
+// 支持 legacy (EIP-155)、EIP-2930 与 EIP-1559 交易，以及 eth_feeHistory
+package simulated
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/mgintoki/multichain/api/provider"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ChainID 是模拟链的链ID
+const ChainID = 1337
+
+const (
+	// DefaultAccounts 是默认创建的有余额账户的数量
+	DefaultAccounts = 10
+	// DefaultGasLimit 是默认的区块 gas 上限
+	DefaultGasLimit = 30000000
+)
+
+// DefaultBalance 是默认给每个账户分配的余额，10000 ether
+var DefaultBalance = new(big.Int).Mul(big.NewInt(10000), big.NewInt(params.Ether))
+
+// Account 是模拟链上有余额的账户
+type Account struct {
+	Address    string // EIP-55 格式的地址
+	PrivateKey string // 16进制的私钥，不带0x前缀
+}
+
+type config struct {
+	accounts int
+	balance  *big.Int
+	gasLimit uint64
+	alloc    core.GenesisAlloc
+	manual   bool
+}
+
+// Option 是创建模拟链的可选参数
+type Option func(c *config)
+
+// WithAccounts 设置创建的有余额账户的数量
+func WithAccounts(n int) Option {
+	return func(c *config) {
+		c.accounts = n
+	}
+}
+
+// WithBalance 设置给每个账户分配的余额
+func WithBalance(balance *big.Int) Option {
+	return func(c *config) {
+		c.balance = balance
+	}
+}
+
+// WithGasLimit 设置区块的 gas 上限
+func WithGasLimit(gasLimit uint64) Option {
+	return func(c *config) {
+		c.gasLimit = gasLimit
+	}
+}
+
+// WithAlloc 在创世区块中给指定地址分配余额，可以多次使用
+func WithAlloc(address string, balance *big.Int) Option {
+	return func(c *config) {
+		c.alloc[common.HexToAddress(address)] = core.GenesisAccount{Balance: balance}
+	}
+}
+
+// WithManualMining 关闭自动出块，交易发送后停留在 pending 区块中，直到调用 Mine
+// 默认每个交易发送后立即出块
+func WithManualMining() Option {
+	return func(c *config) {
+		c.manual = true
+	}
+}
+
+// Backend 是进程内的模拟链
+type Backend struct {
+	sim      *backends.SimulatedBackend
+	db       ethdb.Database
+	accounts []Account
+	manual   bool
+
+	// lock 保证发送交易与出块的原子性
+	lock sync.Mutex
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// New 创建模拟链，并在本地回环地址上启动 JSON-RPC 服务
+func New(opts ...Option) (*Backend, error) {
+	c := &config{
+		accounts: DefaultAccounts,
+		balance:  DefaultBalance,
+		gasLimit: DefaultGasLimit,
+		alloc:    core.GenesisAlloc{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	b := &Backend{manual: c.manual}
+	for i := 0; i < c.accounts; i++ {
+		key := accountKey(i)
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		b.accounts = append(b.accounts, Account{
+			Address:    addr.Hex(),
+			PrivateKey: fmt.Sprintf("%x", crypto.FromECDSA(key)),
+		})
+		if _, ok := c.alloc[addr]; !ok {
+			c.alloc[addr] = core.GenesisAccount{Balance: c.balance}
+		}
+	}
+	b.db = rawdb.NewMemoryDatabase()
+	b.sim = backends.NewSimulatedBackendWithDatabase(b.db, c.alloc, c.gasLimit)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.sim.Close()
+		return nil, err
+	}
+	b.listener = listener
+	b.server = &http.Server{Handler: b}
+	go b.server.Serve(listener)
+	return b, nil
+}
+
+// accountKey 生成确定的账户私钥，相同序号的账户在每次创建的模拟链中地址相同
+func accountKey(i int) *ecdsa.PrivateKey {
+	seed := crypto.Keccak256([]byte(fmt.Sprintf("multichain simulated account %d", i)))
+	key, err := crypto.ToECDSA(seed)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// URL 返回 JSON-RPC 服务的地址
+func (b *Backend) URL() string {
+	return "http://" + b.listener.Addr().String()
+}
+
+// Provider 返回可用于创建 Client、TxBuilder 与 ContractTxBuilder 的 provider
+func (b *Backend) Provider() provider.CommonProvider {
+	return provider.CommonProvider{ProviderUrl: b.URL()}
+}
+
+// Accounts 返回有余额的账户
+func (b *Backend) Accounts() []Account {
+	return append([]Account(nil), b.accounts...)
+}
+
+// Mine 将 pending 区块中的交易打包出块
+func (b *Backend) Mine() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sim.Commit()
+}
+
+// Rollback 丢弃 pending 区块中的交易
+func (b *Backend) Rollback() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sim.Rollback()
+}
+
+// AdjustTime 将 pending 区块的时间向后调整，需要调用 Mine 后生效
+func (b *Backend) AdjustTime(d time.Duration) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.sim.AdjustTime(d)
+}
+
+// Close 关闭 JSON-RPC 服务与模拟链
+func (b *Backend) Close() error {
+	err := b.server.Close()
+	b.sim.Close()
+	return err
+}
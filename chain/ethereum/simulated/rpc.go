@@ -0,0 +1,744 @@
+package simulated
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcMethod func(b *Backend, params []json.RawMessage) (interface{}, error)
+
+// methods 是模拟链支持的 JSON-RPC 方法
+var methods = map[string]rpcMethod{
+	"web3_clientVersion":        func(*Backend, []json.RawMessage) (interface{}, error) { return "multichain/simulated", nil },
+	"net_version":               func(*Backend, []json.RawMessage) (interface{}, error) { return fmt.Sprint(ChainID), nil },
+	"eth_chainId":               func(*Backend, []json.RawMessage) (interface{}, error) { return hexutil.Uint64(ChainID), nil },
+	"eth_accounts":              (*Backend).rpcAccounts,
+	"eth_blockNumber":           (*Backend).rpcBlockNumber,
+	"eth_gasPrice":              (*Backend).rpcGasPrice,
+	"eth_getBalance":            (*Backend).rpcGetBalance,
+	"eth_getTransactionCount":   (*Backend).rpcGetTransactionCount,
+	"eth_getCode":               (*Backend).rpcGetCode,
+	"eth_getStorageAt":          (*Backend).rpcGetStorageAt,
+	"eth_call":                  (*Backend).rpcCall,
+	"eth_estimateGas":           (*Backend).rpcEstimateGas,
+	"eth_sendRawTransaction":    (*Backend).rpcSendRawTransaction,
+	"eth_getTransactionByHash":  (*Backend).rpcGetTransactionByHash,
+	"eth_getTransactionReceipt": (*Backend).rpcGetTransactionReceipt,
+	"eth_getBlockByNumber":      (*Backend).rpcGetBlockByNumber,
+	"eth_getBlockByHash":        (*Backend).rpcGetBlockByHash,
+	"eth_getLogs":               (*Backend).rpcGetLogs,
+	"eth_feeHistory":            (*Backend).rpcFeeHistory,
+}
+
+// maxFeeHistory 是 eth_feeHistory 单次最多返回的区块数，与 geth 一致
+const maxFeeHistory = 1024
+
+// txSigner 支持模拟链上所有类型的交易
+var txSigner = types.LatestSignerForChainID(big.NewInt(ChainID))
+
+// ServeHTTP 处理 JSON-RPC 请求，支持批量请求
+func (b *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) != 0 && body[0] == '[' {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+			return
+		}
+		resps := make([]rpcResponse, 0, len(reqs))
+		for _, req := range reqs {
+			resps = append(resps, b.handle(req))
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: -32700, Message: err.Error()}})
+		return
+	}
+	json.NewEncoder(w).Encode(b.handle(req))
+}
+
+func (b *Backend) handle(req rpcRequest) rpcResponse {
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	method, ok := methods[req.Method]
+	if !ok {
+		resp.Error = &rpcError{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+		return resp
+	}
+	result, err := method(b, req.Params)
+	if err != nil {
+		var re *rpcError
+		if !errors.As(err, &re) {
+			re = &rpcError{Code: -32000, Message: err.Error()}
+		}
+		resp.Error = re
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// param 解析第 i 个参数，参数不存在时保持 out 不变
+func param(params []json.RawMessage, i int, out interface{}) error {
+	if i >= len(params) || string(params[i]) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params[i], out); err != nil {
+		return &rpcError{Code: -32602, Message: fmt.Sprintf("invalid argument %d: %v", i, err)}
+	}
+	return nil
+}
+
+// blockParam 解析区块参数，latest 返回nil，pending 返回 pending 为 true
+func blockParam(params []json.RawMessage, i int) (number *big.Int, pending bool, err error) {
+	tag := "latest"
+	if err := param(params, i, &tag); err != nil {
+		return nil, false, err
+	}
+	return parseBlockTag(tag)
+}
+
+func parseBlockTag(tag string) (*big.Int, bool, error) {
+	switch tag {
+	case "latest", "":
+		return nil, false, nil
+	case "pending":
+		return nil, true, nil
+	case "earliest":
+		return big.NewInt(0), false, nil
+	}
+	n, err := hexutil.DecodeBig(tag)
+	if err != nil {
+		return nil, false, &rpcError{Code: -32602, Message: fmt.Sprintf("invalid block number %q", tag)}
+	}
+	return n, false, nil
+}
+
+func (b *Backend) rpcAccounts(_ []json.RawMessage) (interface{}, error) {
+	addrs := make([]common.Address, 0, len(b.accounts))
+	for _, a := range b.accounts {
+		addrs = append(addrs, common.HexToAddress(a.Address))
+	}
+	return addrs, nil
+}
+
+func (b *Backend) rpcBlockNumber(_ []json.RawMessage) (interface{}, error) {
+	return hexutil.Uint64(b.sim.Blockchain().CurrentBlock().NumberU64()), nil
+}
+
+// rpcGasPrice 与 geth 一致，返回 pending 区块的 base fee 加上建议的小费
+func (b *Backend) rpcGasPrice(_ []json.RawMessage) (interface{}, error) {
+	baseFee, err := b.sim.SuggestGasPrice(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	tip, err := b.sim.SuggestGasTipCap(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(new(big.Int).Add(baseFee, tip)), nil
+}
+
+func (b *Backend) rpcGetBalance(params []json.RawMessage) (interface{}, error) {
+	var addr common.Address
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	number, _, err := blockParam(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := b.sim.BalanceAt(context.Background(), addr, number)
+	return (*hexutil.Big)(balance), err
+}
+
+func (b *Backend) rpcGetTransactionCount(params []json.RawMessage) (interface{}, error) {
+	var addr common.Address
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	number, pending, err := blockParam(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	var nonce uint64
+	if pending {
+		nonce, err = b.sim.PendingNonceAt(context.Background(), addr)
+	} else {
+		nonce, err = b.sim.NonceAt(context.Background(), addr, number)
+	}
+	return hexutil.Uint64(nonce), err
+}
+
+func (b *Backend) rpcGetCode(params []json.RawMessage) (interface{}, error) {
+	var addr common.Address
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	number, pending, err := blockParam(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	var code []byte
+	if pending {
+		code, err = b.sim.PendingCodeAt(context.Background(), addr)
+	} else {
+		code, err = b.sim.CodeAt(context.Background(), addr, number)
+	}
+	return hexutil.Bytes(code), err
+}
+
+func (b *Backend) rpcGetStorageAt(params []json.RawMessage) (interface{}, error) {
+	var addr common.Address
+	var key common.Hash
+	if err := param(params, 0, &addr); err != nil {
+		return nil, err
+	}
+	if err := param(params, 1, &key); err != nil {
+		return nil, err
+	}
+	number, _, err := blockParam(params, 2)
+	if err != nil {
+		return nil, err
+	}
+	value, err := b.sim.StorageAt(context.Background(), addr, key, number)
+	return hexutil.Bytes(value), err
+}
+
+// callArgs 是 eth_call 与 eth_estimateGas 的参数
+type callArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     *hexutil.Bytes  `json:"data"`
+	Input    *hexutil.Bytes  `json:"input"`
+}
+
+func (args *callArgs) msg() ethereum.CallMsg {
+	msg := ethereum.CallMsg{From: args.From, To: args.To}
+	if args.Gas != nil {
+		msg.Gas = uint64(*args.Gas)
+	}
+	if args.GasPrice != nil {
+		msg.GasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		msg.Value = args.Value.ToInt()
+	}
+	if args.Input != nil {
+		msg.Data = *args.Input
+	} else if args.Data != nil {
+		msg.Data = *args.Data
+	}
+	return msg
+}
+
+// revertErr 将 go-ethereum 的 revert 错误转换为带有 revert data 的 JSON-RPC 错误，与 geth 节点的返回一致
+func revertErr(err error) error {
+	if re, ok := err.(interface {
+		ErrorCode() int
+		ErrorData() interface{}
+	}); ok {
+		return &rpcError{Code: re.ErrorCode(), Message: err.Error(), Data: re.ErrorData()}
+	}
+	return err
+}
+
+func (b *Backend) rpcCall(params []json.RawMessage) (interface{}, error) {
+	var args callArgs
+	if err := param(params, 0, &args); err != nil {
+		return nil, err
+	}
+	number, pending, err := blockParam(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	var res []byte
+	if pending {
+		res, err = b.sim.PendingCallContract(context.Background(), args.msg())
+	} else {
+		res, err = b.sim.CallContract(context.Background(), args.msg(), number)
+	}
+	if err != nil {
+		return nil, revertErr(err)
+	}
+	return hexutil.Bytes(res), nil
+}
+
+func (b *Backend) rpcEstimateGas(params []json.RawMessage) (interface{}, error) {
+	var args callArgs
+	if err := param(params, 0, &args); err != nil {
+		return nil, err
+	}
+	gas, err := b.sim.EstimateGas(context.Background(), args.msg())
+	if err != nil {
+		return nil, revertErr(err)
+	}
+	return hexutil.Uint64(gas), nil
+}
+
+func (b *Backend) rpcSendRawTransaction(params []json.RawMessage) (interface{}, error) {
+	var raw hexutil.Bytes
+	if err := param(params, 0, &raw); err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	if err := b.sendTransaction(tx); err != nil {
+		return nil, err
+	}
+	return tx.Hash(), nil
+}
+
+// sendTransaction 将交易加入 pending 区块，自动出块时立即出块
+// SimulatedBackend 遇到无效交易时会 panic，这里提前检查 nonce，并将其他 panic 转换为与 geth 一致的错误
+func (b *Backend) sendTransaction(tx *types.Transaction) (err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sender, err := types.Sender(txSigner, tx)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	nonce, err := b.sim.PendingNonceAt(context.Background(), sender)
+	if err != nil {
+		return err
+	}
+	if tx.Nonce() < nonce {
+		return fmt.Errorf("nonce too low")
+	}
+	if tx.Nonce() > nonce {
+		return fmt.Errorf("nonce too high")
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if err := b.sim.SendTransaction(context.Background(), tx); err != nil {
+		return err
+	}
+	if !b.manual {
+		b.sim.Commit()
+	}
+	return nil
+}
+
+// lookupTx 查询交易，交易在 pending 区块中时 blockHash 为空
+func (b *Backend) lookupTx(hash common.Hash) (tx *types.Transaction, blockHash common.Hash, blockNumber, index uint64, err error) {
+	tx, blockHash, blockNumber, index = rawdb.ReadTransaction(b.db, hash)
+	if tx != nil {
+		return tx, blockHash, blockNumber, index, nil
+	}
+	tx, _, err = b.sim.TransactionByHash(context.Background(), hash)
+	if err == ethereum.NotFound {
+		err = nil
+	}
+	return tx, common.Hash{}, 0, 0, err
+}
+
+func (b *Backend) rpcGetTransactionByHash(params []json.RawMessage) (interface{}, error) {
+	var hash common.Hash
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	tx, blockHash, blockNumber, index, err := b.lookupTx(hash)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return marshalTx(tx, blockHash, blockNumber, index, b.baseFee(blockHash)), nil
+}
+
+func (b *Backend) rpcGetTransactionReceipt(params []json.RawMessage) (interface{}, error) {
+	var hash common.Hash
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	tx, blockHash, _, _, err := b.lookupTx(hash)
+	if err != nil || tx == nil || blockHash == (common.Hash{}) {
+		return nil, err
+	}
+	receipt, err := b.sim.TransactionReceipt(context.Background(), hash)
+	if err != nil {
+		return nil, err
+	}
+	from, _ := types.Sender(txSigner, tx)
+
+	logs := receipt.Logs
+	if logs == nil {
+		logs = []*types.Log{}
+	}
+	fields := map[string]interface{}{
+		"transactionHash":   hash,
+		"transactionIndex":  hexutil.Uint64(receipt.TransactionIndex),
+		"blockHash":         receipt.BlockHash,
+		"blockNumber":       (*hexutil.Big)(receipt.BlockNumber),
+		"from":              from,
+		"to":                tx.To(),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(effectiveGasPrice(tx, b.baseFee(blockHash))),
+		"contractAddress":   nil,
+		"logs":              logs,
+		"logsBloom":         receipt.Bloom,
+		"status":            hexutil.Uint64(receipt.Status),
+		"type":              hexutil.Uint64(tx.Type()),
+	}
+	if tx.To() == nil {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields, nil
+}
+
+func (b *Backend) rpcGetBlockByNumber(params []json.RawMessage) (interface{}, error) {
+	number, _, err := blockParam(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	var full bool
+	if err := param(params, 1, &full); err != nil {
+		return nil, err
+	}
+	block, err := b.sim.BlockByNumber(context.Background(), number)
+	if err != nil {
+		return nil, nil
+	}
+	return marshalBlock(block, full), nil
+}
+
+func (b *Backend) rpcGetBlockByHash(params []json.RawMessage) (interface{}, error) {
+	var hash common.Hash
+	if err := param(params, 0, &hash); err != nil {
+		return nil, err
+	}
+	var full bool
+	if err := param(params, 1, &full); err != nil {
+		return nil, err
+	}
+	block, err := b.sim.BlockByHash(context.Background(), hash)
+	if err != nil {
+		return nil, nil
+	}
+	return marshalBlock(block, full), nil
+}
+
+func (b *Backend) rpcGetLogs(params []json.RawMessage) (interface{}, error) {
+	var args struct {
+		BlockHash *common.Hash    `json:"blockHash"`
+		FromBlock string          `json:"fromBlock"`
+		ToBlock   string          `json:"toBlock"`
+		Address   json.RawMessage `json:"address"`
+		Topics    []interface{}   `json:"topics"`
+	}
+	if err := param(params, 0, &args); err != nil {
+		return nil, err
+	}
+
+	query := ethereum.FilterQuery{BlockHash: args.BlockHash}
+	var err error
+	if query.FromBlock, _, err = parseBlockTag(args.FromBlock); err != nil {
+		return nil, err
+	}
+	if query.ToBlock, _, err = parseBlockTag(args.ToBlock); err != nil {
+		return nil, err
+	}
+	if len(args.Address) != 0 && string(args.Address) != "null" {
+		var single common.Address
+		if err := json.Unmarshal(args.Address, &single); err == nil {
+			query.Addresses = []common.Address{single}
+		} else if err := json.Unmarshal(args.Address, &query.Addresses); err != nil {
+			return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("invalid address: %v", err)}
+		}
+	}
+	for _, topic := range args.Topics {
+		var hashes []common.Hash
+		switch t := topic.(type) {
+		case nil:
+		case string:
+			hashes = append(hashes, common.HexToHash(t))
+		case []interface{}:
+			for _, h := range t {
+				s, ok := h.(string)
+				if !ok {
+					return nil, &rpcError{Code: -32602, Message: "invalid topic"}
+				}
+				hashes = append(hashes, common.HexToHash(s))
+			}
+		default:
+			return nil, &rpcError{Code: -32602, Message: "invalid topic"}
+		}
+		query.Topics = append(query.Topics, hashes)
+	}
+
+	logs, err := b.sim.FilterLogs(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	if logs == nil {
+		logs = []types.Log{}
+	}
+	return logs, nil
+}
+
+// marshalTx 按照 eth_getTransactionByHash 的格式编码交易，blockHash 为空时区块字段为 null
+// baseFee 是交易所在区块的 base fee，EIP-1559 交易的 gasPrice 与 geth 一致，上链后为实际的 gas 价格，pending 时为 maxFeePerGas
+func marshalTx(tx *types.Transaction, blockHash common.Hash, blockNumber, index uint64, baseFee *big.Int) map[string]interface{} {
+	from, _ := types.Sender(txSigner, tx)
+	v, r, s := tx.RawSignatureValues()
+	fields := map[string]interface{}{
+		"hash":             tx.Hash(),
+		"from":             from,
+		"to":               tx.To(),
+		"nonce":            hexutil.Uint64(tx.Nonce()),
+		"gas":              hexutil.Uint64(tx.Gas()),
+		"gasPrice":         (*hexutil.Big)(effectiveGasPrice(tx, baseFee)),
+		"value":            (*hexutil.Big)(tx.Value()),
+		"input":            hexutil.Bytes(tx.Data()),
+		"v":                (*hexutil.Big)(v),
+		"r":                (*hexutil.Big)(r),
+		"s":                (*hexutil.Big)(s),
+		"type":             hexutil.Uint64(tx.Type()),
+		"blockHash":        nil,
+		"blockNumber":      nil,
+		"transactionIndex": nil,
+	}
+	if tx.Type() != types.LegacyTxType {
+		fields["chainId"] = (*hexutil.Big)(tx.ChainId())
+		fields["accessList"] = tx.AccessList()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		fields["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		fields["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	}
+	if blockHash != (common.Hash{}) {
+		fields["blockHash"] = blockHash
+		fields["blockNumber"] = hexutil.Uint64(blockNumber)
+		fields["transactionIndex"] = hexutil.Uint64(index)
+	}
+	return fields
+}
+
+// marshalBlock 按照 eth_getBlockByHash 的格式编码区块
+func marshalBlock(block *types.Block, full bool) map[string]interface{} {
+	head := block.Header()
+	txs := make([]interface{}, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if full {
+			txs = append(txs, marshalTx(tx, block.Hash(), block.NumberU64(), uint64(i), block.BaseFee()))
+		} else {
+			txs = append(txs, tx.Hash())
+		}
+	}
+	uncles := make([]common.Hash, 0, len(block.Uncles()))
+	for _, uncle := range block.Uncles() {
+		uncles = append(uncles, uncle.Hash())
+	}
+	fields := map[string]interface{}{
+		"number":           (*hexutil.Big)(head.Number),
+		"hash":             block.Hash(),
+		"parentHash":       head.ParentHash,
+		"nonce":            head.Nonce,
+		"mixHash":          head.MixDigest,
+		"sha3Uncles":       head.UncleHash,
+		"logsBloom":        head.Bloom,
+		"stateRoot":        head.Root,
+		"miner":            head.Coinbase,
+		"difficulty":       (*hexutil.Big)(head.Difficulty),
+		"extraData":        hexutil.Bytes(head.Extra),
+		"size":             hexutil.Uint64(block.Size()),
+		"gasLimit":         hexutil.Uint64(head.GasLimit),
+		"gasUsed":          hexutil.Uint64(head.GasUsed),
+		"timestamp":        hexutil.Uint64(head.Time),
+		"transactionsRoot": head.TxHash,
+		"receiptsRoot":     head.ReceiptHash,
+		"transactions":     txs,
+		"uncles":           uncles,
+	}
+	if head.BaseFee != nil {
+		fields["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
+	return fields
+}
+
+// effectiveGasPrice 返回交易在 base fee 为 baseFee 的区块中实际的 gas 价格，baseFee 为nil时返回 maxFeePerGas
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if baseFee == nil || tx.Type() != types.DynamicFeeTxType {
+		return tx.GasPrice()
+	}
+	return math.BigMin(new(big.Int).Add(tx.GasTipCap(), baseFee), tx.GasFeeCap())
+}
+
+// baseFee 返回区块的 base fee，区块不存在时返回nil
+func (b *Backend) baseFee(blockHash common.Hash) *big.Int {
+	if blockHash == (common.Hash{}) {
+		return nil
+	}
+	head := b.sim.Blockchain().GetHeaderByHash(blockHash)
+	if head == nil {
+		return nil
+	}
+	return head.BaseFee
+}
+
+// rpcFeeHistory 按照 geth 的方式统计区块的 base fee、gas 使用率与小费的百分位，百分位按交易使用的 gas 加权
+func (b *Backend) rpcFeeHistory(params []json.RawMessage) (interface{}, error) {
+	var count hexutil.Uint64
+	if len(params) != 0 && json.Unmarshal(params[0], &count) != nil {
+		var n uint64
+		if err := param(params, 0, &n); err != nil {
+			return nil, err
+		}
+		count = hexutil.Uint64(n)
+	}
+	newest, _, err := blockParam(params, 1)
+	if err != nil {
+		return nil, err
+	}
+	var percentiles []float64
+	if err := param(params, 2, &percentiles); err != nil {
+		return nil, err
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("invalid reward percentile %v", p)}
+		}
+	}
+
+	chain := b.sim.Blockchain()
+	last := chain.CurrentBlock().NumberU64()
+	if newest != nil && newest.Uint64() < last {
+		last = newest.Uint64()
+	}
+	if count > maxFeeHistory {
+		count = maxFeeHistory
+	}
+	if uint64(count) > last+1 {
+		count = hexutil.Uint64(last + 1)
+	}
+	oldest := last + 1 - uint64(count)
+
+	baseFees := make([]*hexutil.Big, 0, count+1)
+	ratios := make([]float64, 0, count)
+	var rewards [][]*hexutil.Big
+	var head *types.Header
+	for n := oldest; n <= last; n++ {
+		block := chain.GetBlockByNumber(n)
+		head = block.Header()
+		baseFees = append(baseFees, (*hexutil.Big)(blockBaseFee(head)))
+		ratios = append(ratios, float64(head.GasUsed)/float64(head.GasLimit))
+		if len(percentiles) != 0 {
+			rewards = append(rewards, b.blockRewards(block, percentiles))
+		}
+	}
+	if head != nil {
+		baseFees = append(baseFees, (*hexutil.Big)(misc.CalcBaseFee(chain.Config(), head)))
+	}
+
+	res := map[string]interface{}{
+		"oldestBlock":   (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+		"baseFeePerGas": baseFees,
+		"gasUsedRatio":  ratios,
+	}
+	if rewards != nil {
+		res["reward"] = rewards
+	}
+	return res, nil
+}
+
+func blockBaseFee(head *types.Header) *big.Int {
+	if head.BaseFee == nil {
+		return new(big.Int)
+	}
+	return head.BaseFee
+}
+
+// blockRewards 返回区块中交易小费的百分位，空区块的小费为0
+func (b *Backend) blockRewards(block *types.Block, percentiles []float64) []*hexutil.Big {
+	rewards := make([]*hexutil.Big, len(percentiles))
+	for i := range rewards {
+		rewards[i] = new(hexutil.Big)
+	}
+	if len(block.Transactions()) == 0 {
+		return rewards
+	}
+
+	receipts := rawdb.ReadReceipts(b.db, block.Hash(), block.NumberU64(), b.sim.Blockchain().Config())
+	type txTip struct {
+		gasUsed uint64
+		tip     *big.Int
+	}
+	tips := make([]txTip, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		tip, _ := tx.EffectiveGasTip(block.BaseFee())
+		tips[i] = txTip{tip: tip}
+		if i < len(receipts) {
+			tips[i].gasUsed = receipts[i].GasUsed
+		}
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].tip.Cmp(tips[j].tip) < 0 })
+
+	var i int
+	sum := tips[0].gasUsed
+	for j, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for sum < threshold && i < len(tips)-1 {
+			i++
+			sum += tips[i].gasUsed
+		}
+		rewards[j] = (*hexutil.Big)(tips[i].tip)
+	}
+	return rewards
+}
@@ -0,0 +1,254 @@
+package simulated_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/chain/ethereum"
+	"github.com/mgintoki/multichain/chain/ethereum/simulated"
+	"github.com/mgintoki/multichain/common"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// 合约 T 与 Reverter 来自 go-ethereum 的 SimulatedBackend 测试
+//
+//	contract T {
+//		event received(address sender, uint amount, bytes memo);
+//		event receivedAddr(address sender);
+//
+//		function receive(bytes calldata memo) external payable returns (string memory res) {
+//			emit received(msg.sender, msg.value, memo);
+//			emit receivedAddr(msg.sender);
+//			return "hello world";
+//		}
+//	}
+const abiJSON = `[ { "constant": false, "inputs": [ { "name": "memo", "type": "bytes" } ], "name": "receive", "outputs": [ { "name": "res", "type": "string" } ], "payable": true, "stateMutability": "payable", "type": "function" }, { "anonymous": false, "inputs": [ { "indexed": false, "name": "sender", "type": "address" }, { "indexed": false, "name": "amount", "type": "uint256" }, { "indexed": false, "name": "memo", "type": "bytes" } ], "name": "received", "type": "event" }, { "anonymous": false, "inputs": [ { "indexed": false, "name": "sender", "type": "address" } ], "name": "receivedAddr", "type": "event" } ]`
+const abiBin = `0x608060405234801561001057600080fd5b506102a0806100206000396000f3fe60806040526004361061003b576000357c010000000000000000000000000000000000000000000000000000000090048063a69b6ed014610040575b600080fd5b6100b76004803603602081101561005657600080fd5b810190808035906020019064010000000081111561007357600080fd5b82018360208201111561008557600080fd5b803590602001918460018302840111640100000000831117156100a757600080fd5b9091929391929390505050610132565b6040518080602001828103825283818151815260200191508051906020019080838360005b838110156100f75780820151818401526020810190506100dc565b50505050905090810190601f1680156101245780820380516001836020036101000a031916815260200191505b509250505060405180910390f35b60607f75fd880d39c1daf53b6547ab6cb59451fc6452d27caa90e5b6649dd8293b9eed33348585604051808573ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff168152602001848152602001806020018281038252848482818152602001925080828437600081840152601f19601f8201169050808301925050509550505050505060405180910390a17f46923992397eac56cf13058aced2a1871933622717e27b24eabc13bf9dd329c833604051808273ffffffffffffffffffffffffffffffffffffffff1673ffffffffffffffffffffffffffffffffffffffff16815260200191505060405180910390a16040805190810160405280600b81526020017f68656c6c6f20776f726c6400000000000000000000000000000000000000000081525090509291505056fea165627a7a72305820ff0c57dad254cfeda48c9cfb47f1353a558bccb4d1bc31da1dae69315772d29e0029`
+
+// Reverter 的 Revert 方法使用 revert("revert reason")
+const reverterAbi = "[{\"inputs\":[],\"name\":\"Assert\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"OOG\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"PureRevert\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"Revert\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"Valid\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]"
+const reverterBin = "0x60806040523480156100115760006000fd5b50610017565b61016e806100266000396000f3fe60806040523480156100115760006000fd5b506004361061005c5760003560e01c806350f6fe3414610062578063aa8b1d301461006c578063b9b046f914610076578063d8b9839114610080578063e09fface1461008a5761005c565b60006000fd5b61006a610094565b005b6100746100ad565b005b61007e6100b5565b005b6100886100c2565b005b610092610135565b005b6000600090505b5b808060010191505061009b565b505b565b60006000fd5b565b600015156100bf57fe5b5b565b6040517f08c379a000000000000000000000000000000000000000000000000000000000815260040180806020018281038252600d8152602001807f72657665727420726561736f6e0000000000000000000000000000000000000081526020015060200191505060405180910390fd5b565b5b56fea2646970667358221220345bbcbb1a5ecf22b53a78eaebf95f8ee0eceff6d10d4b9643495084d2ec934a64736f6c63430006040033"
+
+func newClient(t *testing.T, backend *simulated.Backend, account simulated.Account) *ethereum.Client {
+	c, err := ethereum.NewClient(backend.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetPrivate(account.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func deploy(t *testing.T, backend *simulated.Backend, c *ethereum.Client, abiStr, bin string) string {
+	builder, err := ethereum.NewContractTxBuilder(backend.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, err := builder.BuildDeployTx(txbuilder.BuildDeployTxReq{From: c.GetAccount(), Abi: abiStr, ByteCode: bin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHash, err := c.SendTx(txn, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txData, err := c.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || txData.ContractAddress != txn.(*ethereum.Txn).ContractAddress() {
+		t.Fatalf("unexpected deploy tx %+v", txData)
+	}
+	return txData.ContractAddress
+}
+
+func TestBackend(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	accounts := backend.Accounts()
+	if len(accounts) != 2 {
+		t.Fatalf("expect 2 accounts, got %d", len(accounts))
+	}
+	c := newClient(t, backend, accounts[0])
+	if chainID, err := c.GetChainID(); err != nil || chainID != "1337" {
+		t.Fatalf("unexpected chain id %s %v", chainID, err)
+	}
+
+	amount := big.NewInt(1e18)
+	txHash, err := c.Transfer(accounts[1].Address, amount, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txData, err := c.QueryTx(txHash, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || txData.GasUsed != 21000 || txData.Value.Cmp(amount) != 0 {
+		t.Fatalf("unexpected transfer tx %+v", txData)
+	}
+	balance, err := c.BalanceOf(accounts[1].Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(new(big.Int).Add(simulated.DefaultBalance, amount)) != 0 {
+		t.Fatalf("unexpected balance %s", balance)
+	}
+
+	contract := deploy(t, backend, c, abiJSON, abiBin)
+	res, err := c.QueryContract(client.CallContractParam{ContractAddress: contract, Abi: abiJSON, CalledFunc: "receive", Params: []interface{}{"0x01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DecodeRes["res"] != "hello world" {
+		t.Fatalf("unexpected call result %v", res.DecodeRes)
+	}
+
+	builder, _ := ethereum.NewContractTxBuilder(backend.Provider())
+	txn, err := builder.BuildInvokeTx(txbuilder.BuildInvokeTxReq{From: c.GetAccount(), Abi: abiJSON, Method: "receive", Params: []interface{}{"0x01"}, ContractAddress: contract, Value: big.NewInt(7)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txHash, err = c.SendTx(txn, nil); err != nil {
+		t.Fatal(err)
+	}
+	txData, err = c.QueryTxWithAbi(txHash, false, abiJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txData.Logs) != 2 || txData.Logs[0].Event != "received(address,uint256,bytes)" || txData.Logs[0].Decoded["amount"].(*big.Int).Int64() != 7 {
+		t.Fatalf("unexpected logs %+v", txData.Logs)
+	}
+
+	reverter := deploy(t, backend, c, reverterAbi, reverterBin)
+	_, err = c.QueryContract(client.CallContractParam{ContractAddress: reverter, Abi: reverterAbi, CalledFunc: "Revert"})
+	if !errors.Is(err, errno.ExecutionReverted) || !strings.Contains(err.Error(), "revert reason") {
+		t.Fatalf("expect revert reason, got %v", err)
+	}
+}
+
+func TestManualMining(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(2), simulated.WithManualMining())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	accounts := backend.Accounts()
+	c := newClient(t, backend, accounts[0])
+	txHash, err := c.Transfer(accounts[1].Address, big.NewInt(1), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txData, err := c.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusPending || txData.From != strings.ToLower(accounts[0].Address) {
+		t.Fatalf("unexpected pending tx %+v", txData)
+	}
+
+	backend.Mine()
+	txData, err = c.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || txData.BlockNumber != 1 {
+		t.Fatalf("unexpected mined tx %+v", txData)
+	}
+
+	// 已使用的 nonce 返回与 geth 一致的错误
+	tb, err := ethereum.NewTxBuilder(backend.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := tb.BuildTx(txbuilder.BuildTxParam{From: accounts[0].Address, To: accounts[1].Address, Value: big.NewInt(1), GasLimit: 21000, GasPrice: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay.(*ethereum.Txn).Nonce = 0
+	if err := replay.SignTx(accounts[0].PrivateKey, "1337"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendSignedTx(replay); !errors.Is(err, errno.NonceTooLow) {
+		t.Fatalf("expect nonce too low, got %v", err)
+	}
+}
+
+func TestDynamicFeeTx(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	accounts := backend.Accounts()
+	c := newClient(t, backend, accounts[0])
+	// 先发送一笔传统交易，使近期区块中有可统计的小费
+	if _, err := c.Transfer(accounts[1].Address, big.NewInt(1), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.SuggestFee()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.BaseFee == 0 || r.Fast.MaxFeePerGas <= r.BaseFee {
+		t.Fatalf("expect eth_feeHistory based recommendation, got %+v", r)
+	}
+
+	tb, err := ethereum.NewTxBuilder(backend.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := tb.BuildTx(txbuilder.BuildTxParam{From: accounts[0].Address, To: accounts[1].Address, Value: big.NewInt(1000), GasLimit: 21000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := built.(*ethereum.Txn)
+	txn.Type = ethereum.DynamicFeeTxType
+	txHash, err := c.SendTx(txn, &fee.OptionFee{Speed: fee.SpeedFast})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.GasFeeCap == 0 || txn.GasTipCap == 0 {
+		t.Fatalf("expect fee caps set by speed, got %+v", txn)
+	}
+	txData, err := c.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || txData.GasUsed != 21000 {
+		t.Fatalf("unexpected dynamic fee tx %+v", txData)
+	}
+
+	rpc, err := ethereum.NewRPCClient(backend.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Type         string `json:"type"`
+		MaxFeePerGas string `json:"maxFeePerGas"`
+	}
+	if err := rpc.Call("eth_getTransactionByHash", &raw, txHash); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Type != "0x2" || raw.MaxFeePerGas != fmt.Sprintf("0x%x", txn.GasFeeCap) {
+		t.Fatalf("unexpected tx %+v", raw)
+	}
+	balance, err := c.BalanceOf(accounts[1].Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(new(big.Int).Add(simulated.DefaultBalance, big.NewInt(1001))) != 0 {
+		t.Fatalf("unexpected balance %s", balance)
+	}
+}
@@ -3,12 +3,11 @@ package ethereum
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/mgintoki/go-web3"
 	"github.com/mgintoki/go-web3/abi"
-	"github.com/mgintoki/go-web3/jsonrpc"
 	"github.com/mgintoki/multichain/api/fee"
 	"github.com/mgintoki/multichain/errno"
 	"math/big"
@@ -17,14 +16,19 @@ import (
 
 type Txn struct {
 	Private    *ecdsa.PrivateKey `json:"private"`
+	Type       uint8             `json:"type"`    // 交易类型，见 LegacyTxType 等定义
+	ChainID    uint64            `json:"chainId"` // 交易所在链的链ID，由 TxBuilder 构建时填充，为0代表未指定
 	From       web3.Address      `json:"from"`
 	Nonce      uint64            `json:"nonce"`
 	Addr       *web3.Address     `json:"to"`
 	Value      *big.Int          `json:"value"`
 	GasPrice   uint64            `json:"gasPrice"`
+	GasTipCap  uint64            `json:"maxPriorityFeePerGas"` // 仅 DynamicFeeTxType 使用
+	GasFeeCap  uint64            `json:"maxFeePerGas"`         // 仅 DynamicFeeTxType 使用
 	GasLimit   uint64            `json:"gas"`
 	Data       []byte            `json:"input"`
-	Provider   *jsonrpc.Client   `json:"provider"`
+	AccessList AccessList        `json:"accessList"` // 仅类型交易使用
+	Provider   *RPCClient        `json:"provider"`
 	Method     *abi.Method       `json:"method"`
 	Args       []interface{}     `json:"args"`
 	Bin        []byte            `json:"bin"`
@@ -32,10 +36,18 @@ type Txn struct {
 	SignedTx   []byte            `json:"signedTx"`
 	Hash       web3.Hash         `json:"hash"`
 	Receipt    *web3.Receipt     `json:"receipt"`
+
+	// sent 表示交易已经通过 Client 广播，签名后 Hash 即有值，但只有广播后才能 Wait
+	sent bool
 }
 
+// GetHash 返回签名后的交易hash，即上链后的交易hash，未签名的交易返回空字符串
+// 交易签名后即可取得hash，不代表交易已经广播
 func (t *Txn) GetHash() string {
-	return new(AddressEncoder).AddressToHex(t.Hash)
+	if t.Hash == (web3.Hash{}) {
+		return ""
+	}
+	return t.Hash.String()
 }
 
 func (t *Txn) GetNonce() uint64 {
@@ -64,37 +76,66 @@ func (t *Txn) GetPayload() []byte {
 
 func (t *Txn) SignTx(privateHex string, chainID string) error {
 
-	private := t.Private
-	if private == nil {
-		p, err := crypto.HexToECDSA(privateHex)
+	var signer Signer
+	if t.Private != nil {
+		signer = NewLocalSigner(t.Private)
+	} else {
+		s, err := NewLocalSignerFromHex(privateHex)
 		if err != nil {
 			return err
-		} else {
-			private = p
 		}
+		signer = s
 	}
 
-	chainIDInt, err := strconv.Atoi(chainID)
+	return t.SignWithSigner(signer, chainID)
+}
+
+// SignWithSigner 使用 Signer 对交易签名
+// 与 SignTx 不同，私钥不需要出现在调用方，签名可以在外部签名服务中完成
+func (t *Txn) SignWithSigner(signer Signer, chainID string) error {
+
+	chainIDInt, err := strconv.ParseUint(chainID, 10, 64)
 	if err != nil {
 		return err
 	}
+	if err := t.checkChainID(chainIDInt); err != nil {
+		return err
+	}
 
-	web3Tx := &web3.Transaction{
-		Nonce:    t.Nonce,
-		To:       t.Addr,
-		Value:    t.Value,
-		Gas:      t.GasLimit,
-		GasPrice: t.GasPrice,
-		Input:    t.Data,
+	if t.Type != LegacyTxType {
+		sig, err := signer.SignHash(t.signingHash(chainIDInt))
+		if err != nil {
+			return err
+		}
+		if t.SignedTx, err = t.typedSignedTx(chainIDInt, sig); err != nil {
+			return err
+		}
+		t.setSigned(chainIDInt)
+		return nil
 	}
 
-	web3Tx, err = SignTx(web3Tx, private, uint64(chainIDInt))
+	web3Tx, err := signer.SignTx(t.web3Tx(), chainIDInt)
 	if err != nil {
 		return err
 	}
 
-	t.SignedTx = web3Tx.MarshalRLP()
+	t.SignedTx = marshalTx(web3Tx)
+	t.setSigned(chainIDInt)
+
+	return nil
+}
+
+// setSigned 根据签名交易原文设置交易hash与链ID，签名后 GetHash 即可返回上链后的交易hash
+func (t *Txn) setSigned(chainID uint64) {
+	copy(t.Hash[:], keccak256(t.SignedTx))
+	t.ChainID = chainID
+}
 
+// checkChainID 校验签名使用的链ID与构建交易时的链ID一致，避免为一条链构建的交易在另一条链上签名
+func (t *Txn) checkChainID(chainID uint64) error {
+	if t.ChainID != 0 && t.ChainID != chainID {
+		return errno.ChainIDMismatch.Add(fmt.Sprintf("tx built for chain %d, signing for chain %d", t.ChainID, chainID))
+	}
 	return nil
 }
 
@@ -121,18 +162,13 @@ func (t *Txn) SignHash(privateHex string, chainID string, hexHash string) (hexSi
 	return hex.EncodeToString(sig), nil
 }
 
-func (t *Txn) EncodeTx() (txEncoded string, err error) {
-	b, err := json.Marshal(t)
-	if err != nil {
-		return "", err
-	} else {
-		return hex.EncodeToString(b), nil
-	}
-}
-
 func (t *Txn) GetFee() *fee.OptionFee {
+	gasPrice := t.GasPrice
+	if t.Type == DynamicFeeTxType {
+		gasPrice = t.GasFeeCap
+	}
 	return &fee.OptionFee{
-		GasPrice: t.GasPrice,
+		GasPrice: gasPrice,
 		GasLimit: t.GasLimit,
 	}
 }
@@ -152,15 +188,10 @@ func (t *Txn) GetTxHash(chainID string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	web3Tx := &web3.Transaction{
-		Nonce:    t.Nonce,
-		To:       t.Addr,
-		Value:    t.Value,
-		Gas:      t.GasLimit,
-		GasPrice: t.GasPrice,
-		Input:    t.Data,
+	if err := t.checkChainID(uint64(ci)); err != nil {
+		return "", err
 	}
-	hash := signHash(web3Tx, uint64(ci))
+	hash := t.signingHash(uint64(ci))
 	return hex.EncodeToString(hash), nil
 }
 
@@ -171,34 +202,45 @@ func (t *Txn) InjectSignature(hexSignature string, chainID string) (err error) {
 		return err
 	}
 
-	web3Tx := &web3.Transaction{
-		Nonce:    t.Nonce,
-		To:       t.Addr,
-		Value:    t.Value,
-		Gas:      t.GasLimit,
-		GasPrice: t.GasPrice,
-		Input:    t.Data,
-	}
-
 	chainIDBig, ok := new(big.Int).SetString(chainID, 10)
 	if !ok {
 		return errno.InvalidStringToBigNum
 	}
+	if err := t.checkChainID(chainIDBig.Uint64()); err != nil {
+		return err
+	}
 
-	vv := uint64(sig[64]) + 35 + chainIDBig.Uint64()*2
+	if t.Type != LegacyTxType {
+		if t.SignedTx, err = t.typedSignedTx(chainIDBig.Uint64(), sig); err != nil {
+			return err
+		}
+		t.setSigned(chainIDBig.Uint64())
+		return nil
+	}
 
-	web3Tx.R, web3Tx.S, err = trimLeadingZero(sig[:32], sig[32:64])
+	web3Tx, err := injectSignature(t.web3Tx(), sig, chainIDBig.Uint64())
 	if err != nil {
 		return err
 	}
 
-	web3Tx.V = new(big.Int).SetUint64(vv).Bytes()
-
-	t.SignedTx = web3Tx.MarshalRLP()
+	t.SignedTx = marshalTx(web3Tx)
+	t.setSigned(chainIDBig.Uint64())
 
 	return nil
 }
 
+// web3Tx 返回交易中需要签名的字段
+func (t *Txn) web3Tx() *web3.Transaction {
+	return &web3.Transaction{
+		Nonce:    t.Nonce,
+		To:       t.Addr,
+		Value:    t.Value,
+		Gas:      t.GasLimit,
+		GasPrice: t.GasPrice,
+		Input:    t.Data,
+	}
+}
+
 //func (t *Txn) SignTx2(private *ecdsa.PrivateKey, chainID uint64) error {
 //
 //	web3Tx := &web3.Transaction{
@@ -231,7 +273,11 @@ func (t *Txn) Validate() error {
 		t.Data = append(t.Data, t.Bin...)
 	}
 	if t.Method != nil {
-		data, err := abi.Encode(t.Args, t.Method.Inputs)
+		args, err := CoerceArgs(t.Method.Inputs, t.Args)
+		if err != nil {
+			return err
+		}
+		data, err := abi.Encode(args, t.Method.Inputs)
 		if err != nil {
 			return fmt.Errorf("failed to encode arguments: %v", err)
 		}
@@ -259,14 +305,14 @@ func (t *Txn) SetGasLimit(gasLimit uint64) *Txn {
 // Wait waits till the transaction is mined
 func (t *Txn) Wait() error {
 
-	if (t.Hash == web3.Hash{}) {
+	if !t.sent {
 		panic("transaction not executed")
 	}
 	var err error
 	for {
 		t.Receipt, err = t.Provider.Eth().GetTransactionReceipt(t.Hash)
 		if err != nil {
-			if err.Error() != "not found" {
+			if !errors.Is(err, errno.NotFound) {
 				return err
 			}
 		}
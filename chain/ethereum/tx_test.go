@@ -0,0 +1,70 @@
+package ethereum
+
+import (
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/testkit"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const testLegacyTxHash = "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788"
+
+// TestTxHashAndWait 签名后即可取得交易hash，但只有广播后才能 Wait
+func TestTxHashAndWait(t *testing.T) {
+	to := web3.HexToAddress("0x3535353535353535353535353535353535353535")
+	txn := &Txn{
+		Addr:     &to,
+		Value:    big.NewInt(1000000000000000000),
+		GasPrice: 20000000000,
+		GasLimit: 21000,
+		Nonce:    9,
+	}
+	if txn.GetHash() != "" {
+		t.Fatalf("expect empty hash before signing, got %s", txn.GetHash())
+	}
+
+	signer, err := NewLocalSignerFromHex(testLegacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.SignWithSigner(signer, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if txn.GetHash() != testLegacyTxHash {
+		t.Fatalf("unexpected hash %s", txn.GetHash())
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expect Wait on unsent tx to panic")
+			}
+		}()
+		txn.Wait()
+	}()
+
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_chainId", "0x1").
+		Respond("eth_sendRawTransaction", testLegacyTxHash).
+		Respond("eth_getTransactionReceipt", testkit.Raw(`{"transactionHash":"`+testLegacyTxHash+`","transactionIndex":"0x0","blockHash":"`+testLegacyTxHash+`",
+			"blockNumber":"0x1","gasUsed":"0x5208","cumulativeGasUsed":"0x5208","status":"0x1","logs":[],"from":"`+testLegacySender+`",
+			"logsBloom":"0x`+strings.Repeat("00", 256)+`"}`))
+	c, err := NewClient(server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := c.SendSignedTx(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != txn.GetHash() {
+		t.Fatalf("expect tx hash %s, got %s", txn.GetHash(), hash)
+	}
+	if err := txn.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if txn.Receipt == nil || txn.Receipt.GasUsed != 21000 {
+		t.Fatalf("unexpected receipt %+v", txn.Receipt)
+	}
+}
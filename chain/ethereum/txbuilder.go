@@ -2,11 +2,8 @@ package ethereum
 
 import (
 	"encoding/hex"
-	"encoding/json"
-	"fmt"
 	"github.com/mgintoki/go-web3"
 	"github.com/mgintoki/go-web3/abi"
-	"github.com/mgintoki/go-web3/jsonrpc"
 	"github.com/mgintoki/multichain/api/provider"
 	"github.com/mgintoki/multichain/api/tx"
 	"github.com/mgintoki/multichain/api/txbuilder"
@@ -15,15 +12,16 @@ import (
 )
 
 type TxBuilder struct {
-	provider *jsonrpc.Client
+	provider *RPCClient
+	oracle   *FeeOracle
 }
 
 func NewTxBuilder(provider provider.CommonProvider) (*TxBuilder, error) {
-	p, err := jsonrpc.NewClient(provider.ProviderUrl)
+	p, err := NewRPCClient(provider)
 	if err != nil {
 		return nil, err
 	}
-	txBuilder := &TxBuilder{p}
+	txBuilder := &TxBuilder{p, &FeeOracle{provider: p, caps: provider.FeeCaps}}
 	return txBuilder, nil
 }
 
@@ -48,8 +46,15 @@ func (t *TxBuilder) BuildTx(req txbuilder.BuildTxParam) (tx tx.Tx, err error) {
 		}
 	}
 
+	// 链ID写入交易，交易序列化后交给其他服务签名时，可以校验签名使用的链ID
+	chainID, err := t.provider.Eth().ChainID()
+	if err != nil {
+		return nil, err
+	}
+
 	txn := &Txn{
 		Provider: t.provider,
+		ChainID:  chainID.Uint64(),
 		From:     web3.HexToAddress(from),
 		Addr:     toAddr,
 		Data:     req.Payload,
@@ -60,7 +65,7 @@ func (t *TxBuilder) BuildTx(req txbuilder.BuildTxParam) (tx tx.Tx, err error) {
 	if req.GasPrice != 0 {
 		txn.GasPrice = req.GasPrice
 	} else {
-		txn.GasPrice, err = t.provider.Eth().GasPrice()
+		txn.GasPrice, err = t.feeOracle().GasPrice(req.Speed)
 		if err != nil {
 			return nil, err
 		}
@@ -78,92 +83,156 @@ func (t *TxBuilder) BuildTx(req txbuilder.BuildTxParam) (tx tx.Tx, err error) {
 	return txn, err
 }
 
+// feeOracle 返回 TxBuilder 使用的费用预言机，直接创建的 TxBuilder 使用不限制 gas 价格的预言机
+func (t *TxBuilder) feeOracle() *FeeOracle {
+	if t.oracle == nil {
+		return &FeeOracle{provider: t.provider}
+	}
+	return t.oracle
+}
+
+// DecodeTx 解析一个序列化后的交易
+// encodedTx 可以是 EncodeTx 输出的 TxEnvelope (也兼容旧版本SDK的输出)，也可以是16进制格式 (可带0x前缀) 的签名交易原文，
+// 签名交易支持传统交易、EIP-2930 和 EIP-1559 交易，解析得到的交易带有由签名恢复出的 From，可使用 SendSignedTx 广播
 func (t *TxBuilder) DecodeTx(encodedTx string) (tx tx.Tx, err error) {
-	txByte, err := hex.DecodeString(encodedTx)
+	txByte, err := hex.DecodeString(strings.TrimPrefix(encodedTx, "0x"))
 	if err != nil {
 		return nil, err
 	}
-	var txn *Txn
-	if err := json.Unmarshal(txByte, &txn); err != nil {
-		return nil, err
-	} else {
+	if len(txByte) == 0 {
+		return nil, errno.ParseTxError.Add("empty tx")
+	}
+
+	// EncodeTx 的输出是json, 以 '{' 开头; 签名交易以RLP列表前缀或交易类型开头
+	if txByte[0] != '{' {
+		signed, err := DecodeSignedTx(txByte)
+		if err != nil {
+			return nil, err
+		}
+		txn := signed.Txn()
+		txn.Provider = t.provider
 		return txn, nil
 	}
+
+	txn, err := decodeEnvelope(txByte)
+	if err != nil {
+		return nil, err
+	}
+	txn.Provider = t.provider
+	return txn, nil
 }
 
 type ContractTxBuilder struct {
-	provider *jsonrpc.Client
+	provider *RPCClient
+	oracle   *FeeOracle
 }
 
 func NewContractTxBuilder(provider provider.CommonProvider) (*ContractTxBuilder, error) {
-	p, err := jsonrpc.NewClient(provider.ProviderUrl)
+	p, err := NewRPCClient(provider)
 	if err != nil {
 		return nil, err
 	}
-	txBuilder := &ContractTxBuilder{p}
+	txBuilder := &ContractTxBuilder{p, &FeeOracle{provider: p, caps: provider.FeeCaps}}
 	return txBuilder, nil
 }
 
 //构建部署合约的交易
+//返回的 *Txn 可以通过 ContractAddress 获取将要部署的合约地址
 func (b *ContractTxBuilder) BuildDeployTx(req txbuilder.BuildDeployTxReq) (tx.Tx, error) {
 
-	abiIns, err := abi.NewABI(req.Abi)
+	data, err := EncodeDeployData(req.Abi, req.ByteCode, req.Params)
 	if err != nil {
 		return nil, err
 	}
 
-	bin, err := hex.DecodeString(strings.TrimPrefix(req.ByteCode, "0x"))
-	if err != nil {
-		return nil, err
-	}
+	txn, err := b.txBuilder().BuildTx(txbuilder.BuildTxParam{
+		From:     req.From,
+		Payload:  data,
+		Nonce:    req.Nonce,
+		Value:    req.Value,
+		GasPrice: req.GasPrice,
+		GasLimit: req.GasLimit,
+		Speed:    req.Speed,
+	})
+	return txn, err
+}
+
+//构建调用合约的交易
+func (b *ContractTxBuilder) BuildInvokeTx(req txbuilder.BuildInvokeTxReq) (tx.Tx, error) {
 
-	data, err := abi.Encode(req.Params, abiIns.Constructor.Inputs)
+	data, err := EncodeInvokeData(req.Abi, req.Method, req.Params)
 	if err != nil {
 		return nil, err
 	}
 
-	data = append(bin, data...)
-
-	t := &TxBuilder{b.provider}
-	txn, err := t.BuildTx(txbuilder.BuildTxParam{
+	return b.txBuilder().BuildTx(txbuilder.BuildTxParam{
 		From:     req.From,
+		To:       req.ContractAddress,
 		Payload:  data,
 		Nonce:    req.Nonce,
 		Value:    req.Value,
 		GasPrice: req.GasPrice,
 		GasLimit: req.GasLimit,
+		Speed:    req.Speed,
 	})
-	return txn, err
 }
 
-//构建调用合约的交易
-func (b *ContractTxBuilder) BuildInvokeTx(req txbuilder.BuildInvokeTxReq) (tx.Tx, error) {
+// txBuilder 返回与 ContractTxBuilder 使用相同节点与费用预言机的 TxBuilder
+func (b *ContractTxBuilder) txBuilder() *TxBuilder {
+	return &TxBuilder{b.provider, b.oracle}
+}
 
-	abiIns, err := abi.NewABI(req.Abi)
+// EncodeDeployData 编码部署合约的 initcode (字节码 + 构造函数参数)
+// 与 BuildDeployTx 使用的编码一致，可用于 CREATE2 部署与地址预测
+func EncodeDeployData(abiStr string, byteCode string, params []interface{}) ([]byte, error) {
+	abiIns, err := ParseContractABI(abiStr)
 	if err != nil {
 		return nil, err
 	}
 
-	m, ok := abiIns.Methods[req.Method]
-	if !ok {
-		return nil, fmt.Errorf("invalid method")
+	bin, err := hex.DecodeString(strings.TrimPrefix(byteCode, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if abiIns.Constructor != nil {
+		params, err := CoerceArgs(abiIns.Constructor.Inputs, params)
+		if err != nil {
+			return nil, err
+		}
+		data, err = abi.Encode(params, abiIns.Constructor.Inputs)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(params) != 0 {
+		return nil, errno.InvalidContractArg.Add("contract has no constructor arguments")
+	}
+
+	return append(bin, data...), nil
+}
+
+// EncodeInvokeData 按照 abi 编码调用合约方法的 calldata (方法选择器 + 参数)
+// 与 BuildInvokeTx 使用的编码一致，可用于构建 UserOperation、Safe 交易等需要 calldata 的场景
+func EncodeInvokeData(abiStr string, method string, params []interface{}) ([]byte, error) {
+	abiIns, err := ParseContractABI(abiStr)
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := abi.Encode(req.Params, m.Inputs)
+	m, err := abiIns.Method(method)
 	if err != nil {
 		return nil, err
 	}
 
-	data = append(m.ID(), data...)
+	params, err = CoerceArgs(m.Inputs, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := abi.Encode(params, m.Inputs)
+	if err != nil {
+		return nil, err
+	}
 
-	t := &TxBuilder{b.provider}
-	return t.BuildTx(txbuilder.BuildTxParam{
-		From:     req.From,
-		To:       req.ContractAddress,
-		Payload:  data,
-		Nonce:    req.Nonce,
-		Value:    req.Value,
-		GasPrice: req.GasPrice,
-		GasLimit: req.GasLimit,
-	})
+	return append(m.ID(), data...), nil
 }
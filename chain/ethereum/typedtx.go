@@ -0,0 +1,130 @@
+package ethereum
+
+import (
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/umbracle/fastrlp"
+	"math/big"
+)
+
+// 以太坊交易类型，见 EIP-2718
+const (
+	LegacyTxType     = 0 // 传统交易
+	AccessListTxType = 1 // EIP-2930 访问列表交易
+	DynamicFeeTxType = 2 // EIP-1559 动态手续费交易
+)
+
+// txArenaPool 是本包计算交易RLP使用的 Arena 池
+// go-web3 在 fastrlp.DefaultArenaPool 中使用 NewBytes 引用交易字段的内存，复用其中的 Arena 可能改写这些字段
+var txArenaPool fastrlp.ArenaPool
+
+// AccessTuple 是访问列表中的一项
+type AccessTuple struct {
+	Address     web3.Address `json:"address"`
+	StorageKeys []web3.Hash  `json:"storageKeys"`
+}
+
+// AccessList 是 EIP-2930 定义的访问列表
+type AccessList []AccessTuple
+
+// signingHash 返回交易需要被签名的hash
+// 传统交易按照 EIP-155 计算，类型交易计算 keccak256(type ‖ rlp(payload))
+func (t *Txn) signingHash(chainID uint64) []byte {
+	if t.Type == LegacyTxType {
+		return signHash(t.web3Tx(), chainID)
+	}
+	a := txArenaPool.Get()
+	defer txArenaPool.Put(a)
+
+	v := t.typedPayload(a, chainID)
+	return keccak256(append([]byte{t.Type}, v.MarshalTo(nil)...))
+}
+
+// typedSignedTx 将 [R || S || V] 格式的签名注入类型交易，返回 type ‖ rlp(payload ‖ yParity ‖ r ‖ s)
+func (t *Txn) typedSignedTx(chainID uint64, sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, errno.InvalidSignature.Add("signature must be 65 bytes")
+	}
+	if sig[64] > 1 {
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("invalid y parity %d", sig[64]))
+	}
+	a := txArenaPool.Get()
+	defer txArenaPool.Put(a)
+
+	v := t.typedPayload(a, chainID)
+	v.Set(a.NewUint(uint64(sig[64])))
+	v.Set(a.NewBigInt(new(big.Int).SetBytes(sig[:32])))
+	v.Set(a.NewBigInt(new(big.Int).SetBytes(sig[32:64])))
+	return append([]byte{t.Type}, v.MarshalTo(nil)...), nil
+}
+
+func (t *Txn) typedPayload(a *fastrlp.Arena, chainID uint64) *fastrlp.Value {
+	v := a.NewArray()
+	v.Set(a.NewUint(chainID))
+	v.Set(a.NewUint(t.Nonce))
+	if t.Type == DynamicFeeTxType {
+		v.Set(a.NewUint(t.GasTipCap))
+		v.Set(a.NewUint(t.GasFeeCap))
+	} else {
+		v.Set(a.NewUint(t.GasPrice))
+	}
+	v.Set(a.NewUint(t.GasLimit))
+	if t.Addr == nil {
+		v.Set(a.NewNull())
+	} else {
+		v.Set(a.NewCopyBytes((*t.Addr)[:]))
+	}
+	v.Set(a.NewBigInt(t.Value))
+	v.Set(a.NewCopyBytes(t.Data))
+
+	accessList := a.NewArray()
+	for _, tuple := range t.AccessList {
+		at := a.NewArray()
+		at.Set(a.NewCopyBytes(tuple.Address[:]))
+		keys := a.NewArray()
+		for _, key := range tuple.StorageKeys {
+			keys.Set(a.NewCopyBytes(key[:]))
+		}
+		at.Set(keys)
+		accessList.Set(at)
+	}
+	v.Set(accessList)
+	return v
+}
+
+func decodeAccessList(v *fastrlp.Value) (AccessList, error) {
+	elems, err := v.GetElems()
+	if err != nil {
+		return nil, errno.ParseTxError.Add("accessList: " + err.Error())
+	}
+	accessList := make(AccessList, 0, len(elems))
+	for _, elem := range elems {
+		tuple, err := elem.GetElems()
+		if err != nil || len(tuple) != 2 {
+			return nil, errno.ParseTxError.Add("accessList: invalid tuple")
+		}
+		var at AccessTuple
+		addr, err := tuple[0].GetBytes(nil, 20)
+		if err != nil {
+			return nil, errno.ParseTxError.Add("accessList: " + err.Error())
+		}
+		copy(at.Address[:], addr)
+
+		keys, err := tuple[1].GetElems()
+		if err != nil {
+			return nil, errno.ParseTxError.Add("accessList: " + err.Error())
+		}
+		for _, key := range keys {
+			b, err := key.GetBytes(nil, 32)
+			if err != nil {
+				return nil, errno.ParseTxError.Add("accessList: " + err.Error())
+			}
+			var h web3.Hash
+			copy(h[:], b)
+			at.StorageKeys = append(at.StorageKeys, h)
+		}
+		accessList = append(accessList, at)
+	}
+	return accessList, nil
+}
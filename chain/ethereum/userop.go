@@ -0,0 +1,419 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/go-web3/abi"
+	"github.com/mgintoki/go-web3/jsonrpc"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// EntryPointV06 是 ERC-4337 v0.6 EntryPoint 合约在各链上的地址
+const EntryPointV06 = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+// SimpleAccountABI 是 SimpleAccount 风格智能合约账户中执行调用的方法
+const SimpleAccountABI = `[
+{"type":"function","name":"execute","stateMutability":"nonpayable","inputs":[{"name":"dest","type":"address"},{"name":"value","type":"uint256"},{"name":"func","type":"bytes"}],"outputs":[]},
+{"type":"function","name":"executeBatch","stateMutability":"nonpayable","inputs":[{"name":"dest","type":"address[]"},{"name":"func","type":"bytes[]"}],"outputs":[]}
+]`
+
+const entryPointABI = `[
+{"type":"function","name":"getNonce","stateMutability":"view","inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],"outputs":[{"name":"nonce","type":"uint256"}]}
+]`
+
+// 估算gas时使用的占位签名，与常见 ECDSA 账户的签名长度一致，且能通过 ecrecover 而不会导致验证提前 revert
+var dummyUserOpSignature, _ = hex.DecodeString("fffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+// UserOperation 是 ERC-4337 (EntryPoint v0.6) 的用户操作
+type UserOperation struct {
+	Sender               web3.Address
+	Nonce                *big.Int
+	InitCode             []byte // 账户尚未部署时为 factory 地址 + 创建账户的 calldata
+	CallData             []byte // 账户执行的 calldata，如 EncodeExecuteCallData 的结果
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// UserOperationGas 是 eth_estimateUserOperationGas 的返回
+type UserOperationGas struct {
+	PreVerificationGas   *big.Int
+	VerificationGasLimit *big.Int
+	CallGasLimit         *big.Int
+}
+
+// UserOperationReceipt 是 eth_getUserOperationReceipt 的返回
+type UserOperationReceipt struct {
+	UserOpHash    web3.Hash
+	EntryPoint    web3.Address
+	Sender        web3.Address
+	Nonce         *big.Int
+	Paymaster     web3.Address
+	ActualGasCost *big.Int
+	ActualGasUsed *big.Int
+	Success       bool
+	Reason        string
+	// TxHash 是打包该用户操作的交易hash
+	TxHash web3.Hash
+	// Receipt 是打包该用户操作的交易的收据原文
+	Receipt json.RawMessage
+}
+
+type rpcUserOperation struct {
+	Sender               web3.Address  `json:"sender"`
+	Nonce                *hexutil.Big  `json:"nonce"`
+	InitCode             hexutil.Bytes `json:"initCode"`
+	CallData             hexutil.Bytes `json:"callData"`
+	CallGasLimit         *hexutil.Big  `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big  `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big  `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big  `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big  `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes `json:"paymasterAndData"`
+	Signature            hexutil.Bytes `json:"signature"`
+}
+
+// MarshalJSON 按照 bundler JSON-RPC 的格式编码，数值与字节均为16进制字符串
+func (op *UserOperation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&rpcUserOperation{
+		Sender:               op.Sender,
+		Nonce:                (*hexutil.Big)(bigOrZero(op.Nonce)),
+		InitCode:             bytesOrEmpty(op.InitCode),
+		CallData:             bytesOrEmpty(op.CallData),
+		CallGasLimit:         (*hexutil.Big)(bigOrZero(op.CallGasLimit)),
+		VerificationGasLimit: (*hexutil.Big)(bigOrZero(op.VerificationGasLimit)),
+		PreVerificationGas:   (*hexutil.Big)(bigOrZero(op.PreVerificationGas)),
+		MaxFeePerGas:         (*hexutil.Big)(bigOrZero(op.MaxFeePerGas)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(bigOrZero(op.MaxPriorityFeePerGas)),
+		PaymasterAndData:     bytesOrEmpty(op.PaymasterAndData),
+		Signature:            bytesOrEmpty(op.Signature),
+	})
+}
+
+// UnmarshalJSON 解析 bundler JSON-RPC 格式的用户操作
+func (op *UserOperation) UnmarshalJSON(input []byte) error {
+	var dec rpcUserOperation
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*op = UserOperation{
+		Sender:               dec.Sender,
+		Nonce:                dec.Nonce.ToInt(),
+		InitCode:             dec.InitCode,
+		CallData:             dec.CallData,
+		CallGasLimit:         dec.CallGasLimit.ToInt(),
+		VerificationGasLimit: dec.VerificationGasLimit.ToInt(),
+		PreVerificationGas:   dec.PreVerificationGas.ToInt(),
+		MaxFeePerGas:         dec.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: dec.MaxPriorityFeePerGas.ToInt(),
+		PaymasterAndData:     dec.PaymasterAndData,
+		Signature:            dec.Signature,
+	}
+	return nil
+}
+
+// Hash 计算用户操作的hash (userOpHash)，与 EntryPoint v0.6 getUserOpHash 的返回一致:
+// keccak256(abi.encode(keccak256(pack(userOp)), entryPoint, chainId))
+func (op *UserOperation) Hash(entryPoint string, chainID uint64) []byte {
+	packed := concatWords(
+		leftPad32(op.Sender[:]),
+		leftPad32(bigOrZero(op.Nonce).Bytes()),
+		keccak256(op.InitCode),
+		keccak256(op.CallData),
+		leftPad32(bigOrZero(op.CallGasLimit).Bytes()),
+		leftPad32(bigOrZero(op.VerificationGasLimit).Bytes()),
+		leftPad32(bigOrZero(op.PreVerificationGas).Bytes()),
+		leftPad32(bigOrZero(op.MaxFeePerGas).Bytes()),
+		leftPad32(bigOrZero(op.MaxPriorityFeePerGas).Bytes()),
+		keccak256(op.PaymasterAndData),
+	)
+	ep := web3.HexToAddress(entryPoint)
+	return keccak256(concatWords(
+		keccak256(packed),
+		leftPad32(ep[:]),
+		leftPad32(new(big.Int).SetUint64(chainID).Bytes()),
+	))
+}
+
+// SignUserOperation 使用账户 owner 的 Signer 对用户操作签名，并写入 op.Signature
+// 签名方式与 SimpleAccount 一致，对 userOpHash 做 EIP-191 (personal_sign) 签名
+func SignUserOperation(signer Signer, op *UserOperation, entryPoint string, chainID uint64) error {
+	sig, err := signMessage(signer, op.Hash(entryPoint, chainID))
+	if err != nil {
+		return err
+	}
+	op.Signature = sig
+	return nil
+}
+
+// EncodeExecuteCallData 编码 SimpleAccount 风格账户 execute(dest, value, func) 的 calldata
+// data 可以使用 EncodeInvokeData 按照目标合约的 abi 编码
+func EncodeExecuteCallData(to string, value *big.Int, data []byte) ([]byte, error) {
+	return EncodeInvokeData(SimpleAccountABI, "execute", []interface{}{web3.HexToAddress(to), bigOrZero(value), bytesOrEmpty(data)})
+}
+
+// Bundler 是 ERC-4337 bundler 的 JSON-RPC 客户端
+type Bundler struct {
+	provider *jsonrpc.Client
+}
+
+// NewBundler 新建一个 bundler 客户端，url 为 bundler 的 JSON-RPC 地址
+func NewBundler(url string) (*Bundler, error) {
+	p, err := jsonrpc.NewClient(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundler{p}, nil
+}
+
+// SupportedEntryPoints 查询 bundler 支持的 EntryPoint 地址
+func (b *Bundler) SupportedEntryPoints() ([]string, error) {
+	var entryPoints []string
+	if err := b.provider.Call("eth_supportedEntryPoints", &entryPoints); err != nil {
+		return nil, err
+	}
+	return entryPoints, nil
+}
+
+// EstimateUserOperationGas 估算用户操作的gas
+// op.Signature 为空时使用占位签名估算
+func (b *Bundler) EstimateUserOperationGas(op *UserOperation, entryPoint string) (*UserOperationGas, error) {
+	estimated := *op
+	if len(estimated.Signature) == 0 {
+		estimated.Signature = dummyUserOpSignature
+	}
+	var res struct {
+		PreVerificationGas   rpcBig `json:"preVerificationGas"`
+		VerificationGasLimit rpcBig `json:"verificationGasLimit"`
+		CallGasLimit         rpcBig `json:"callGasLimit"`
+	}
+	if err := b.provider.Call("eth_estimateUserOperationGas", &res, &estimated, entryPoint); err != nil {
+		return nil, err
+	}
+	return &UserOperationGas{
+		PreVerificationGas:   res.PreVerificationGas.Int(),
+		VerificationGasLimit: res.VerificationGasLimit.Int(),
+		CallGasLimit:         res.CallGasLimit.Int(),
+	}, nil
+}
+
+// SendUserOperation 提交已签名的用户操作，返回 userOpHash
+func (b *Bundler) SendUserOperation(op *UserOperation, entryPoint string) (userOpHash string, err error) {
+	if err := b.provider.Call("eth_sendUserOperation", &userOpHash, op, entryPoint); err != nil {
+		return "", err
+	}
+	return userOpHash, nil
+}
+
+// GetUserOperationReceipt 查询用户操作的收据，用户操作尚未上链时返回 nil
+func (b *Bundler) GetUserOperationReceipt(userOpHash string) (*UserOperationReceipt, error) {
+	var res *struct {
+		UserOpHash    web3.Hash       `json:"userOpHash"`
+		EntryPoint    web3.Address    `json:"entryPoint"`
+		Sender        web3.Address    `json:"sender"`
+		Nonce         rpcBig          `json:"nonce"`
+		Paymaster     web3.Address    `json:"paymaster"`
+		ActualGasCost rpcBig          `json:"actualGasCost"`
+		ActualGasUsed rpcBig          `json:"actualGasUsed"`
+		Success       bool            `json:"success"`
+		Reason        string          `json:"reason"`
+		Receipt       json.RawMessage `json:"receipt"`
+	}
+	if err := b.provider.Call("eth_getUserOperationReceipt", &res, userOpHash); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+
+	receipt := &UserOperationReceipt{
+		UserOpHash:    res.UserOpHash,
+		EntryPoint:    res.EntryPoint,
+		Sender:        res.Sender,
+		Nonce:         res.Nonce.Int(),
+		Paymaster:     res.Paymaster,
+		ActualGasCost: res.ActualGasCost.Int(),
+		ActualGasUsed: res.ActualGasUsed.Int(),
+		Success:       res.Success,
+		Reason:        res.Reason,
+		Receipt:       res.Receipt,
+	}
+	var txReceipt struct {
+		TransactionHash web3.Hash `json:"transactionHash"`
+	}
+	if len(res.Receipt) != 0 && json.Unmarshal(res.Receipt, &txReceipt) == nil {
+		receipt.TxHash = txReceipt.TransactionHash
+	}
+	return receipt, nil
+}
+
+// WaitUserOperationReceipt 每隔 interval 查询一次用户操作的收据，直到上链或超时
+func (b *Bundler) WaitUserOperationReceipt(userOpHash string, interval time.Duration, timeout time.Duration) (*UserOperationReceipt, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		receipt, err := b.GetUserOperationReceipt(userOpHash)
+		if err != nil {
+			return nil, err
+		}
+		if receipt != nil {
+			return receipt, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return nil, errno.UserOpReceiptTimeout.Add(userOpHash)
+		}
+	}
+}
+
+// BuildUserOperationReq 是构建用户操作的参数
+type BuildUserOperationReq struct {
+	Sender               string   // 智能合约账户地址
+	EntryPoint           string   // 可选，不传则使用 EntryPointV06
+	InitCode             []byte   // 可选，账户尚未部署时需要
+	CallData             []byte   // 账户执行的 calldata
+	NonceKey             *big.Int // 可选，EntryPoint 二维nonce的key
+	Nonce                *big.Int // 可选，不传则从 EntryPoint 查询
+	MaxFeePerGas         *big.Int // 可选，不传则使用 eth_gasPrice
+	MaxPriorityFeePerGas *big.Int // 可选，不传则与 MaxFeePerGas 相同
+	PaymasterAndData     []byte   // 可选
+}
+
+// BuildUserOperation 构建用户操作，补全nonce、gas价格，并通过 bundler 估算gas
+// 返回的用户操作需要签名后再提交，见 SendUserOperation
+func (c *Client) BuildUserOperation(bundler *Bundler, req BuildUserOperationReq) (*UserOperation, error) {
+	if c.provider == nil {
+		return nil, errno.ProviderNotSet
+	}
+	entryPoint := req.EntryPoint
+	if entryPoint == "" {
+		entryPoint = EntryPointV06
+	}
+
+	op := &UserOperation{
+		Sender:               web3.HexToAddress(req.Sender),
+		Nonce:                req.Nonce,
+		InitCode:             req.InitCode,
+		CallData:             req.CallData,
+		MaxFeePerGas:         req.MaxFeePerGas,
+		MaxPriorityFeePerGas: req.MaxPriorityFeePerGas,
+		PaymasterAndData:     req.PaymasterAndData,
+	}
+
+	if op.Nonce == nil {
+		nonce, err := c.GetUserOperationNonce(entryPoint, req.Sender, req.NonceKey)
+		if err != nil {
+			return nil, err
+		}
+		op.Nonce = nonce
+	}
+	if op.MaxFeePerGas == nil {
+		gasPrice, err := c.provider.Eth().GasPrice()
+		if err != nil {
+			return nil, err
+		}
+		op.MaxFeePerGas = new(big.Int).SetUint64(gasPrice)
+	}
+	if op.MaxPriorityFeePerGas == nil {
+		op.MaxPriorityFeePerGas = op.MaxFeePerGas
+	}
+
+	gas, err := bundler.EstimateUserOperationGas(op, entryPoint)
+	if err != nil {
+		return nil, err
+	}
+	op.PreVerificationGas = gas.PreVerificationGas
+	op.VerificationGasLimit = gas.VerificationGasLimit
+	op.CallGasLimit = gas.CallGasLimit
+	return op, nil
+}
+
+// SendUserOperation 使用 Client 的签名者 (智能合约账户的 owner) 对用户操作签名并提交到 bundler，返回 userOpHash
+func (c *Client) SendUserOperation(bundler *Bundler, op *UserOperation, entryPoint string) (userOpHash string, err error) {
+	if c.signer == nil {
+		return "", errno.PrivateNotSet
+	}
+	if entryPoint == "" {
+		entryPoint = EntryPointV06
+	}
+	chainID, err := c.provider.Eth().ChainID()
+	if err != nil {
+		return "", err
+	}
+	if err := SignUserOperation(c.signer, op, entryPoint, chainID.Uint64()); err != nil {
+		return "", err
+	}
+	return bundler.SendUserOperation(op, entryPoint)
+}
+
+// GetUserOperationNonce 从 EntryPoint 查询智能合约账户的nonce
+func (c *Client) GetUserOperationNonce(entryPoint string, sender string, key *big.Int) (*big.Int, error) {
+	if c.provider == nil {
+		return nil, errno.ProviderNotSet
+	}
+	abiIns, err := abi.NewABI(entryPointABI)
+	if err != nil {
+		return nil, err
+	}
+	contract := NewContract(web3.HexToAddress(entryPoint), abiIns, c.provider)
+	contract.SetFrom(web3.HexToAddress(DefaultAddress))
+	_, res, err := contract.Call("getNonce", web3.Latest, web3.HexToAddress(sender), bigOrZero(key))
+	if err != nil {
+		return nil, err
+	}
+	nonce, ok := res["nonce"].(*big.Int)
+	if !ok {
+		return nil, errno.InvalidTypeAssert.Add("getNonce")
+	}
+	return nonce, nil
+}
+
+// rpcBig 兼容 bundler 返回的16进制字符串、10进制字符串和json数字
+type rpcBig struct {
+	i *big.Int
+}
+
+func (r *rpcBig) UnmarshalJSON(input []byte) error {
+	s := strings.Trim(string(input), `"`)
+	if s == "null" || s == "" {
+		return nil
+	}
+	i, err := parseTypedIntegerString(s)
+	if err != nil {
+		return err
+	}
+	r.i = i
+	return nil
+}
+
+func (r rpcBig) Int() *big.Int {
+	return bigOrZero(r.i)
+}
+
+func concatWords(words ...[]byte) []byte {
+	var b []byte
+	for _, w := range words {
+		b = append(b, w...)
+	}
+	return b
+}
+
+func bytesOrEmpty(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
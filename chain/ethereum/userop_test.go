@@ -0,0 +1,102 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUserOperation(t *testing.T) {
+	if len(dummyUserOpSignature) != 65 {
+		t.Fatalf("unexpected dummy signature length %d", len(dummyUserOpSignature))
+	}
+
+	owner, _ := NewLocalSignerFromHex("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	callData, err := EncodeExecuteCallData("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c", big.NewInt(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := &UserOperation{
+		Sender:       web3.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"),
+		Nonce:        big.NewInt(0),
+		CallData:     callData,
+		MaxFeePerGas: big.NewInt(1e9),
+	}
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		var result string
+		switch req.Method {
+		case "eth_estimateUserOperationGas":
+			var sent UserOperation
+			if err := json.Unmarshal(req.Params[0], &sent); err != nil || len(sent.Signature) != 65 {
+				t.Fatalf("unexpected user operation %s", req.Params[0])
+			}
+			result = `{"preVerificationGas":"0xb000","verificationGasLimit":70000,"callGasLimit":"0x5208"}`
+		case "eth_sendUserOperation":
+			var sent UserOperation
+			if err := json.Unmarshal(req.Params[0], &sent); err != nil {
+				t.Fatal(err)
+			}
+			hash := sent.Hash(EntryPointV06, 56)
+			result = fmt.Sprintf(`"0x%x"`, hash)
+		case "eth_getUserOperationReceipt":
+			if polls++; polls < 2 {
+				result = `null`
+			} else {
+				result = `{"success":true,"actualGasCost":"0x10","receipt":{"transactionHash":"0x0000000000000000000000000000000000000000000000000000000000000001"}}`
+			}
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + result + `}`))
+	}))
+	defer server.Close()
+
+	bundler, err := NewBundler(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gas, err := bundler.EstimateUserOperationGas(op, EntryPointV06)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas.PreVerificationGas.Int64() != 0xb000 || gas.VerificationGasLimit.Int64() != 70000 || gas.CallGasLimit.Int64() != 21000 {
+		t.Fatalf("unexpected gas %+v", gas)
+	}
+	op.PreVerificationGas, op.VerificationGasLimit, op.CallGasLimit = gas.PreVerificationGas, gas.VerificationGasLimit, gas.CallGasLimit
+
+	if err := SignUserOperation(owner, op, EntryPointV06, 56); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := VerifyMessage(op.Hash(EntryPointV06, 56), op.Signature, owner.Address().String()); err != nil || !ok {
+		t.Fatalf("signature does not recover to owner: %v", err)
+	}
+
+	userOpHash, err := bundler.SendUserOperation(op, EntryPointV06)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userOpHash != fmt.Sprintf("0x%x", op.Hash(EntryPointV06, 56)) {
+		t.Fatalf("unexpected user operation hash %s", userOpHash)
+	}
+	receipt, err := bundler.WaitUserOperationReceipt(userOpHash, 10*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !receipt.Success || receipt.ActualGasCost.Int64() != 16 || receipt.TxHash != web3.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000001") {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+}
@@ -0,0 +1,261 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/umbracle/fastrlp"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SignedTx 是从签名交易中解析出的交易
+type SignedTx struct {
+	Type       uint8
+	Nonce      uint64
+	GasPrice   uint64
+	GasTipCap  uint64
+	GasFeeCap  uint64
+	Gas        uint64
+	To         *web3.Address
+	Value      *big.Int
+	Input      []byte
+	AccessList AccessList
+	V          *big.Int
+	R          *big.Int
+	S          *big.Int
+
+	// ChainID 是交易的链ID，传统交易由V推导得出，为0代表交易未使用 EIP-155 签名
+	ChainID uint64
+	// Hash 是交易hash
+	Hash web3.Hash
+	// From 是由签名恢复出的交易发起方
+	From web3.Address
+	// Raw 是签名交易的原文
+	Raw []byte
+}
+
+// DecodeSignedTx 解析签名交易，并由签名恢复交易发起方
+// 支持RLP编码的传统交易，以及 EIP-2718 编码的 EIP-2930 (type 1)、EIP-1559 (type 2) 交易
+// 只会校验签名能否恢复出公钥，签名是否规范请使用 VerifySignedTx
+func DecodeSignedTx(raw []byte) (*SignedTx, error) {
+	if len(raw) == 0 {
+		return nil, errno.ParseTxError.Add("empty tx")
+	}
+
+	tx := &SignedTx{
+		Value: new(big.Int),
+		V:     new(big.Int),
+		R:     new(big.Int),
+		S:     new(big.Int),
+		Raw:   raw,
+	}
+	copy(tx.Hash[:], keccak256(raw))
+
+	payload := raw
+	if raw[0] < 0x7f {
+		tx.Type = raw[0]
+		payload = raw[1:]
+		if tx.Type != AccessListTxType && tx.Type != DynamicFeeTxType {
+			return nil, errno.ParseTxError.Add(fmt.Sprintf("unsupported tx type %d", tx.Type))
+		}
+	}
+
+	p := &fastrlp.Parser{}
+	v, err := p.Parse(payload)
+	if err != nil {
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+	elems, err := v.GetElems()
+	if err != nil {
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+
+	expected := map[uint8]int{LegacyTxType: 9, AccessListTxType: 11, DynamicFeeTxType: 12}[tx.Type]
+	if len(elems) != expected {
+		return nil, errno.ParseTxError.Add(fmt.Sprintf("expect %d fields in type %d tx, got %d", expected, tx.Type, len(elems)))
+	}
+
+	if tx.Type != LegacyTxType {
+		if tx.ChainID, err = elems[0].GetUint64(); err != nil {
+			return nil, errno.ParseTxError.Add("chainId: " + err.Error())
+		}
+		elems = elems[1:]
+	}
+	if tx.Nonce, err = elems[0].GetUint64(); err != nil {
+		return nil, errno.ParseTxError.Add("nonce: " + err.Error())
+	}
+	if tx.Type == DynamicFeeTxType {
+		if tx.GasTipCap, err = elems[1].GetUint64(); err != nil {
+			return nil, errno.ParseTxError.Add("maxPriorityFeePerGas: " + err.Error())
+		}
+		if tx.GasFeeCap, err = elems[2].GetUint64(); err != nil {
+			return nil, errno.ParseTxError.Add("maxFeePerGas: " + err.Error())
+		}
+		elems = elems[1:]
+	} else if tx.GasPrice, err = elems[1].GetUint64(); err != nil {
+		return nil, errno.ParseTxError.Add("gasPrice: " + err.Error())
+	}
+	if tx.Gas, err = elems[2].GetUint64(); err != nil {
+		return nil, errno.ParseTxError.Add("gas: " + err.Error())
+	}
+	if tx.To, err = decodeTo(elems[3]); err != nil {
+		return nil, err
+	}
+	if err := elems[4].GetBigInt(tx.Value); err != nil {
+		return nil, errno.ParseTxError.Add("value: " + err.Error())
+	}
+	if tx.Input, err = elems[5].GetBytes(nil); err != nil {
+		return nil, errno.ParseTxError.Add("input: " + err.Error())
+	}
+	if tx.Type != LegacyTxType {
+		if tx.AccessList, err = decodeAccessList(elems[6]); err != nil {
+			return nil, err
+		}
+		elems = elems[1:]
+	}
+	for i, n := range []*big.Int{tx.V, tx.R, tx.S} {
+		if err := elems[6+i].GetBigInt(n); err != nil {
+			return nil, errno.ParseTxError.Add("signature: " + err.Error())
+		}
+	}
+
+	var recID uint64
+	switch {
+	case tx.Type != LegacyTxType:
+		if !tx.V.IsUint64() || tx.V.Uint64() > 1 {
+			return nil, errno.InvalidSignature.Add(fmt.Sprintf("invalid y parity %s", tx.V))
+		}
+		recID = tx.V.Uint64()
+	case tx.V.IsUint64() && (tx.V.Uint64() == 27 || tx.V.Uint64() == 28):
+		recID = tx.V.Uint64() - 27
+	case tx.V.IsUint64() && tx.V.Uint64() >= 35:
+		tx.ChainID = (tx.V.Uint64() - 35) / 2
+		recID = tx.V.Uint64() - 35 - tx.ChainID*2
+	default:
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("invalid v %s", tx.V))
+	}
+
+	if tx.From, err = recoverTxSender(tx.Txn().signingHash(tx.ChainID), tx.R, tx.S, byte(recID)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Txn 将签名交易转换为 Txn, 可直接使用 Client.SendSignedTx 广播
+func (tx *SignedTx) Txn() *Txn {
+	return &Txn{
+		ChainID:    tx.ChainID,
+		Type:       tx.Type,
+		From:       tx.From,
+		Nonce:      tx.Nonce,
+		Addr:       tx.To,
+		Value:      tx.Value,
+		GasPrice:   tx.GasPrice,
+		GasTipCap:  tx.GasTipCap,
+		GasFeeCap:  tx.GasFeeCap,
+		GasLimit:   tx.Gas,
+		Data:       tx.Input,
+		AccessList: tx.AccessList,
+		SignedTx:   tx.Raw,
+		Hash:       tx.Hash,
+	}
+}
+
+// DecodeSignedTxHex 解析16进制格式 (可带0x前缀) 的签名交易
+func DecodeSignedTxHex(rawHex string) (*SignedTx, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(rawHex, "0x"))
+	if err != nil {
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+	return DecodeSignedTx(raw)
+}
+
+// RecoverSender 由RLP编码的签名交易恢复交易发起方地址
+func RecoverSender(raw []byte) (string, error) {
+	tx, err := DecodeSignedTx(raw)
+	if err != nil {
+		return "", err
+	}
+	return checksumAddress(tx.From), nil
+}
+
+// VerifySignedTx 校验签名交易
+// 签名必须是规范的 (low-S，R、S在曲线阶之内)，交易的链ID必须与 chainID 一致，否则返回 errno.ChainIDMismatch，
+// from 不为空时，恢复出的交易发起方必须与 from 一致
+func VerifySignedTx(raw []byte, chainID uint64, from string) (*SignedTx, error) {
+	tx, err := DecodeSignedTx(raw)
+	if err != nil {
+		return nil, err
+	}
+	if tx.R.Sign() <= 0 || tx.R.Cmp(secp256k1N) >= 0 || tx.S.Sign() <= 0 || tx.S.Cmp(secp256k1N) >= 0 {
+		return nil, errno.InvalidSignature.Add("signature values out of range")
+	}
+	if tx.S.Cmp(secp256k1HalfN) > 0 {
+		return nil, errno.InvalidSignature.Add("signature is not canonical (high s)")
+	}
+	if tx.ChainID != chainID {
+		return nil, errno.ChainIDMismatch.Add(fmt.Sprintf("signed for chain %d, expect chain %d", tx.ChainID, chainID))
+	}
+	if from != "" && tx.From != web3.HexToAddress(from) {
+		return nil, errno.InvalidSignature.Add(fmt.Sprintf("signed by %s, expect %s", tx.From, web3.HexToAddress(from)))
+	}
+	return tx, nil
+}
+
+// VerifySignature 校验 SignedTx 中的签名
+// 除 VerifySignedTx 的校验外，还会校验签名交易的内容与当前交易一致
+func (t *Txn) VerifySignature(chainID string) error {
+	if len(t.SignedTx) == 0 {
+		return errno.InvalidTx.Add("tx not signed")
+	}
+	ci, err := strconv.ParseUint(chainID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	var from string
+	if t.From != (web3.Address{}) {
+		from = t.From.String()
+	}
+	signed, err := VerifySignedTx(t.SignedTx, ci, from)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(signed.Txn().signingHash(ci), t.signingHash(ci)) {
+		return errno.InvalidSignature.Add("signed tx does not match tx fields")
+	}
+	return nil
+}
+
+func decodeTo(v *fastrlp.Value) (*web3.Address, error) {
+	b, err := v.GetBytes(nil)
+	if err != nil {
+		return nil, errno.ParseTxError.Add("to: " + err.Error())
+	}
+	switch len(b) {
+	case 0:
+		return nil, nil
+	case 20:
+		var addr web3.Address
+		copy(addr[:], b)
+		return &addr, nil
+	default:
+		return nil, errno.ParseTxError.Add(fmt.Sprintf("invalid to address length %d", len(b)))
+	}
+}
+
+func recoverTxSender(hash []byte, r, s *big.Int, recID byte) (web3.Address, error) {
+	if r.BitLen() > 256 || s.BitLen() > 256 || recID > 1 {
+		return web3.Address{}, errno.InvalidSignature.Add("signature values out of range")
+	}
+	sig := make([]byte, 65)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = recID
+	return recoverSigner(hash, sig)
+}
@@ -0,0 +1,178 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/testkit"
+	"math/big"
+	"testing"
+)
+
+// EIP-155 规范中的示例交易: 私钥 0x4646...46 在 chainID 1 上签名
+const (
+	testLegacyPrivate = "4646464646464646464646464646464646464646464646464646464646464646"
+	testLegacySender  = "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F"
+	testLegacyRawTx   = "0xf86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+)
+
+func TestDecodeSignedTx(t *testing.T) {
+	// 私钥 4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318 在 chainID 56 上签名的交易
+	cases := []struct {
+		txType uint8
+		raw    string
+	}{
+		{AccessListTxType, "01f89e38070382c350943f43e75aaba2c2fd6e227c10c6e7dc125a93de3c82303982deadf838f7943f43e75aaba2c2fd6e227c10c6e7dc125a93de3ce1a0000000000000000000000000000000000000000000000000000000000000000180a0066ba5abcdd793ae5a280da1c41a474d34f84a0d09c09dfe47fc409ab7f376ffa06937d58cdc641c155add912d5d2ab69cc79d3719b990a12ec7a1880f4cc1ae2e"},
+		{DynamicFeeTxType, "0x02f89f3807026482c350943f43e75aaba2c2fd6e227c10c6e7dc125a93de3c82303982deadf838f7943f43e75aaba2c2fd6e227c10c6e7dc125a93de3ce1a0000000000000000000000000000000000000000000000000000000000000000180a00ea45c39ef97c3442c73e0c0821aa36dffd842e6af1598209fcc27f79dcf1725a04ba18efbddded0b502195a66295d59aca9f4999f405abbdc0edca88862f0e981"},
+	}
+	signer, err := NewLocalSignerFromHex("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := &TxBuilder{}
+	for _, c := range cases {
+		decoded, err := builder.DecodeTx(c.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txn := decoded.(*Txn)
+		if txn.Type != c.txType {
+			t.Fatalf("expect type %d, got %d", c.txType, txn.Type)
+		}
+		if txn.From != signer.Address() {
+			t.Fatalf("unexpected sender %s", txn.From)
+		}
+		if err := txn.VerifySignature("56"); err != nil {
+			t.Fatal(err)
+		}
+		if err := txn.VerifySignature("1"); err == nil {
+			t.Fatal("expect chain id mismatch")
+		}
+
+		raw := txn.SignedTx
+		if err := txn.SignWithSigner(signer, "56"); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, txn.SignedTx) {
+			t.Fatalf("re-signed tx mismatch: %x", txn.SignedTx)
+		}
+	}
+}
+
+func TestDecodeLegacySignedTx(t *testing.T) {
+	decoded, err := (&TxBuilder{}).DecodeTx(testLegacyRawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := decoded.(*Txn)
+	if txn.Type != LegacyTxType {
+		t.Fatalf("expect legacy tx, got type %d", txn.Type)
+	}
+	if txn.Nonce != 9 || txn.GasPrice != 20000000000 || txn.GasLimit != 21000 {
+		t.Fatalf("unexpected nonce %d, gas price %d, gas limit %d", txn.Nonce, txn.GasPrice, txn.GasLimit)
+	}
+	if txn.Addr == nil || *txn.Addr != web3.HexToAddress("0x3535353535353535353535353535353535353535") {
+		t.Fatalf("unexpected to %v", txn.Addr)
+	}
+	if txn.Value.Cmp(big.NewInt(1000000000000000000)) != 0 {
+		t.Fatalf("unexpected value %s", txn.Value)
+	}
+	if txn.From != web3.HexToAddress(testLegacySender) {
+		t.Fatalf("unexpected sender %s", txn.From)
+	}
+	if txn.GetHash() != "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788" {
+		t.Fatalf("unexpected hash %s", txn.GetHash())
+	}
+
+	signer, err := NewLocalSignerFromHex(testLegacyPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := txn.SignedTx
+	if err := txn.SignWithSigner(signer, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, txn.SignedTx) {
+		t.Fatalf("re-signed tx mismatch: %x", txn.SignedTx)
+	}
+}
+
+// highSLegacyTx 返回与 EIP-155 示例交易等价、但使用 high-S 签名的交易，其签名者不变
+func highSLegacyTx(t *testing.T) []byte {
+	signed, err := DecodeSignedTxHex(testLegacyRawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := new(big.Int).Sub(signed.V, big.NewInt(35+2))
+	v.Xor(v, big.NewInt(1))
+	v.Add(v, big.NewInt(35+2))
+
+	tx := &web3.Transaction{
+		Nonce:    signed.Nonce,
+		GasPrice: signed.GasPrice,
+		Gas:      signed.Gas,
+		To:       signed.To,
+		Value:    signed.Value,
+		Input:    signed.Input,
+		V:        v.Bytes(),
+		R:        signed.R.Bytes(),
+		S:        new(big.Int).Sub(secp256k1N, signed.S).Bytes(),
+	}
+	return marshalTx(tx)
+}
+
+func TestSendSignedTxVerifiesSignature(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_chainId", "0x1").
+		Respond("eth_sendRawTransaction", "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788")
+
+	c, err := NewClient(server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func(raw string) *Txn {
+		decoded, err := (&TxBuilder{}).DecodeTx(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded.(*Txn)
+	}
+
+	hash, err := c.SendSignedTx(decode(testLegacyRawTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "0x33469b22e9f636356c4160a87eb19df52b7412e8eac32a4a55ffe88ea8350788" {
+		t.Fatalf("unexpected hash %s", hash)
+	}
+	server.AssertCalled(t, "eth_sendRawTransaction", 1)
+
+	// high-S 签名同样能恢复出正确的签名者，但会被节点拒绝
+	highS := highSLegacyTx(t)
+	if from, err := RecoverSender(highS); err != nil || web3.HexToAddress(from) != web3.HexToAddress(testLegacySender) {
+		t.Fatalf("unexpected high-s sender %s, %v", from, err)
+	}
+	_, err = c.SendSignedTx(decode(hex.EncodeToString(highS)))
+	if !errors.Is(err, errno.InvalidSignature) {
+		t.Fatalf("expect InvalidSignature for high-s tx, got %v", err)
+	}
+
+	mismatch := decode(testLegacyRawTx)
+	mismatch.From = web3.HexToAddress("0x3535353535353535353535353535353535353535")
+	_, err = c.SendSignedTx(mismatch)
+	if !errors.Is(err, errno.InvalidSignature) {
+		t.Fatalf("expect InvalidSignature for from mismatch, got %v", err)
+	}
+
+	server.Respond("eth_chainId", "0x38")
+	_, err = c.SendSignedTx(decode(testLegacyRawTx))
+	if !errors.Is(err, errno.ChainIDMismatch) {
+		t.Fatalf("expect ChainIDMismatch, got %v", err)
+	}
+
+	server.AssertCalled(t, "eth_sendRawTransaction", 1)
+}
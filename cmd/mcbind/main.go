@@ -0,0 +1,76 @@
+// mcbind 根据合约的 abi 或 Hardhat/Foundry 编译产物生成类型化的 Go 绑定代码
+//
+// 用法:
+//
+//	mcbind -abi artifacts/Token.json -pkg token -out token/token.go
+//	mcbind -abi Token.abi -bin Token.bin -type Token -pkg token
+//
+// 生成的代码基于 client.Client 查询合约、基于 txbuilder.ContractTxBuilder 构建交易
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mgintoki/multichain/bind"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		abiPath  = flag.String("abi", "", "abi 文件或 Hardhat/Foundry 编译产物的路径 (必填)")
+		binPath  = flag.String("bin", "", "部署字节码文件的路径，编译产物中已包含字节码时可不填")
+		typeName = flag.String("type", "", "生成的类型名，默认使用编译产物中的合约名或文件名")
+		pkg      = flag.String("pkg", "", "生成代码的包名 (必填)")
+		out      = flag.String("out", "", "输出文件路径，默认输出到标准输出")
+	)
+	flag.Parse()
+
+	if err := run(*abiPath, *binPath, *typeName, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "mcbind:", err)
+		os.Exit(1)
+	}
+}
+
+func run(abiPath, binPath, typeName, pkg, out string) error {
+	if abiPath == "" || pkg == "" {
+		flag.Usage()
+		return fmt.Errorf("-abi and -pkg are required")
+	}
+
+	data, err := ioutil.ReadFile(abiPath)
+	if err != nil {
+		return err
+	}
+	artifact, err := bind.ParseArtifact(data)
+	if err != nil {
+		return err
+	}
+
+	if binPath != "" {
+		bin, err := ioutil.ReadFile(binPath)
+		if err != nil {
+			return err
+		}
+		artifact.Bin = "0x" + strings.TrimPrefix(strings.TrimSpace(string(bin)), "0x")
+	}
+	if typeName != "" {
+		artifact.Name = typeName
+	}
+	if artifact.Name == "" {
+		base := filepath.Base(abiPath)
+		artifact.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	code, err := bind.Generate(pkg, artifact)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = fmt.Print(code)
+		return err
+	}
+	return ioutil.WriteFile(out, []byte(code), 0644)
+}
@@ -0,0 +1,100 @@
+// multichain-gateway 启动 HTTP/JSON 网关，将 multichain SDK 的多链功能提供给非 Go 服务使用，接口说明见 gateway 包
+//
+// 用法:
+//
+//	multichain-gateway -config gateway.json
+//
+// 配置文件:
+//
+//	{
+//	  "listen": ":8545",
+//	  "tokens": ["secret-token"],
+//	  "chains": [
+//	    {"name": "eth", "type": "ethereum", "provider": "https://..."},
+//	    {"name": "bsc", "type": "binance", "provider": "https://...", "privateKey": "..."}
+//	  ]
+//	}
+//
+// 配置了 tokens 时，请求需要带有 Authorization: Bearer <token> 请求头。
+// 为链配置了 privateKey 时网关可以使用私钥转账，此时必须配置 tokens，否则拒绝启动
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mgintoki/multichain/gateway"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+type config struct {
+	gateway.Config
+	Listen string   `json:"listen"`
+	Tokens []string `json:"tokens"`
+}
+
+func main() {
+	var (
+		configPath = flag.String("config", "", "json 配置文件路径 (必填)")
+		listen     = flag.String("listen", "", "监听地址，覆盖配置文件中的 listen，默认 :8080")
+	)
+	flag.Parse()
+
+	if err := run(*configPath, *listen); err != nil {
+		fmt.Fprintln(os.Stderr, "multichain-gateway:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, listen string) error {
+	if configPath == "" {
+		flag.Usage()
+		return fmt.Errorf("-config is required")
+	}
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("invalid config %s: %v", configPath, err)
+	}
+	if listen == "" {
+		listen = c.Listen
+	}
+	if listen == "" {
+		listen = ":8080"
+	}
+
+	var opts []gateway.Option
+	if len(c.Tokens) != 0 {
+		opts = append(opts, gateway.WithAuth(gateway.BearerAuth(c.Tokens...)))
+	} else {
+		for _, ch := range c.Chains {
+			if ch.PrivateKey != "" {
+				return fmt.Errorf("chain %s has a privateKey, tokens must be configured", ch.Name)
+			}
+		}
+		log.Println("warning: no tokens configured, the gateway accepts unauthenticated requests")
+	}
+	handler, err := gateway.New(c.Config, opts...)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// 查询交易时最多等待 gateway.DefaultMaxWait
+		WriteTimeout: gateway.DefaultMaxWait + 30*time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+	log.Printf("multichain gateway listening on %s with %d chains", listen, len(c.Chains))
+	return server.ListenAndServe()
+}
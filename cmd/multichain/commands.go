@@ -0,0 +1,676 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/bind"
+	"github.com/mgintoki/multichain/errno"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+// txResult 是发送交易的命令的输出，使用 -wait 时包含上链后的交易详情
+type txResult struct {
+	TxHash          string     `json:"txHash"`
+	ContractAddress string     `json:"contractAddress,omitempty"`
+	Tx              *tx.TxData `json:"tx,omitempty"`
+}
+
+// encodedTxResult 是 build 与 sign 命令的输出
+type encodedTxResult struct {
+	EncodedTx string `json:"encodedTx"`
+	ChainID   string `json:"chainId"`
+	Hash      string `json:"hash"` // build 输出待签名hash，sign 输出交易hash
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Nonce     uint64 `json:"nonce"`
+	Value     string `json:"value"`
+}
+
+// feeFlags 注册可选的交易费用参数
+type feeFlags struct {
+	gasLimit, gasPrice uint64
+	speed              string
+}
+
+func (f *feeFlags) register(fs *flag.FlagSet) {
+	fs.Uint64Var(&f.gasLimit, "gas-limit", 0, "可选，gas 上限，默认估算")
+	fs.Uint64Var(&f.gasPrice, "gas-price", 0, "可选，gas 价格 (wei)，默认使用节点推荐值")
+	fs.StringVar(&f.speed, "speed", "", "可选，未指定 -gas-price 时按照档位计算 gas 价格: slow、standard 或 fast")
+}
+
+func (f *feeFlags) option() *fee.OptionFee {
+	if f.gasLimit == 0 && f.gasPrice == 0 && f.speed == "" {
+		return nil
+	}
+	return &fee.OptionFee{GasLimit: f.gasLimit, GasPrice: f.gasPrice, Speed: fee.Speed(f.speed)}
+}
+
+// contractFlags 注册合约方法与参数
+type contractFlags struct {
+	abi, method, args string
+}
+
+func (c *contractFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.abi, "abi", "", "json 或 human-readable 格式的 abi，@path 从文件读取 (支持 Hardhat/Foundry 编译产物)")
+	fs.StringVar(&c.method, "method", "", "方法名、方法签名或方法定义")
+	fs.StringVar(&c.args, "args", "", "json 数组格式的方法参数，如 '[\"0x...\", \"1000\"]'，@path 从文件读取")
+}
+
+// readValue 读取参数值，@path 格式的参数从文件读取
+func readValue(s string) (string, error) {
+	if !strings.HasPrefix(s, "@") {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(s[1:])
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// loadABI 读取 abi，文件为编译产物时同时返回其中的字节码
+func loadABI(s string) (abiStr, bin string, err error) {
+	if s == "" {
+		return "", "", fmt.Errorf("-abi is required")
+	}
+	abiStr, err = readValue(s)
+	if err != nil {
+		return "", "", err
+	}
+	if strings.HasPrefix(s, "@") {
+		if artifact, err := bind.ParseArtifact([]byte(abiStr)); err == nil {
+			return artifact.ABI, artifact.Bin, nil
+		}
+	}
+	return abiStr, "", nil
+}
+
+// parseArgs 解析 json 数组格式的参数，数字保留为 json.Number 以免丢失精度
+func parseArgs(s string) ([]interface{}, error) {
+	s, err := readValue(s)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var args []interface{}
+	if err := dec.Decode(&args); err != nil {
+		return nil, errno.InvalidContractArg.Add(fmt.Sprintf("-args must be a json array: %v", err))
+	}
+	return args, nil
+}
+
+// parseAmount 解析10进制或0x开头的16进制数量，空字符串返回nil
+func parseAmount(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok || n.Sign() < 0 {
+		return nil, errno.InvalidStringToBigNum.Add(s)
+	}
+	return n, nil
+}
+
+func parseData(s string) ([]byte, error) {
+	s, err := readValue(s)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// required 检查必填参数，pairs 为参数名与参数值交替排列
+func required(pairs ...string) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			return fmt.Errorf("-%s is required", pairs[i])
+		}
+	}
+	return nil
+}
+
+// sendResult 返回交易hash，wait 为 true 时等待交易上链
+func sendResult(cli client.Client, txHash string, wait bool) (*txResult, error) {
+	res := &txResult{TxHash: txHash}
+	if !wait {
+		return res, nil
+	}
+	txData, err := cli.QueryTx(txHash, true)
+	if err != nil {
+		return nil, err
+	}
+	res.Tx = txData
+	res.ContractAddress = txData.ContractAddress
+	return res, nil
+}
+
+func runBalance(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	address := fs.String("address", "", "查询的账户地址，默认为私钥对应的地址")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	cli, err := e.client(*address == "")
+	if err != nil {
+		return nil, err
+	}
+	if *address == "" {
+		*address = cli.GetAccount()
+	}
+	balance, err := cli.BalanceOf(*address, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": *address, "balance": balance.String()}, nil
+}
+
+func runTransfer(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var f feeFlags
+	f.register(fs)
+	to := fs.String("to", "", "收款地址")
+	amount := fs.String("amount", "", "转账数量 (wei)")
+	wait := fs.Bool("wait", false, "等待交易上链")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	if err := required("to", *to, "amount", *amount); err != nil {
+		return nil, err
+	}
+	value, err := parseAmount(*amount)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(true)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := cli.Transfer(*to, value, nil, f.option())
+	if err != nil {
+		return nil, err
+	}
+	return sendResult(cli, txHash, *wait)
+}
+
+func runTx(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	hash := fs.String("hash", "", "交易hash")
+	wait := fs.Bool("wait", false, "等待交易上链后返回")
+	abiStr := fs.String("abi", "", "可选，用于解析交易日志的 abi，@path 从文件读取")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	if err := required("hash", *hash); err != nil {
+		return nil, err
+	}
+	cli, err := e.client(false)
+	if err != nil {
+		return nil, err
+	}
+	if *abiStr == "" {
+		return cli.QueryTx(*hash, *wait)
+	}
+	contractAbi, _, err := loadABI(*abiStr)
+	if err != nil {
+		return nil, err
+	}
+	q, ok := cli.(interface {
+		QueryTxWithAbi(txHash string, isWait bool, abiStr string) (*tx.TxData, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("decoding logs with -abi is not supported by chain %s", e.chain)
+	}
+	return q.QueryTxWithAbi(*hash, *wait, contractAbi)
+}
+
+func runCall(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var c contractFlags
+	c.register(fs)
+	contract := fs.String("contract", "", "合约地址")
+	from := fs.String("from", "", "可选，调用发起地址")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	if err := required("contract", *contract, "method", c.method); err != nil {
+		return nil, err
+	}
+	abiStr, _, err := loadABI(c.abi)
+	if err != nil {
+		return nil, err
+	}
+	params, err := parseArgs(c.args)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(false)
+	if err != nil {
+		return nil, err
+	}
+	res, err := cli.QueryContract(client.CallContractParam{
+		From:            *from,
+		ContractAddress: *contract,
+		Abi:             abiStr,
+		CalledFunc:      c.method,
+		Params:          params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": res.RawRes, "decoded": res.DecodeRes}, nil
+}
+
+func runSend(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var c contractFlags
+	c.register(fs)
+	var f feeFlags
+	f.register(fs)
+	contract := fs.String("contract", "", "合约地址")
+	value := fs.String("value", "", "可选，随交易发送的原生币数量 (wei)")
+	nonce := fs.Uint64("nonce", 0, "可选，交易的 nonce，默认使用账户当前的 nonce")
+	wait := fs.Bool("wait", false, "等待交易上链")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	if err := required("contract", *contract, "method", c.method); err != nil {
+		return nil, err
+	}
+	abiStr, _, err := loadABI(c.abi)
+	if err != nil {
+		return nil, err
+	}
+	params, err := parseArgs(c.args)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseAmount(*value)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(true)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := e.contractTxBuilder()
+	if err != nil {
+		return nil, err
+	}
+	txn, err := builder.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            cli.GetAccount(),
+		Abi:             abiStr,
+		Method:          c.method,
+		Params:          params,
+		Nonce:           *nonce,
+		Value:           amount,
+		ContractAddress: *contract,
+		GasLimit:        f.gasLimit,
+		GasPrice:        f.gasPrice,
+		Speed:           fee.Speed(f.speed),
+	})
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := cli.SendTx(txn, f.option())
+	if err != nil {
+		return nil, err
+	}
+	return sendResult(cli, txHash, *wait)
+}
+
+func runDeploy(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var f feeFlags
+	f.register(fs)
+	abiFlag := fs.String("abi", "", "json 或 human-readable 格式的 abi，@path 从文件读取 (支持 Hardhat/Foundry 编译产物)")
+	binFlag := fs.String("bin", "", "16进制的部署字节码，@path 从文件读取，-abi 为编译产物时可不填")
+	argsFlag := fs.String("args", "", "json 数组格式的构造函数参数，@path 从文件读取")
+	value := fs.String("value", "", "可选，随交易发送的原生币数量 (wei)")
+	nonce := fs.Uint64("nonce", 0, "可选，交易的 nonce，默认使用账户当前的 nonce")
+	wait := fs.Bool("wait", false, "等待交易上链")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	abiStr, bin, err := loadABI(*abiFlag)
+	if err != nil {
+		return nil, err
+	}
+	if *binFlag != "" {
+		if bin, err = readValue(*binFlag); err != nil {
+			return nil, err
+		}
+	}
+	if bin == "" {
+		return nil, fmt.Errorf("-bin is required")
+	}
+	params, err := parseArgs(*argsFlag)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := parseAmount(*value)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(true)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := e.contractTxBuilder()
+	if err != nil {
+		return nil, err
+	}
+	txn, err := builder.BuildDeployTx(txbuilder.BuildDeployTxReq{
+		From:     cli.GetAccount(),
+		Abi:      abiStr,
+		ByteCode: bin,
+		Params:   params,
+		Nonce:    *nonce,
+		Value:    amount,
+		GasLimit: f.gasLimit,
+		GasPrice: f.gasPrice,
+		Speed:    fee.Speed(f.speed),
+	})
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := cli.SendTx(txn, f.option())
+	if err != nil {
+		return nil, err
+	}
+	res, err := sendResult(cli, txHash, *wait)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := txn.(interface{ ContractAddress() string }); ok && res.ContractAddress == "" {
+		res.ContractAddress = c.ContractAddress()
+	}
+	return res, nil
+}
+
+// txFlags 注册构建普通交易或合约调用交易的参数
+type txFlags struct {
+	contractFlags
+	from, to, value, data string
+}
+
+func (t *txFlags) register(fs *flag.FlagSet) {
+	t.contractFlags.register(fs)
+	fs.StringVar(&t.from, "from", "", "交易发起地址，默认为私钥对应的地址")
+	fs.StringVar(&t.to, "to", "", "交易目标地址或合约地址")
+	fs.StringVar(&t.value, "value", "", "可选，原生币数量 (wei)")
+	fs.StringVar(&t.data, "data", "", "可选，16进制的交易数据，与 -method 二选一")
+}
+
+// param 将参数转换为 BuildTxParam，指定 -method 时按 abi 编码合约调用数据
+func (t *txFlags) param(e *env) (txbuilder.BuildTxParam, error) {
+	var req txbuilder.BuildTxParam
+	if t.from == "" && e.privateKey != "" {
+		signer, err := e.client(true)
+		if err != nil {
+			return req, err
+		}
+		t.from = signer.GetAccount()
+	}
+	if err := required("from", t.from); err != nil {
+		return req, err
+	}
+	value, err := parseAmount(t.value)
+	if err != nil {
+		return req, err
+	}
+	req = txbuilder.BuildTxParam{From: t.from, To: t.to, Value: value}
+
+	if t.method == "" {
+		req.Payload, err = parseData(t.data)
+		return req, err
+	}
+	if t.data != "" {
+		return req, fmt.Errorf("-data and -method are mutually exclusive")
+	}
+	abiStr, _, err := loadABI(t.abi)
+	if err != nil {
+		return req, err
+	}
+	params, err := parseArgs(t.args)
+	if err != nil {
+		return req, err
+	}
+	builder, err := e.contractTxBuilder()
+	if err != nil {
+		return req, err
+	}
+	// 借助 ContractTxBuilder 编码调用数据，与 send 命令的编码保持一致，这里只使用交易的 payload，
+	// 指定 nonce 与 gas 避免 BuildInvokeTx 访问节点
+	invoke, err := builder.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            t.from,
+		Abi:             abiStr,
+		Method:          t.method,
+		Params:          params,
+		ContractAddress: t.to,
+		Value:           value,
+		GasLimit:        1,
+		GasPrice:        1,
+		Nonce:           1,
+	})
+	if err != nil {
+		return req, err
+	}
+	req.Payload = invoke.GetPayload()
+	return req, nil
+}
+
+func runEstimate(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var t txFlags
+	t.register(fs)
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	req, err := t.param(e)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := e.txBuilder(false)
+	if err != nil {
+		return nil, err
+	}
+	// 指定 gas 使 BuildTx 跳过估算，估算统一由 Client.EstimateGas 完成
+	req.GasLimit, req.GasPrice, req.Nonce = 1, 1, 1
+	txn, err := builder.BuildTx(req)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(false)
+	if err != nil {
+		return nil, err
+	}
+	f, err := cli.EstimateGas(txn)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]uint64{"gasLimit": f.GasLimit, "gasPrice": f.GasPrice}, nil
+}
+
+// feeEstimate 是 fees 命令中一个档位的推荐费用
+type feeEstimate struct {
+	GasPrice             uint64  `json:"gasPrice"`
+	MaxFeePerGas         uint64  `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas uint64  `json:"maxPriorityFeePerGas"`
+	WaitSeconds          float64 `json:"waitSeconds"`
+}
+
+func runFees(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	cli, err := e.client(false)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := cli.(interface {
+		SuggestFee() (*fee.Recommendation, error)
+	})
+	if !ok {
+		return nil, errno.NotSupportChainType.Add("fee oracle")
+	}
+	r, err := o.SuggestFee()
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{"baseFee": r.BaseFee, "blockSeconds": r.BlockTime.Seconds()}
+	for _, est := range []fee.Estimate{r.Slow, r.Standard, r.Fast} {
+		res[string(est.Speed)] = feeEstimate{
+			GasPrice:             est.GasPrice,
+			MaxFeePerGas:         est.MaxFeePerGas,
+			MaxPriorityFeePerGas: est.MaxPriorityFeePerGas,
+			WaitSeconds:          est.WaitTime.Seconds(),
+		}
+	}
+	return res, nil
+}
+
+func encodedResult(t tx.Tx, chainID, hash string) (*encodedTxResult, error) {
+	encoded, err := t.EncodeTx()
+	if err != nil {
+		return nil, err
+	}
+	res := &encodedTxResult{
+		EncodedTx: encoded,
+		ChainID:   chainID,
+		Hash:      hash,
+		From:      t.GetFrom(),
+		To:        t.GetTo(),
+		Nonce:     t.GetNonce(),
+		Value:     "0",
+	}
+	if t.GetValue() != nil {
+		res.Value = t.GetValue().String()
+	}
+	return res, nil
+}
+
+func runBuild(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	var t txFlags
+	t.register(fs)
+	var f feeFlags
+	f.register(fs)
+	nonce := fs.Uint64("nonce", 0, "可选，交易的 nonce，默认使用账户当前的 nonce")
+	chainID := fs.String("chain-id", "", "可选，计算待签名hash使用的链ID，默认从节点获取")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	req, err := t.param(e)
+	if err != nil {
+		return nil, err
+	}
+	req.Nonce, req.GasLimit, req.GasPrice, req.Speed = *nonce, f.gasLimit, f.gasPrice, fee.Speed(f.speed)
+
+	builder, err := e.txBuilder(false)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := builder.BuildTx(req)
+	if err != nil {
+		return nil, err
+	}
+	if *chainID == "" {
+		cli, err := e.client(false)
+		if err != nil {
+			return nil, err
+		}
+		if *chainID, err = cli.GetChainID(); err != nil {
+			return nil, err
+		}
+	}
+	hash, err := txn.GetTxHash(*chainID)
+	if err != nil {
+		return nil, err
+	}
+	return encodedResult(txn, *chainID, "0x"+hash)
+}
+
+func runSign(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	encoded := fs.String("tx", "", "build 输出的 encodedTx，@path 从文件读取 (可以是 build 的 json 输出)")
+	chainID := fs.String("chain-id", "", "链ID，使用 build 的 json 输出时可不填")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	txn, txChainID, err := e.decodeTx(*encoded, true)
+	if err != nil {
+		return nil, err
+	}
+	if *chainID == "" {
+		*chainID = txChainID
+	}
+	if err := required("chain-id", *chainID); err != nil {
+		return nil, err
+	}
+	if e.privateKey == "" {
+		return nil, errno.PrivateNotSet
+	}
+	if err := txn.SignTx(e.privateKey, *chainID); err != nil {
+		return nil, err
+	}
+	return encodedResult(txn, *chainID, txn.GetHash())
+}
+
+func runBroadcast(e *env, args []string) (interface{}, error) {
+	fs := e.flags()
+	encoded := fs.String("tx", "", "sign 输出的 encodedTx 或16进制的签名交易原文，@path 从文件读取 (可以是 sign 的 json 输出)")
+	wait := fs.Bool("wait", false, "等待交易上链")
+	if err := e.parse(fs, args); err != nil {
+		return nil, err
+	}
+	txn, _, err := e.decodeTx(*encoded, false)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := e.client(false)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := cli.SendSignedTx(txn)
+	if err != nil {
+		return nil, err
+	}
+	return sendResult(cli, txHash, *wait)
+}
+
+// decodeTx 解析 -tx 参数，参数可以是序列化的交易，也可以是 build 或 sign 的 json 输出
+func (e *env) decodeTx(s string, offline bool) (tx.Tx, string, error) {
+	if err := required("tx", s); err != nil {
+		return nil, "", err
+	}
+	s, err := readValue(s)
+	if err != nil {
+		return nil, "", err
+	}
+	var chainID string
+	if strings.HasPrefix(s, "{") {
+		var res encodedTxResult
+		dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+		if err := dec.Decode(&res); err != nil {
+			return nil, "", errno.ParseTxError.Add(err.Error())
+		}
+		s, chainID = res.EncodedTx, res.ChainID
+	}
+	builder, err := e.txBuilder(offline)
+	if err != nil {
+		return nil, "", err
+	}
+	txn, err := builder.DecodeTx(s)
+	return txn, chainID, err
+}
@@ -0,0 +1,252 @@
+// multichain 是基于 multichain SDK 的命令行工具，用于查询余额与交易、转账、调用与部署合约以及离线签名
+//
+// 用法:
+//
+//	multichain <command> [flags]
+//
+//	multichain balance -chain bsc -provider https://bsc-dataseed.binance.org -address 0x...
+//	multichain transfer -config prod.json -to 0x... -amount 1000000000000000000
+//	multichain tx -hash 0x... -wait
+//	multichain call -contract 0x... -abi 'function balanceOf(address) view returns (uint256)' -method balanceOf -args '["0x..."]'
+//	multichain build -from 0x... -to 0x... -value 1 > unsigned.txt
+//	multichain sign -tx @unsigned.txt -chain-id 56 > signed.txt        (离线机器)
+//	multichain broadcast -tx @signed.txt
+//
+// 链与节点地址可以通过 -chain、-provider 指定，也可以写在 -config 指定的 json 配置文件中:
+//
+//	{"chain": "ethereum", "provider": "https://...", "privateKey": "...", "minGasPrice": 0, "maxGasPrice": 0}
+//
+// minGasPrice 与 maxGasPrice 可选，是自动计算的 gas 价格的上下限 (wei)，用于 BSC、OKC 等有最低 gas 价格限制的链
+//
+// 命令行参数优先于配置文件。私钥也可以通过环境变量 MULTICHAIN_PRIVATE_KEY 设置，配置文件路径可以通过 MULTICHAIN_CONFIG 设置
+//
+// 命令的结果以 json 格式输出到标准输出，出错时将 {"error": "...", "state": 0} 输出到标准错误并以状态码 1 退出
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mgintoki/multichain"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/errno"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command 是一个子命令，run 返回的结果会以 json 格式输出
+type command struct {
+	usage string
+	run   func(e *env, args []string) (interface{}, error)
+}
+
+var commands = map[string]command{
+	"balance":   {"查询账户的原生币余额", runBalance},
+	"transfer":  {"原生币转账", runTransfer},
+	"tx":        {"查询交易状态与详情", runTx},
+	"call":      {"查询合约", runCall},
+	"send":      {"发送调用合约的交易", runSend},
+	"deploy":    {"部署合约", runDeploy},
+	"estimate":  {"估算交易的 gas", runEstimate},
+	"fees":      {"查询 slow、standard、fast 三个档位的推荐费用", runFees},
+	"build":     {"构建未签名的交易，输出序列化的交易与待签名hash", runBuild},
+	"sign":      {"离线签名序列化的交易", runSign},
+	"broadcast": {"广播已签名的交易", runBroadcast},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回进程的退出状态码
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "multichain: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	e := &env{name: args[0], stderr: stderr}
+	res, err := cmd.run(e, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		out := map[string]interface{}{"error": err.Error()}
+		var en *errno.Errno
+		if errors.As(err, &en) {
+			out["state"] = en.State
+		}
+		writeJSON(stderr, out)
+		return 1
+	}
+	if err := writeJSON(stdout, res); err != nil {
+		fmt.Fprintln(stderr, "multichain:", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: multichain <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nrun 'multichain <command> -h' for the flags of a command")
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// config 是配置文件的内容
+type config struct {
+	Chain       string `json:"chain"`
+	Provider    string `json:"provider"`
+	PrivateKey  string `json:"privateKey"`
+	MinGasPrice uint64 `json:"minGasPrice"`
+	MaxGasPrice uint64 `json:"maxGasPrice"`
+}
+
+// env 保存所有命令共用的参数
+type env struct {
+	name   string
+	stderr io.Writer
+
+	configPath string
+	chain      string
+	provider   string
+	privateKey string
+	feeCaps    fee.Caps
+}
+
+// flags 创建子命令的参数集合，并注册共用的参数
+func (e *env) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("multichain "+e.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&e.configPath, "config", os.Getenv("MULTICHAIN_CONFIG"), "json 配置文件路径")
+	fs.StringVar(&e.chain, "chain", "", "链类型: ethereum (eth)、binance (bsc) 或 okex (okc)，默认 ethereum")
+	fs.StringVar(&e.provider, "provider", "", "节点地址")
+	fs.StringVar(&e.privateKey, "key", "", "16进制私钥，建议使用配置文件或环境变量 MULTICHAIN_PRIVATE_KEY")
+	return fs
+}
+
+// parse 解析命令行参数并合并配置文件与环境变量
+func (e *env) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	if e.configPath != "" {
+		data, err := ioutil.ReadFile(e.configPath)
+		if err != nil {
+			return err
+		}
+		var c config
+		if err := json.Unmarshal(data, &c); err != nil {
+			return fmt.Errorf("invalid config %s: %v", e.configPath, err)
+		}
+		if e.chain == "" {
+			e.chain = c.Chain
+		}
+		if e.provider == "" {
+			e.provider = c.Provider
+		}
+		if e.privateKey == "" {
+			e.privateKey = c.PrivateKey
+		}
+		e.feeCaps = fee.Caps{Min: c.MinGasPrice, Max: c.MaxGasPrice}
+	}
+	if e.privateKey == "" {
+		e.privateKey = os.Getenv("MULTICHAIN_PRIVATE_KEY")
+	}
+	e.privateKey = strings.TrimPrefix(e.privateKey, "0x")
+	return nil
+}
+
+// chainType 将链名称转换为 multichain 中的链类型，默认为 ethereum
+func (e *env) chainType() (uint, error) {
+	if e.chain == "" {
+		return multichain.TypeEthereum, nil
+	}
+	return multichain.ParseChainType(e.chain)
+}
+
+func (e *env) commonProvider() (provider.CommonProvider, error) {
+	if e.provider == "" {
+		return provider.CommonProvider{}, errno.ProviderNotSet.Add("use -provider or the provider field of -config")
+	}
+	return provider.CommonProvider{ProviderUrl: e.provider, FeeCaps: e.feeCaps}, nil
+}
+
+// client 创建 Client，needKey 为 true 时要求设置私钥
+func (e *env) client(needKey bool) (client.Client, error) {
+	chainType, err := e.chainType()
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.commonProvider()
+	if err != nil {
+		return nil, err
+	}
+	cli, err := multichain.NewClient(chainType, p)
+	if err != nil {
+		return nil, err
+	}
+	if e.privateKey != "" {
+		if err := cli.SetPrivate(e.privateKey); err != nil {
+			return nil, err
+		}
+	} else if needKey {
+		return nil, errno.PrivateNotSet
+	}
+	return cli, nil
+}
+
+// txBuilder 创建 TxBuilder，offline 为 true 时不需要节点地址
+func (e *env) txBuilder(offline bool) (txbuilder.TxBuilder, error) {
+	chainType, err := e.chainType()
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.commonProvider()
+	if err != nil && !offline {
+		return nil, err
+	}
+	return multichain.NewTxBuilder(chainType, p)
+}
+
+func (e *env) contractTxBuilder() (txbuilder.ContractTxBuilder, error) {
+	chainType, err := e.chainType()
+	if err != nil {
+		return nil, err
+	}
+	p, err := e.commonProvider()
+	if err != nil {
+		return nil, err
+	}
+	return multichain.NewContractTxBuilder(chainType, p)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mgintoki/multichain/chain/ethereum/simulated"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func exec(t *testing.T, out interface{}, args ...string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if code := run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("%v exit %d: %s", args, code, stderr.String())
+	}
+	if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+		t.Fatalf("%v: invalid json output %q: %v", args, stdout.String(), err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	accounts := backend.Accounts()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	config := `{"chain": "bsc", "provider": "` + backend.URL() + `", "privateKey": "` + accounts[0].PrivateKey + `"}`
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var balance map[string]string
+	exec(t, &balance, "balance", "-config", configPath)
+	if !strings.EqualFold(balance["address"], accounts[0].Address) || balance["balance"] != simulated.DefaultBalance.String() {
+		t.Fatalf("unexpected balance %v", balance)
+	}
+
+	var transfer txResult
+	exec(t, &transfer, "transfer", "-config", configPath, "-to", accounts[1].Address, "-amount", "0x10", "-wait")
+	if transfer.Tx == nil || transfer.Tx.Value.Int64() != 16 || transfer.Tx.TxHash != transfer.TxHash {
+		t.Fatalf("unexpected transfer %+v", transfer)
+	}
+
+	// build 与 broadcast 在线执行，sign 不需要节点
+	var built encodedTxResult
+	exec(t, &built, "build", "-chain", "bsc", "-provider", backend.URL(), "-from", accounts[0].Address, "-to", accounts[1].Address, "-value", "1")
+	if built.ChainID != "1337" || built.Nonce != 1 || built.Value != "1" {
+		t.Fatalf("unexpected built tx %+v", built)
+	}
+	builtPath := filepath.Join(dir, "built.json")
+	data, _ := json.Marshal(built)
+	if err := ioutil.WriteFile(builtPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var signed encodedTxResult
+	exec(t, &signed, "sign", "-chain", "bsc", "-key", accounts[0].PrivateKey, "-tx", "@"+builtPath)
+	if signed.Hash == "" || signed.EncodedTx == built.EncodedTx {
+		t.Fatalf("unexpected signed tx %+v", signed)
+	}
+
+	var broadcast txResult
+	exec(t, &broadcast, "broadcast", "-chain", "bsc", "-provider", backend.URL(), "-tx", signed.EncodedTx)
+	if broadcast.TxHash != signed.Hash {
+		t.Fatalf("expect tx hash %s, got %s", signed.Hash, broadcast.TxHash)
+	}
+
+	var estimate map[string]uint64
+	exec(t, &estimate, "estimate", "-config", configPath, "-to", accounts[1].Address, "-value", "1")
+	if estimate["gasLimit"] != 21000 || estimate["gasPrice"] == 0 {
+		t.Fatalf("unexpected estimate %v", estimate)
+	}
+
+	// 模拟链启用了 London 升级，推荐费用由 eth_feeHistory 统计
+	var fees map[string]interface{}
+	exec(t, &fees, "fees", "-config", configPath)
+	fast, ok := fees["fast"].(map[string]interface{})
+	if !ok || fast["gasPrice"] == float64(0) || fees["baseFee"] == float64(0) || fast["maxFeePerGas"] == float64(0) {
+		t.Fatalf("unexpected fees %v", fees)
+	}
+}
+
+func TestErrorOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"transfer", "-provider", "http://127.0.0.1:1", "-to", "0x0", "-amount", "1"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expect exit 1, got %d", code)
+	}
+	var out struct {
+		Error string `json:"error"`
+		State int    `json:"state"`
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.State != 20005 || stdout.Len() != 0 {
+		t.Fatalf("unexpected error output %s", stderr.String())
+	}
+	if code := run([]string{"unknown"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expect exit 2, got %d", code)
+	}
+}
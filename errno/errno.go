@@ -15,6 +15,36 @@ func (e Errno) Add(s string) *Errno {
 	return &e
 }
 
+// Wrap 返回以 err 为原因的错误，错误信息会加上 err 的信息
+func (e Errno) Wrap(err error) *WrappedErrno {
+	return &WrappedErrno{Errno: e.Add(err.Error()), Err: err}
+}
+
+// Is 使 errors.Is 按错误码比较，经过 Add 或 Wrap 的错误与原错误相等，如 errors.Is(err, errno.NonceTooLow)
+func (e *Errno) Is(target error) bool {
+	t, ok := target.(*Errno)
+	return ok && t.State == e.State
+}
+
+// WrappedErrno 是带有原始错误的 Errno，由 Errno.Wrap 创建
+// errors.As 可以从中取得 *Errno，errors.Unwrap 返回原始错误
+type WrappedErrno struct {
+	*Errno
+	Err error
+}
+
+func (e *WrappedErrno) Unwrap() error {
+	return e.Err
+}
+
+func (e *WrappedErrno) As(target interface{}) bool {
+	if t, ok := target.(**Errno); ok {
+		*t = e.Errno
+		return true
+	}
+	return false
+}
+
 type ResponseErrno struct {
 	State      int    `json:"state"`
 	Msg        string `json:"msg"`
@@ -43,7 +73,39 @@ var (
 	InvalidTxType         = &Errno{10003, "Invalid Tx type"}
 	InvalidTypeAssert     = &Errno{20001, "Invalid type asset"}
 	InvalidStringToBigNum = &Errno{20002, "Invalid string for big number"}
-	TxFromNotSet          = &Errno{20002, "From of tx not set"}
 	ProviderNotSet        = &Errno{20003, "Not set provider"}
 	ParseTxError          = &Errno{20004, "Parse tx error"}
+	PrivateNotSet         = &Errno{20005, "Private key or signer not set"}
+	InvalidKeystore       = &Errno{20006, "Invalid keystore"}
+	InvalidMnemonic       = &Errno{20007, "Invalid mnemonic"}
+	InvalidDerivationPath = &Errno{20008, "Invalid derivation path"}
+	HDWalletPublicOnly    = &Errno{20009, "HD wallet can only derive public keys"}
+	InvalidSignature      = &Errno{20010, "Invalid signature"}
+	SignerNotSupport      = &Errno{20011, "Operation not supported by signer"}
+	InvalidTypedData      = &Errno{20012, "Invalid typed data"}
+	UnsupportedTxEnvelope = &Errno{20013, "Unsupported tx envelope"}
+	InvalidOfflineBundle  = &Errno{20014, "Invalid offline signing bundle"}
+	InvalidFragment       = &Errno{20015, "Invalid fragment"}
+	UserOpReceiptTimeout  = &Errno{20016, "Timeout waiting for user operation receipt"}
+	InvalidContractArg    = &Errno{20017, "Invalid contract argument"}
+	InvalidABI            = &Errno{20018, "Invalid abi"}
+	MethodNotFound        = &Errno{20019, "Contract method not found"}
+	AmbiguousMethod       = &Errno{20020, "Ambiguous overloaded contract method"}
+	FactoryNotDeployed    = &Errno{20021, "Create2 factory not deployed"}
+	AlreadyDeployed       = &Errno{20022, "Contract already deployed"}
+	InvalidRequest        = &Errno{20023, "Invalid request"}
+	Unauthorized          = &Errno{20024, "Unauthorized"}
+	InvalidFeeSpeed       = &Errno{20025, "Invalid fee speed"}
+	TxFromNotSet          = &Errno{20026, "From of tx not set"}
+
+	// 节点返回的错误，由各链的实现将节点的错误信息转换为以下错误，原始错误可以通过 errors.Unwrap 取得
+	NonceTooLow            = &Errno{30001, "Nonce too low"}
+	ReplacementUnderpriced = &Errno{30002, "Replacement transaction underpriced"}
+	InsufficientFunds      = &Errno{30003, "Insufficient funds"}
+	GasTooLow              = &Errno{30004, "Gas too low"}
+	ExceedsBlockGasLimit   = &Errno{30005, "Exceeds block gas limit"}
+	ExecutionReverted      = &Errno{30006, "Execution reverted"}
+	RateLimited            = &Errno{30007, "Rate limited"}
+	ChainIDMismatch        = &Errno{30008, "Chain ID mismatch"}
+	NotFound               = &Errno{30009, "Not found"}
 )
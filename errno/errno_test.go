@@ -0,0 +1,67 @@
+package errno
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// TestDistinctStates 检查每个导出的 Errno 错误码都不相同，errors.Is 只比较错误码
+func TestDistinctStates(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "errno.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]string{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if !name.IsExported() || i >= len(vs.Values) {
+					continue
+				}
+				unary, ok := vs.Values[i].(*ast.UnaryExpr)
+				if !ok {
+					continue
+				}
+				lit, ok := unary.X.(*ast.CompositeLit)
+				if !ok || len(lit.Elts) == 0 {
+					continue
+				}
+				if typ, ok := lit.Type.(*ast.Ident); !ok || typ.Name != "Errno" {
+					continue
+				}
+				state, ok := lit.Elts[0].(*ast.BasicLit)
+				if !ok {
+					t.Fatalf("%s: state is not a literal", name.Name)
+				}
+				if other, ok := seen[state.Value]; ok {
+					t.Fatalf("%s and %s share state %s", other, name.Name, state.Value)
+				}
+				seen[state.Value] = name.Name
+			}
+		}
+	}
+	if len(seen) == 0 {
+		t.Fatal("no errno found")
+	}
+}
+
+func TestIs(t *testing.T) {
+	if !errors.Is(TxFromNotSet.Add("tx 1"), TxFromNotSet) {
+		t.Fatal("expect added errno to match")
+	}
+	if errors.Is(TxFromNotSet, InvalidStringToBigNum) {
+		t.Fatal("expect TxFromNotSet not to match InvalidStringToBigNum")
+	}
+	if !errors.Is(NonceTooLow.Wrap(errors.New("nonce too low")), NonceTooLow) {
+		t.Fatal("expect wrapped errno to match")
+	}
+}
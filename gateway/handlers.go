@@ -0,0 +1,602 @@
+package gateway
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/common"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBodySize 是请求体的大小上限，部署合约的字节码通常小于 100KB
+const maxBodySize = 1 << 20
+
+var (
+	// 目前支持的链均为 EVM 链，地址与hash使用相同的格式
+	addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	hashPattern    = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+)
+
+// decodeBody 解析 json 请求体，不允许未定义的字段，数字保留为 json.Number 以免丢失精度
+func decodeBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return errno.InvalidRequest.Add("invalid json body: " + err.Error())
+	}
+	return nil
+}
+
+func validateAddress(field, addr string, required bool) error {
+	if addr == "" && !required {
+		return nil
+	}
+	if !addressPattern.MatchString(addr) {
+		return errno.InvalidRequest.Add(fmt.Sprintf("%s: invalid address %q", field, addr))
+	}
+	return nil
+}
+
+func requireField(field, value string) error {
+	if value == "" {
+		return errno.InvalidRequest.Add(field + " is required")
+	}
+	return nil
+}
+
+// parseAmount 解析10进制的数量，空字符串返回nil
+func parseAmount(field, s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 {
+		return nil, errno.InvalidRequest.Add(fmt.Sprintf("%s: invalid amount %q", field, s))
+	}
+	return n, nil
+}
+
+func parseHex(field, s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, errno.InvalidRequest.Add(fmt.Sprintf("%s: %v", field, err))
+	}
+	return b, nil
+}
+
+// feeParam 是可选的交易费用参数，不传时由网关计算推荐值
+// Speed 为 slow、standard 或 fast 时，gasPrice 为0则按照档位计算 gas 价格，参考 GET /v1/{chain}/fees
+type feeParam struct {
+	GasLimit uint64    `json:"gasLimit"`
+	GasPrice uint64    `json:"gasPrice"`
+	Speed    fee.Speed `json:"speed,omitempty"`
+}
+
+func (f feeParam) option() *fee.OptionFee {
+	if f.GasLimit == 0 && f.GasPrice == 0 && f.Speed == "" {
+		return nil
+	}
+	return &fee.OptionFee{GasLimit: f.GasLimit, GasPrice: f.GasPrice, Speed: f.Speed}
+}
+
+// EncodedTx 是构建交易与注入签名的返回值
+type EncodedTx struct {
+	EncodedTx string `json:"encodedTx"` // 序列化的交易，可以传给 tx/inject、tx/broadcast 与 tx/send
+	ChainID   string `json:"chainId"`
+	// SigningHash 是交易待签名的hash，外部签名后使用 tx/inject 注入；TxHash 是签名后的交易hash
+	SigningHash string `json:"signingHash,omitempty"`
+	TxHash      string `json:"txHash,omitempty"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Nonce       uint64 `json:"nonce"`
+	Value       string `json:"value"`
+	GasLimit    uint64 `json:"gasLimit"`
+	GasPrice    uint64 `json:"gasPrice"`
+}
+
+// TxHash 是发送交易的返回值
+type TxHash struct {
+	TxHash string `json:"txHash"`
+}
+
+func (ch *chain) chainID() (interface{}, error) {
+	chainID, err := ch.client.GetChainID()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"chainId": chainID}, nil
+}
+
+func (ch *chain) balance(address string) (interface{}, error) {
+	if err := validateAddress("address", address, true); err != nil {
+		return nil, err
+	}
+	balance, err := ch.client.BalanceOf(address, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": address, "balance": balance.String()}, nil
+}
+
+// waitPollInterval 是等待交易上链时查询交易的间隔
+const waitPollInterval = time.Second
+
+// queryTx 查询交易，wait 为 true 时轮询直到交易上链、请求被取消或超过 maxWait，超时返回交易当前的状态
+func (ch *chain) queryTx(r *http.Request, hash string, maxWait time.Duration) (interface{}, error) {
+	if !hashPattern.MatchString(hash) {
+		return nil, errno.InvalidRequest.Add(fmt.Sprintf("invalid tx hash %q", hash))
+	}
+	query := r.URL.Query()
+	wait := false
+	if v := query.Get("wait"); v != "" {
+		var err error
+		if wait, err = strconv.ParseBool(v); err != nil {
+			return nil, errno.InvalidRequest.Add(fmt.Sprintf("wait: invalid bool %q", v))
+		}
+	}
+
+	// 不使用 QueryTx 的等待，其没有超时且不会因请求取消而结束
+	get := func() (*tx.TxData, error) { return ch.client.QueryTx(hash, false) }
+	if abiStr := query.Get("abi"); abiStr != "" {
+		q, ok := ch.client.(interface {
+			QueryTxWithAbi(txHash string, isWait bool, abiStr string) (*tx.TxData, error)
+		})
+		if !ok {
+			return nil, errno.InvalidRequest.Add("decoding logs is not supported by chain " + ch.name)
+		}
+		get = func() (*tx.TxData, error) { return q.QueryTxWithAbi(hash, false, abiStr) }
+	}
+
+	data, err := get()
+	if err != nil || !wait {
+		return data, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), maxWait)
+	defer cancel()
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for data.Status == common.TxStatusPending {
+		select {
+		case <-ctx.Done():
+			return data, nil
+		case <-ticker.C:
+		}
+		if data, err = get(); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// FeeEstimate 是一个档位的推荐费用，WaitSeconds 是预计的上链时间
+type FeeEstimate struct {
+	GasPrice             uint64  `json:"gasPrice"`
+	MaxFeePerGas         uint64  `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas uint64  `json:"maxPriorityFeePerGas"`
+	WaitSeconds          float64 `json:"waitSeconds"`
+}
+
+// Fees 是 GET /v1/{chain}/fees 的返回值，参考 fee.Recommendation
+type Fees struct {
+	BaseFee      uint64      `json:"baseFee"`
+	BlockSeconds float64     `json:"blockSeconds"`
+	Slow         FeeEstimate `json:"slow"`
+	Standard     FeeEstimate `json:"standard"`
+	Fast         FeeEstimate `json:"fast"`
+}
+
+func (ch *chain) fees() (interface{}, error) {
+	o, ok := ch.client.(interface {
+		SuggestFee() (*fee.Recommendation, error)
+	})
+	if !ok {
+		return nil, errno.InvalidRequest.Add("fee oracle is not supported by chain " + ch.name)
+	}
+	r, err := o.SuggestFee()
+	if err != nil {
+		return nil, err
+	}
+	estimate := func(e fee.Estimate) FeeEstimate {
+		return FeeEstimate{
+			GasPrice:             e.GasPrice,
+			MaxFeePerGas:         e.MaxFeePerGas,
+			MaxPriorityFeePerGas: e.MaxPriorityFeePerGas,
+			WaitSeconds:          e.WaitTime.Seconds(),
+		}
+	}
+	return Fees{
+		BaseFee:      r.BaseFee,
+		BlockSeconds: r.BlockTime.Seconds(),
+		Slow:         estimate(r.Slow),
+		Standard:     estimate(r.Standard),
+		Fast:         estimate(r.Fast),
+	}, nil
+}
+
+type callRequest struct {
+	From     string        `json:"from"`
+	Contract string        `json:"contract"`
+	Abi      string        `json:"abi"`
+	Method   string        `json:"method"`
+	Params   []interface{} `json:"params"`
+}
+
+func (ch *chain) call(r *http.Request) (interface{}, error) {
+	var req callRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("contract", req.Contract, true); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("from", req.From, false); err != nil {
+		return nil, err
+	}
+	if err := requireField("method", req.Method); err != nil {
+		return nil, err
+	}
+	res, err := ch.client.QueryContract(client.CallContractParam{
+		From:            req.From,
+		ContractAddress: req.Contract,
+		Abi:             req.Abi,
+		CalledFunc:      req.Method,
+		Params:          req.Params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": res.RawRes, "decoded": res.DecodeRes}, nil
+}
+
+// txRequest 是构建普通交易与估算交易费用的参数
+type txRequest struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"`
+	Data  string `json:"data"` // 16进制的交易数据
+	Nonce uint64 `json:"nonce"`
+	feeParam
+}
+
+func (req *txRequest) param() (txbuilder.BuildTxParam, error) {
+	var p txbuilder.BuildTxParam
+	if err := validateAddress("from", req.From, true); err != nil {
+		return p, err
+	}
+	if err := validateAddress("to", req.To, false); err != nil {
+		return p, err
+	}
+	value, err := parseAmount("value", req.Value)
+	if err != nil {
+		return p, err
+	}
+	data, err := parseHex("data", req.Data)
+	if err != nil {
+		return p, err
+	}
+	return txbuilder.BuildTxParam{
+		From:     req.From,
+		To:       req.To,
+		Value:    value,
+		Nonce:    req.Nonce,
+		GasLimit: req.GasLimit,
+		GasPrice: req.GasPrice,
+		Payload:  data,
+		Speed:    req.Speed,
+	}, nil
+}
+
+func (ch *chain) estimate(r *http.Request) (interface{}, error) {
+	var req txRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	p, err := req.param()
+	if err != nil {
+		return nil, err
+	}
+	// 指定 gas 与 nonce 使 BuildTx 不访问节点，估算统一由 Client.EstimateGas 完成
+	p.Nonce, p.GasLimit, p.GasPrice = 1, 1, 1
+	txn, err := ch.builder.BuildTx(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ch.client.EstimateGas(txn)
+	if err != nil {
+		return nil, err
+	}
+	return feeParam{GasLimit: f.GasLimit, GasPrice: f.GasPrice}, nil
+}
+
+// encoded 序列化未签名的交易并计算待签名hash
+func (ch *chain) encoded(t tx.Tx) (*EncodedTx, error) {
+	chainID, err := ch.client.GetChainID()
+	if err != nil {
+		return nil, err
+	}
+	res, err := encodeTx(t, chainID)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := t.GetTxHash(chainID)
+	if err != nil {
+		return nil, err
+	}
+	res.SigningHash = "0x" + hash
+	return res, nil
+}
+
+func encodeTx(t tx.Tx, chainID string) (*EncodedTx, error) {
+	encoded, err := t.EncodeTx()
+	if err != nil {
+		return nil, err
+	}
+	res := &EncodedTx{
+		EncodedTx: encoded,
+		ChainID:   chainID,
+		TxHash:    t.GetHash(),
+		From:      t.GetFrom(),
+		To:        t.GetTo(),
+		Nonce:     t.GetNonce(),
+		Value:     "0",
+	}
+	if t.GetValue() != nil {
+		res.Value = t.GetValue().String()
+	}
+	if f := t.GetFee(); f != nil {
+		res.GasLimit, res.GasPrice = f.GasLimit, f.GasPrice
+	}
+	return res, nil
+}
+
+func (ch *chain) buildTx(r *http.Request) (interface{}, error) {
+	var req txRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	p, err := req.param()
+	if err != nil {
+		return nil, err
+	}
+	txn, err := ch.builder.BuildTx(p)
+	if err != nil {
+		return nil, err
+	}
+	return ch.encoded(txn)
+}
+
+type deployRequest struct {
+	From     string        `json:"from"`
+	Abi      string        `json:"abi"`
+	ByteCode string        `json:"byteCode"`
+	Params   []interface{} `json:"params"`
+	Value    string        `json:"value"`
+	Nonce    uint64        `json:"nonce"`
+	feeParam
+}
+
+func (ch *chain) buildDeployTx(r *http.Request) (interface{}, error) {
+	var req deployRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("from", req.From, true); err != nil {
+		return nil, err
+	}
+	if err := requireField("byteCode", req.ByteCode); err != nil {
+		return nil, err
+	}
+	value, err := parseAmount("value", req.Value)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := ch.contract.BuildDeployTx(txbuilder.BuildDeployTxReq{
+		From:     req.From,
+		Abi:      req.Abi,
+		ByteCode: req.ByteCode,
+		Params:   req.Params,
+		Nonce:    req.Nonce,
+		Value:    value,
+		GasLimit: req.GasLimit,
+		GasPrice: req.GasPrice,
+		Speed:    req.Speed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := ch.encoded(txn)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := txn.(interface{ ContractAddress() string }); ok {
+		return struct {
+			*EncodedTx
+			ContractAddress string `json:"contractAddress"`
+		}{res, c.ContractAddress()}, nil
+	}
+	return res, nil
+}
+
+type invokeRequest struct {
+	From     string        `json:"from"`
+	Contract string        `json:"contract"`
+	Abi      string        `json:"abi"`
+	Method   string        `json:"method"`
+	Params   []interface{} `json:"params"`
+	Value    string        `json:"value"`
+	Nonce    uint64        `json:"nonce"`
+	feeParam
+}
+
+func (ch *chain) buildInvokeTx(r *http.Request) (interface{}, error) {
+	var req invokeRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("from", req.From, true); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("contract", req.Contract, true); err != nil {
+		return nil, err
+	}
+	if err := requireField("method", req.Method); err != nil {
+		return nil, err
+	}
+	value, err := parseAmount("value", req.Value)
+	if err != nil {
+		return nil, err
+	}
+	txn, err := ch.contract.BuildInvokeTx(txbuilder.BuildInvokeTxReq{
+		From:            req.From,
+		Abi:             req.Abi,
+		Method:          req.Method,
+		Params:          req.Params,
+		Nonce:           req.Nonce,
+		Value:           value,
+		ContractAddress: req.Contract,
+		GasLimit:        req.GasLimit,
+		GasPrice:        req.GasPrice,
+		Speed:           req.Speed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch.encoded(txn)
+}
+
+// decodeTx 解析序列化的交易，交易格式错误属于请求的问题
+func (ch *chain) decodeTx(encoded string) (tx.Tx, error) {
+	if err := requireField("encodedTx", encoded); err != nil {
+		return nil, err
+	}
+	t, err := ch.builder.DecodeTx(encoded)
+	if err != nil {
+		if _, ok := err.(*errno.Errno); ok {
+			return nil, err
+		}
+		return nil, errno.ParseTxError.Add(err.Error())
+	}
+	return t, nil
+}
+
+type injectRequest struct {
+	EncodedTx string `json:"encodedTx"`
+	Signature string `json:"signature"` // 16进制的签名，签名算法与链的 Tx.SignHash 一致
+}
+
+func (ch *chain) injectSignature(r *http.Request) (interface{}, error) {
+	var req injectRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if err := requireField("signature", req.Signature); err != nil {
+		return nil, err
+	}
+	t, err := ch.decodeTx(req.EncodedTx)
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := ch.client.GetChainID()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.InjectSignature(strings.TrimPrefix(req.Signature, "0x"), chainID); err != nil {
+		if _, ok := err.(*errno.Errno); ok {
+			return nil, err
+		}
+		return nil, errno.InvalidSignature.Add(err.Error())
+	}
+	return encodeTx(t, chainID)
+}
+
+type broadcastRequest struct {
+	EncodedTx string `json:"encodedTx"` // 已签名的序列化交易，也可以是16进制的签名交易原文
+}
+
+func (ch *chain) broadcast(r *http.Request) (interface{}, error) {
+	var req broadcastRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	t, err := ch.decodeTx(req.EncodedTx)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := ch.client.SendSignedTx(t)
+	if err != nil {
+		return nil, err
+	}
+	return TxHash{txHash}, nil
+}
+
+type transferRequest struct {
+	To    string `json:"to"`
+	Value string `json:"value"`
+	feeParam
+}
+
+func (ch *chain) transfer(r *http.Request) (interface{}, error) {
+	if !ch.signing {
+		return nil, errno.PrivateNotSet.Add("signing is not enabled for chain " + ch.name)
+	}
+	var req transferRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	if err := validateAddress("to", req.To, true); err != nil {
+		return nil, err
+	}
+	if err := requireField("value", req.Value); err != nil {
+		return nil, err
+	}
+	value, err := parseAmount("value", req.Value)
+	if err != nil {
+		return nil, err
+	}
+	txHash, err := ch.client.Transfer(req.To, value, nil, req.option())
+	if err != nil {
+		return nil, err
+	}
+	return TxHash{txHash}, nil
+}
+
+type sendRequest struct {
+	EncodedTx string `json:"encodedTx"` // 未签名的序列化交易，发起地址必须是网关私钥对应的地址
+	feeParam
+}
+
+func (ch *chain) sendTx(r *http.Request) (interface{}, error) {
+	if !ch.signing {
+		return nil, errno.PrivateNotSet.Add("signing is not enabled for chain " + ch.name)
+	}
+	var req sendRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, err
+	}
+	t, err := ch.decodeTx(req.EncodedTx)
+	if err != nil {
+		return nil, err
+	}
+	// 交易的 nonce 与费用是按发起地址构建的，不能直接换成网关的账户发送
+	if account := ch.client.GetAccount(); !strings.EqualFold(t.GetFrom(), account) {
+		return nil, errno.InvalidRequest.Add(fmt.Sprintf("from %s of encodedTx is not the gateway account %s", t.GetFrom(), account))
+	}
+	txHash, err := ch.client.SendTx(t, req.option())
+	if err != nil {
+		return nil, err
+	}
+	return TxHash{txHash}, nil
+}
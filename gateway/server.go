@@ -0,0 +1,302 @@
+// Package gateway 将 client.Client、TxBuilder 与 ContractTxBuilder 的功能以 HTTP/JSON 接口提供给非 Go 服务使用
+//
+// 所有接口以 /v1/{chain} 为前缀，{chain} 为配置中链的名称:
+//
+//	GET  /v1/chains                            已配置的链
+//	GET  /v1/{chain}/chainId                   链ID
+//	GET  /v1/{chain}/balance/{address}         原生币余额
+//	GET  /v1/{chain}/tx/{hash}?wait=&abi=      交易状态与详情，wait=true 时最多等待 MaxWait 直到交易上链
+//	GET  /v1/{chain}/fees                      slow、standard、fast 三个档位的推荐费用
+//	POST /v1/{chain}/call                      查询合约
+//	POST /v1/{chain}/estimate                  估算交易费用
+//	POST /v1/{chain}/tx/build                  构建未签名的交易
+//	POST /v1/{chain}/deploy/build              构建部署合约的交易
+//	POST /v1/{chain}/invoke/build              构建调用合约的交易
+//	POST /v1/{chain}/tx/inject                 将外部签名注入交易
+//	POST /v1/{chain}/tx/broadcast              广播已签名的交易
+//	POST /v1/{chain}/transfer                  使用网关的私钥转账，需要为链配置私钥
+//	POST /v1/{chain}/tx/send                   使用网关的私钥签名并发送发起地址为网关账户的交易，需要为链配置私钥
+//
+// 构建与发送交易的请求可以使用 speed 字段 (slow、standard、fast) 按照档位计算 gas 价格。
+// 数量 (value、balance) 使用10进制字符串表示，出错时返回 {"error": {"state": 20023, "message": "..."}}，
+// 其中 state 为 errno 中定义的错误码。节点返回的错误以 502 返回，能识别的错误 state 为 3xxxx，如 30001 (nonce too low)，
+// 无法识别的错误 state 为0；节点限流时返回 503
+//
+// 配置了私钥的链可以由网关签名交易，此时必须使用 WithAuth 设置请求的校验，否则 New 会返回错误
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/mgintoki/multichain"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/errno"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxWait 是查询交易时等待交易上链的默认最长时间
+const DefaultMaxWait = time.Minute
+
+// ChainConfig 是网关中一条链的配置
+type ChainConfig struct {
+	Name     string `json:"name"`     // 链的名称，作为接口路径的一部分
+	Type     string `json:"type"`     // 链类型，参考 multichain.ParseChainType
+	Provider string `json:"provider"` // 节点地址
+	// PrivateKey 可选，配置后可以使用 transfer 与 tx/send 接口由网关签名交易
+	PrivateKey string `json:"privateKey,omitempty"`
+	// MinGasPrice 与 MaxGasPrice 可选，是网关计算的 gas 价格的上下限，单位为 wei
+	MinGasPrice uint64 `json:"minGasPrice,omitempty"`
+	MaxGasPrice uint64 `json:"maxGasPrice,omitempty"`
+}
+
+// Config 是网关的配置
+type Config struct {
+	Chains []ChainConfig `json:"chains"`
+}
+
+// Authenticator 校验请求，返回的错误会以 401 返回给调用方
+type Authenticator func(r *http.Request) error
+
+// BearerAuth 返回校验 Authorization: Bearer <token> 请求头的 Authenticator
+// token 使用常量时间比较，避免通过响应时间猜测 token
+func BearerAuth(tokens ...string) Authenticator {
+	allowed := make([][]byte, 0, len(tokens))
+	for _, token := range tokens {
+		if token != "" {
+			allowed = append(allowed, []byte(token))
+		}
+	}
+	return func(r *http.Request) error {
+		token := []byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		matched := 0
+		for _, t := range allowed {
+			matched |= subtle.ConstantTimeCompare(token, t)
+		}
+		if len(token) == 0 || matched != 1 {
+			return errno.Unauthorized
+		}
+		return nil
+	}
+}
+
+// Option 是创建网关的可选参数
+type Option func(s *Server)
+
+// WithAuth 设置请求的校验，可以设置多个，按顺序执行
+func WithAuth(auth Authenticator) Option {
+	return func(s *Server) {
+		s.auth = append(s.auth, auth)
+	}
+}
+
+// WithMaxWait 设置查询交易时等待交易上链的最长时间，默认为 DefaultMaxWait
+// 超时后返回交易当前的状态，http.Server 的 WriteTimeout 需要大于该时间
+func WithMaxWait(d time.Duration) Option {
+	return func(s *Server) {
+		s.maxWait = d
+	}
+}
+
+// chain 是一条链的 Client 与交易构造器
+type chain struct {
+	name     string
+	typ      uint
+	client   client.Client
+	builder  txbuilder.TxBuilder
+	contract txbuilder.ContractTxBuilder
+	signing  bool
+}
+
+// Server 是 HTTP/JSON 网关，实现了 http.Handler
+type Server struct {
+	chains  map[string]*chain
+	auth    []Authenticator
+	maxWait time.Duration
+}
+
+// New 根据配置创建网关
+func New(cfg Config, opts ...Option) (*Server, error) {
+	s := &Server{chains: map[string]*chain{}, maxWait: DefaultMaxWait}
+	for _, c := range cfg.Chains {
+		if c.Name == "" || c.Provider == "" {
+			return nil, errno.InvalidRequest.Add("chain name and provider are required")
+		}
+		if _, ok := s.chains[c.Name]; ok {
+			return nil, errno.InvalidRequest.Add("duplicate chain " + c.Name)
+		}
+		typ, err := multichain.ParseChainType(c.Type)
+		if err != nil {
+			return nil, err
+		}
+		p := provider.CommonProvider{
+			ProviderUrl: c.Provider,
+			FeeCaps:     fee.Caps{Min: c.MinGasPrice, Max: c.MaxGasPrice},
+		}
+		ch := &chain{name: c.Name, typ: typ}
+		if ch.client, err = multichain.NewClient(typ, p); err != nil {
+			return nil, err
+		}
+		if ch.builder, err = multichain.NewTxBuilder(typ, p); err != nil {
+			return nil, err
+		}
+		if ch.contract, err = multichain.NewContractTxBuilder(typ, p); err != nil {
+			return nil, err
+		}
+		if c.PrivateKey != "" {
+			if err := ch.client.SetPrivate(strings.TrimPrefix(c.PrivateKey, "0x")); err != nil {
+				return nil, err
+			}
+			ch.signing = true
+		}
+		s.chains[c.Name] = ch
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// 没有请求校验时，能访问网关的任何人都可以使用私钥转账
+	if len(s.auth) == 0 {
+		for _, c := range cfg.Chains {
+			if c.PrivateKey != "" {
+				return nil, errno.Unauthorized.Add("chain " + c.Name + " has a private key, an authenticator must be set with WithAuth")
+			}
+		}
+	}
+	return s, nil
+}
+
+// ServeHTTP 处理网关请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, auth := range s.auth {
+		if err := auth(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeError(w, http.StatusNotFound, errno.InvalidRequest.Add("unknown path "+r.URL.Path))
+		return
+	}
+	if len(parts) == 2 && parts[1] == "chains" {
+		s.serve(w, r, http.MethodGet, func() (interface{}, error) { return s.listChains(), nil })
+		return
+	}
+	ch, ok := s.chains[parts[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, errno.NotSupportChainType.Add(parts[1]))
+		return
+	}
+
+	route := strings.Join(parts[2:], "/")
+	if handler, ok := postRoutes[route]; ok {
+		s.serve(w, r, http.MethodPost, func() (interface{}, error) { return handler(ch, r) })
+		return
+	}
+	switch {
+	case route == "chainId":
+		s.serve(w, r, http.MethodGet, func() (interface{}, error) { return ch.chainID() })
+	case route == "fees":
+		s.serve(w, r, http.MethodGet, func() (interface{}, error) { return ch.fees() })
+	case len(parts) == 4 && parts[2] == "balance":
+		s.serve(w, r, http.MethodGet, func() (interface{}, error) { return ch.balance(parts[3]) })
+	case len(parts) == 4 && parts[2] == "tx":
+		s.serve(w, r, http.MethodGet, func() (interface{}, error) { return ch.queryTx(r, parts[3], s.maxWait) })
+	default:
+		writeError(w, http.StatusNotFound, errno.InvalidRequest.Add("unknown path "+r.URL.Path))
+	}
+}
+
+// postRoutes 是 POST 接口，请求体为 json
+var postRoutes = map[string]func(ch *chain, r *http.Request) (interface{}, error){
+	"call":         (*chain).call,
+	"estimate":     (*chain).estimate,
+	"tx/build":     (*chain).buildTx,
+	"deploy/build": (*chain).buildDeployTx,
+	"invoke/build": (*chain).buildInvokeTx,
+	"tx/inject":    (*chain).injectSignature,
+	"tx/broadcast": (*chain).broadcast,
+	"transfer":     (*chain).transfer,
+	"tx/send":      (*chain).sendTx,
+}
+
+type chainInfo struct {
+	Name    string `json:"name"`
+	Type    uint   `json:"type"`
+	Signing bool   `json:"signing"` // 是否可以使用 transfer 与 tx/send 接口
+}
+
+func (s *Server) listChains() []chainInfo {
+	chains := make([]chainInfo, 0, len(s.chains))
+	for _, ch := range s.chains {
+		chains = append(chains, chainInfo{Name: ch.name, Type: ch.typ, Signing: ch.signing})
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Name < chains[j].Name })
+	return chains
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, method string, handler func() (interface{}, error)) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, errno.InvalidRequest.Add("method "+r.Method+" not allowed"))
+		return
+	}
+	res, err := handler()
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// statusOf 根据错误类型返回 HTTP 状态码
+// errno 中定义的请求错误返回 4xx；节点返回的错误返回 502，节点限流时返回 503
+func statusOf(err error) int {
+	var e *errno.Errno
+	if !errors.As(err, &e) {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, errno.RateLimited) {
+		return http.StatusServiceUnavailable
+	}
+	if e.State >= errno.NonceTooLow.State {
+		return http.StatusBadGateway
+	}
+	switch e.State {
+	case errno.NotSupportChainType.State:
+		return http.StatusNotFound
+	case errno.PrivateNotSet.State:
+		return http.StatusForbidden
+	case errno.Unauthorized.State:
+		return http.StatusUnauthorized
+	}
+	return http.StatusBadRequest
+}
+
+// Error 是接口返回的错误
+type Error struct {
+	State   int    `json:"state"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	e := Error{Message: err.Error()}
+	var en *errno.Errno
+	if errors.As(err, &en) {
+		e.State = en.State
+	}
+	writeJSON(w, status, map[string]Error{"error": e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,228 @@
+package gateway_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/mgintoki/multichain/chain/ethereum"
+	"github.com/mgintoki/multichain/chain/ethereum/simulated"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/gateway"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testGateway struct {
+	t      *testing.T
+	server *httptest.Server
+	token  string
+}
+
+func (g *testGateway) do(method, path string, body interface{}, status int, out interface{}) {
+	g.t.Helper()
+	var reader *bytes.Reader
+	if s, ok := body.(string); ok {
+		reader = bytes.NewReader([]byte(s))
+	} else {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, _ := http.NewRequest(method, g.server.URL+path, reader)
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		g.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		var e map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		g.t.Fatalf("%s %s: expect status %d, got %d %v", method, path, status, resp.StatusCode, e)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			g.t.Fatal(err)
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	accounts := backend.Accounts()
+
+	handler, err := gateway.New(gateway.Config{Chains: []gateway.ChainConfig{
+		{Name: "local", Type: "ethereum", Provider: backend.URL()},
+		{Name: "signing", Type: "bsc", Provider: backend.URL(), PrivateKey: accounts[1].PrivateKey},
+	}}, gateway.WithAuth(gateway.BearerAuth("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	g := &testGateway{t: t, server: server}
+
+	var errRes struct {
+		Error gateway.Error `json:"error"`
+	}
+	g.do("GET", "/v1/chains", nil, http.StatusUnauthorized, &errRes)
+	if errRes.Error.State != 20024 {
+		t.Fatalf("unexpected error %+v", errRes.Error)
+	}
+	g.token = "secret"
+
+	var chains []map[string]interface{}
+	g.do("GET", "/v1/chains", nil, http.StatusOK, &chains)
+	if len(chains) != 2 || chains[0]["name"] != "local" || chains[1]["signing"] != true {
+		t.Fatalf("unexpected chains %v", chains)
+	}
+
+	var balance map[string]string
+	g.do("GET", "/v1/local/balance/"+accounts[0].Address, nil, http.StatusOK, &balance)
+	if balance["balance"] != simulated.DefaultBalance.String() {
+		t.Fatalf("unexpected balance %v", balance)
+	}
+
+	// 构建交易，在外部签名后注入并广播
+	var built gateway.EncodedTx
+	g.do("POST", "/v1/local/tx/build", map[string]interface{}{"from": accounts[0].Address, "to": accounts[1].Address, "value": "1000"}, http.StatusOK, &built)
+	if built.ChainID != "1337" || built.Value != "1000" || built.SigningHash == "" || built.TxHash != "" || built.GasLimit != 21000 {
+		t.Fatalf("unexpected built tx %+v", built)
+	}
+	sig, err := new(ethereum.Txn).SignHash(accounts[0].PrivateKey, built.ChainID, strings.TrimPrefix(built.SigningHash, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signed gateway.EncodedTx
+	g.do("POST", "/v1/local/tx/inject", map[string]string{"encodedTx": built.EncodedTx, "signature": sig}, http.StatusOK, &signed)
+	if signed.TxHash == "" {
+		t.Fatalf("unexpected signed tx %+v", signed)
+	}
+	var sent gateway.TxHash
+	g.do("POST", "/v1/local/tx/broadcast", map[string]string{"encodedTx": signed.EncodedTx}, http.StatusOK, &sent)
+	if sent.TxHash != signed.TxHash {
+		t.Fatalf("expect tx hash %s, got %s", signed.TxHash, sent.TxHash)
+	}
+
+	var txData map[string]interface{}
+	g.do("GET", "/v1/local/tx/"+sent.TxHash+"?wait=true", nil, http.StatusOK, &txData)
+	if txData["status"] != float64(2) || txData["value"] != float64(1000) {
+		t.Fatalf("unexpected tx %v", txData)
+	}
+
+	// 由网关签名的转账
+	g.do("POST", "/v1/local/transfer", map[string]string{"to": accounts[0].Address, "value": "1"}, http.StatusForbidden, &errRes)
+	g.do("POST", "/v1/signing/transfer", map[string]string{"to": accounts[0].Address, "value": "1", "speed": "fast"}, http.StatusOK, &sent)
+
+	// 由网关签名的交易，发起地址必须是网关的账户
+	g.do("POST", "/v1/signing/tx/build", map[string]interface{}{"from": accounts[0].Address, "to": accounts[1].Address, "value": "1"}, http.StatusOK, &built)
+	g.do("POST", "/v1/signing/tx/send", map[string]string{"encodedTx": built.EncodedTx}, http.StatusBadRequest, &errRes)
+	if errRes.Error.State != 20023 || !strings.Contains(errRes.Error.Message, "gateway account") {
+		t.Fatalf("unexpected error %+v", errRes.Error)
+	}
+	g.do("POST", "/v1/signing/tx/build", map[string]interface{}{"from": accounts[1].Address, "to": accounts[0].Address, "value": "1"}, http.StatusOK, &built)
+	g.do("POST", "/v1/signing/tx/send", map[string]string{"encodedTx": built.EncodedTx}, http.StatusOK, &sent)
+	g.do("GET", "/v1/signing/tx/"+sent.TxHash+"?wait=true", nil, http.StatusOK, &txData)
+	if !strings.EqualFold(txData["from"].(string), accounts[1].Address) || txData["status"] != float64(2) {
+		t.Fatalf("unexpected tx %v", txData)
+	}
+
+	// 推荐费用与按档位构建交易
+	var fees gateway.Fees
+	g.do("GET", "/v1/local/fees", nil, http.StatusOK, &fees)
+	if fees.Fast.GasPrice == 0 || fees.Fast.GasPrice < fees.Slow.GasPrice {
+		t.Fatalf("unexpected fees %+v", fees)
+	}
+	g.do("POST", "/v1/local/tx/build", map[string]interface{}{"from": accounts[0].Address, "to": accounts[1].Address, "speed": "fast"}, http.StatusOK, &built)
+	if built.GasPrice != fees.Fast.GasPrice {
+		t.Fatalf("expect fast gas price %d, got %d", fees.Fast.GasPrice, built.GasPrice)
+	}
+	g.do("POST", "/v1/local/tx/build", map[string]interface{}{"from": accounts[0].Address, "to": accounts[1].Address, "speed": "instant"}, http.StatusBadRequest, &errRes)
+
+	// 请求校验
+	g.do("POST", "/v1/local/tx/build", map[string]interface{}{"from": "0x1234", "to": accounts[1].Address}, http.StatusBadRequest, &errRes)
+	if errRes.Error.State != 20023 || !strings.Contains(errRes.Error.Message, "from") {
+		t.Fatalf("unexpected error %+v", errRes.Error)
+	}
+	g.do("POST", "/v1/local/tx/build", `{"from": "`+accounts[0].Address+`", "unknown": 1}`, http.StatusBadRequest, &errRes)
+	g.do("POST", "/v1/local/tx/broadcast", map[string]string{"encodedTx": "zz"}, http.StatusBadRequest, &errRes)
+	g.do("GET", "/v1/local/tx/build", nil, http.StatusMethodNotAllowed, &errRes)
+	g.do("GET", "/v1/unknown/chainId", nil, http.StatusNotFound, &errRes)
+	if errRes.Error.State != 10001 {
+		t.Fatalf("unexpected error %+v", errRes.Error)
+	}
+
+	// 节点返回的错误
+	g.do("POST", "/v1/local/tx/broadcast", map[string]string{"encodedTx": signed.EncodedTx}, http.StatusBadGateway, &errRes)
+	if errRes.Error.State != 30001 || !strings.Contains(errRes.Error.Message, "nonce too low") {
+		t.Fatalf("unexpected error %+v", errRes.Error)
+	}
+}
+
+func TestGatewayRequiresAuthForSigning(t *testing.T) {
+	backend, err := simulated.New(simulated.WithAccounts(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	cfg := gateway.Config{Chains: []gateway.ChainConfig{
+		{Name: "signing", Type: "ethereum", Provider: backend.URL(), PrivateKey: backend.Accounts()[0].PrivateKey},
+	}}
+	if _, err := gateway.New(cfg); !errors.Is(err, errno.Unauthorized) {
+		t.Fatalf("expect Unauthorized without authenticator, got %v", err)
+	}
+
+	handler, err := gateway.New(cfg, gateway.WithAuth(gateway.BearerAuth("secret", "other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	g := &testGateway{t: t, server: server}
+	for _, token := range []string{"", "secre", "secret2", "Bearer secret"} {
+		g.token = token
+		g.do("GET", "/v1/chains", nil, http.StatusUnauthorized, nil)
+	}
+	for _, token := range []string{"secret", "other"} {
+		g.token = token
+		g.do("GET", "/v1/chains", nil, http.StatusOK, nil)
+	}
+}
+
+func TestGatewayWaitTimeout(t *testing.T) {
+	backend, err := simulated.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	handler, err := gateway.New(gateway.Config{Chains: []gateway.ChainConfig{
+		{Name: "local", Type: "ethereum", Provider: backend.URL()},
+	}}, gateway.WithMaxWait(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	g := &testGateway{t: t, server: server}
+
+	// 不存在的交易一直处于 pending 状态，超过 MaxWait 后返回当前状态
+	start := time.Now()
+	var txData map[string]interface{}
+	g.do("GET", "/v1/local/tx/0x"+strings.Repeat("ab", 32)+"?wait=true", nil, http.StatusOK, &txData)
+	if txData["status"] != float64(1) {
+		t.Fatalf("expect pending tx, got %v", txData)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("wait is not bounded, took %s", elapsed)
+	}
+}
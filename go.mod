@@ -4,9 +4,12 @@ go 1.16
 
 require (
 	github.com/btcsuite/btcd v0.21.0-beta
-	github.com/ethereum/go-ethereum v1.9.21
+	github.com/btcsuite/btcutil v1.0.2
+	github.com/ethereum/go-ethereum v1.10.16
+	github.com/google/uuid v1.1.5
+	github.com/gorilla/websocket v1.4.2
 	github.com/mgintoki/go-web3 v0.0.7
-	github.com/umbracle/ethgo v0.1.0
+	github.com/tyler-smith/go-bip39 v1.1.0
 	github.com/umbracle/fastrlp v0.0.0-20211229195328-c1416904ae17
-	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 )
@@ -0,0 +1,179 @@
+package multichain
+
+import (
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/errno"
+	"github.com/mgintoki/multichain/middleware"
+	"math/big"
+)
+
+// WrapClient 返回使用 provider 中的拦截器包装的 Client，每次访问链的方法调用都会以 middleware.KindClient 经过拦截器
+// multichain.NewClient 会在 provider 设置了 Interceptors 时自动包装，直接使用各链的 NewClient 时可以调用该方法
+// 包装后的 Client 只实现 client.Client，SetSigner、SignMessage 等各链特有的方法需要先通过 Unwrap 取得原始 Client，这些调用不经过拦截器
+func WrapClient(cli client.Client, provider provider.CommonProvider) client.Client {
+	if len(provider.Interceptors) == 0 {
+		return cli
+	}
+	return &interceptedClient{
+		cli:          cli,
+		chain:        provider.Chain,
+		endpoint:     middleware.Endpoint(provider.ProviderUrl),
+		interceptors: provider.Interceptors,
+	}
+}
+
+// Unwrap 返回 WrapClient 包装前的 Client，cli 未被包装时原样返回
+// 例如 multichain.Unwrap(cli).(*ethereum.Client)
+func Unwrap(cli client.Client) client.Client {
+	if w, ok := cli.(interface{ Unwrap() client.Client }); ok {
+		return w.Unwrap()
+	}
+	return cli
+}
+
+// interceptedClient 为 Client 的方法调用执行拦截器
+type interceptedClient struct {
+	cli          client.Client
+	chain        string
+	endpoint     string
+	interceptors []middleware.Interceptor
+}
+
+// Unwrap 返回被包装的 Client
+func (c *interceptedClient) Unwrap() client.Client {
+	return c.cli
+}
+
+func (c *interceptedClient) invoke(method string, fn func() error) error {
+	call := &middleware.Call{
+		Kind:     middleware.KindClient,
+		Chain:    c.chain,
+		Endpoint: c.endpoint,
+		Method:   method,
+	}
+	return middleware.Invoke(c.interceptors, call, fn)
+}
+
+func (c *interceptedClient) SetProvider(provider provider.CommonProvider) error {
+	if provider.Chain == "" {
+		provider.Chain = c.chain
+	}
+	if err := c.cli.SetProvider(provider); err != nil {
+		return err
+	}
+	c.endpoint = middleware.Endpoint(provider.ProviderUrl)
+	return nil
+}
+
+func (c *interceptedClient) SetPrivate(hexPrivate string) error {
+	return c.cli.SetPrivate(hexPrivate)
+}
+
+func (c *interceptedClient) SetKeystore(keyJSON string, passphrase string) error {
+	return c.cli.SetKeystore(keyJSON, passphrase)
+}
+
+func (c *interceptedClient) SetMnemonic(mnemonic string, password string, path string) error {
+	return c.cli.SetMnemonic(mnemonic, password, path)
+}
+
+func (c *interceptedClient) GetAccount() string {
+	return c.cli.GetAccount()
+}
+
+func (c *interceptedClient) GetChainID() (chainID string, err error) {
+	err = c.invoke("GetChainID", func() error {
+		chainID, err = c.cli.GetChainID()
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) BalanceOf(address string, optionAsset *client.OptionAsset) (amount *big.Int, err error) {
+	err = c.invoke("BalanceOf", func() error {
+		amount, err = c.cli.BalanceOf(address, optionAsset)
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) Transfer(to string, amount *big.Int, optionAsset *client.OptionAsset, optionFee *fee.OptionFee) (txHash string, err error) {
+	err = c.invoke("Transfer", func() error {
+		txHash, err = c.cli.Transfer(to, amount, optionAsset, optionFee)
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) QueryTx(txHash string, isWait bool) (txData *tx.TxData, err error) {
+	err = c.invoke("QueryTx", func() error {
+		txData, err = c.cli.QueryTx(txHash, isWait)
+		return err
+	})
+	return
+}
+
+// QueryTxWithAbi 在被包装的 Client 支持时，查询交易并使用 abi 解析日志
+func (c *interceptedClient) QueryTxWithAbi(txHash string, isWait bool, abiStr string) (txData *tx.TxData, err error) {
+	q, ok := c.cli.(interface {
+		QueryTxWithAbi(txHash string, isWait bool, abiStr string) (*tx.TxData, error)
+	})
+	if !ok {
+		return nil, errno.NotSupportChainType.Add("QueryTxWithAbi")
+	}
+	err = c.invoke("QueryTxWithAbi", func() error {
+		txData, err = q.QueryTxWithAbi(txHash, isWait, abiStr)
+		return err
+	})
+	return
+}
+
+// SuggestFee 在被包装的 Client 支持时，返回费用预言机各档位的推荐费用
+func (c *interceptedClient) SuggestFee() (r *fee.Recommendation, err error) {
+	o, ok := c.cli.(interface {
+		SuggestFee() (*fee.Recommendation, error)
+	})
+	if !ok {
+		return nil, errno.NotSupportChainType.Add("SuggestFee")
+	}
+	err = c.invoke("SuggestFee", func() error {
+		r, err = o.SuggestFee()
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) SendTx(tx tx.Tx, feeOption *fee.OptionFee) (txHash string, err error) {
+	err = c.invoke("SendTx", func() error {
+		txHash, err = c.cli.SendTx(tx, feeOption)
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) SendSignedTx(signedTx tx.Tx) (txHash string, err error) {
+	err = c.invoke("SendSignedTx", func() error {
+		txHash, err = c.cli.SendSignedTx(signedTx)
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) QueryContract(req client.CallContractParam) (res *client.CallContractRes, err error) {
+	err = c.invoke("QueryContract", func() error {
+		res, err = c.cli.QueryContract(req)
+		return err
+	})
+	return
+}
+
+func (c *interceptedClient) EstimateGas(tx tx.Tx) (feeRes *fee.OptionFee, err error) {
+	err = c.invoke("EstimateGas", func() error {
+		feeRes, err = c.cli.EstimateGas(tx)
+		return err
+	})
+	return
+}
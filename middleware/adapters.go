@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"time"
+)
+
+// MetricLabels 是 Metrics 上报指标时使用的标签，按顺序对应 Counter 与 Histogram 的 labels 参数
+// status 为 ok 或 error
+var MetricLabels = []string{"kind", "chain", "endpoint", "method", "status"}
+
+// Counter 是计数器，labels 与 MetricLabels 一一对应
+// 使用 prometheus 时可以用 CounterFunc 包装 CounterVec: func(l ...string) { vec.WithLabelValues(l...).Inc() }
+type Counter interface {
+	Inc(labels ...string)
+}
+
+// CounterFunc 将函数适配为 Counter
+type CounterFunc func(labels ...string)
+
+func (f CounterFunc) Inc(labels ...string) {
+	f(labels...)
+}
+
+// Histogram 是直方图，labels 与 MetricLabels 一一对应
+// 使用 prometheus 时可以用 HistogramFunc 包装 HistogramVec: func(v float64, l ...string) { vec.WithLabelValues(l...).Observe(v) }
+type Histogram interface {
+	Observe(value float64, labels ...string)
+}
+
+// HistogramFunc 将函数适配为 Histogram
+type HistogramFunc func(value float64, labels ...string)
+
+func (f HistogramFunc) Observe(value float64, labels ...string) {
+	f(value, labels...)
+}
+
+// MetricsConfig 是 Metrics 上报的指标，为 nil 的指标不会上报
+type MetricsConfig struct {
+	Requests     Counter   // 调用次数
+	Latency      Histogram // 调用耗时，单位为秒
+	RequestSize  Histogram // 请求大小，单位为字节，仅 KindRPC 上报
+	ResponseSize Histogram // 返回大小，单位为字节，仅 KindRPC 上报
+}
+
+// Metrics 返回上报调用次数、耗时与请求大小的拦截器
+func Metrics(cfg MetricsConfig) Interceptor {
+	return func(call *Call, next func() error) error {
+		err := next()
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		labels := []string{call.Kind, call.Chain, call.Endpoint, call.Method, status}
+		if cfg.Requests != nil {
+			cfg.Requests.Inc(labels...)
+		}
+		if cfg.Latency != nil {
+			cfg.Latency.Observe(call.Latency.Seconds(), labels...)
+		}
+		if call.Kind == KindRPC {
+			if cfg.RequestSize != nil {
+				cfg.RequestSize.Observe(float64(call.RequestSize), labels...)
+			}
+			if cfg.ResponseSize != nil {
+				cfg.ResponseSize.Observe(float64(call.ResponseSize), labels...)
+			}
+		}
+		return err
+	}
+}
+
+// Logger 是结构化日志，args 为交替的 key 与 value，*slog.Logger 实现了该接口
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Logging 返回记录调用日志的拦截器，成功的调用使用 Debug 级别，失败的调用使用 Error 级别
+func Logging(logger Logger) Interceptor {
+	return func(call *Call, next func() error) error {
+		err := next()
+		args := []interface{}{
+			"kind", call.Kind,
+			"chain", call.Chain,
+			"endpoint", call.Endpoint,
+			"method", call.Method,
+			"latency", call.Latency.Round(time.Microsecond),
+		}
+		if call.Kind == KindRPC {
+			args = append(args, "requestSize", call.RequestSize, "responseSize", call.ResponseSize)
+		}
+		if err != nil {
+			logger.Error("multichain call failed", append(args, "error", err)...)
+		} else {
+			logger.Debug("multichain call", args...)
+		}
+		return err
+	}
+}
+
+// Tracer 创建链路追踪的 span
+// 使用 OpenTelemetry 时，Start 中调用 trace.Tracer 的 Start，并将 trace.Span 包装为 Span
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span 是一次调用对应的 span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracing 返回为每次调用创建 span 的拦截器
+// KindRPC 的 span 名称为 RPC 方法名，如 eth_call，KindClient 的 span 名称为 Client.方法名，如 Client.Transfer
+func Tracing(tracer Tracer) Interceptor {
+	return func(call *Call, next func() error) error {
+		name := call.Method
+		if call.Kind == KindClient {
+			name = "Client." + call.Method
+		}
+		span := tracer.Start(name)
+		defer span.End()
+		span.SetAttribute("multichain.kind", call.Kind)
+		span.SetAttribute("multichain.chain", call.Chain)
+		span.SetAttribute("multichain.endpoint", call.Endpoint)
+		span.SetAttribute("multichain.method", call.Method)
+
+		err := next()
+		if call.Kind == KindRPC {
+			span.SetAttribute("multichain.request_size", call.RequestSize)
+			span.SetAttribute("multichain.response_size", call.ResponseSize)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,81 @@
+// Package middleware 提供 SDK 对外调用的拦截器，用于接入指标、日志与链路追踪
+//
+// 拦截器会作用于两个层次:
+//   - 每一次发往节点的 JSON-RPC 请求，Call.Kind 为 KindRPC，Method 为 RPC 方法名，如 eth_getBalance
+//   - 每一次 client.Client 的方法调用，Call.Kind 为 KindClient，Method 为 Client 的方法名，如 Transfer
+//
+// 通过 provider.CommonProvider 的 Interceptors 字段设置拦截器，multichain.NewClient 等方法创建的对象会自动使用
+package middleware
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+const (
+	KindRPC    = "rpc"    // 发往节点的 JSON-RPC 请求
+	KindClient = "client" // client.Client 的方法调用
+)
+
+// Call 描述一次被拦截的调用
+// 调用 next 之前只有 Kind、Chain、Endpoint、Method 与 RequestSize 可用，
+// next 返回后 ResponseSize 与 Latency 才会被设置
+type Call struct {
+	Kind     string // KindRPC 或 KindClient
+	Chain    string // 链的名称，如 ethereum
+	Endpoint string // 节点地址，只保留 scheme 与 host，避免将地址中的 api key 写入指标与日志
+	Method   string // RPC 方法名或 Client 方法名
+
+	RequestSize  int           // 请求参数 json 编码后的字节数，没有参数时为0，仅 KindRPC 有效
+	ResponseSize int           // 返回结果 json 编码后的字节数，仅 KindRPC 有效
+	Start        time.Time     // 调用开始的时间
+	Latency      time.Duration // 调用的耗时
+}
+
+// Interceptor 拦截一次调用，必须调用 next 才会真正执行调用，并应当返回 next 的错误
+type Interceptor func(call *Call, next func() error) error
+
+// Chain 将多个拦截器组合为一个，按顺序由外向内执行
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(call *Call, next func() error) error {
+		return run(interceptors, call, next)
+	}
+}
+
+// Invoke 使用拦截器执行 fn，并设置 call 的 Start 与 Latency
+func Invoke(interceptors []Interceptor, call *Call, fn func() error) error {
+	call.Start = time.Now()
+	return run(interceptors, call, func() error {
+		err := fn()
+		call.Latency = time.Since(call.Start)
+		return err
+	})
+}
+
+func run(interceptors []Interceptor, call *Call, next func() error) error {
+	if len(interceptors) == 0 {
+		return next()
+	}
+	return interceptors[0](call, func() error {
+		return run(interceptors[1:], call, next)
+	})
+}
+
+// Endpoint 返回节点地址中的 scheme 与 host，解析失败时返回空字符串
+func Endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// payloadSize 返回 v 经过 json 编码后的字节数
+func payloadSize(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
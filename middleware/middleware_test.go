@@ -0,0 +1,188 @@
+package middleware_test
+
+import (
+	"errors"
+	"github.com/mgintoki/go-web3/jsonrpc/transport"
+	"github.com/mgintoki/multichain"
+	"github.com/mgintoki/multichain/middleware"
+	"github.com/mgintoki/multichain/testkit"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const account = "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"
+
+// recorder 记录经过拦截器的调用
+type recorder struct {
+	mu    sync.Mutex
+	calls []middleware.Call
+	errs  []error
+}
+
+func (r *recorder) intercept(call *middleware.Call, next func() error) error {
+	err := next()
+	r.mu.Lock()
+	r.calls = append(r.calls, *call)
+	r.errs = append(r.errs, err)
+	r.mu.Unlock()
+	return err
+}
+
+func TestWrapTransport(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_blockNumber", "0x10").RespondError("eth_chainId", -32000, "boom")
+
+	var order []string
+	trace := func(name string) middleware.Interceptor {
+		return func(call *middleware.Call, next func() error) error {
+			order = append(order, name+">")
+			err := next()
+			order = append(order, "<"+name)
+			return err
+		}
+	}
+	rec := &recorder{}
+	inner, err := transport.NewTransport(server.URL() + "/v3/secret-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := middleware.WrapTransport(inner, "local", server.URL()+"/v3/secret-key", trace("a"), trace("b"), rec.intercept)
+
+	var num string
+	if err := c.Call("eth_blockNumber", &num); err != nil || num != "0x10" {
+		t.Fatalf("unexpected block number %v %v", num, err)
+	}
+	if strings.Join(order, " ") != "a> b> <b <a" {
+		t.Fatalf("unexpected interceptor order %v", order)
+	}
+	var chainID string
+	if err := c.Call("eth_chainId", &chainID); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expect rpc error, got %v", err)
+	}
+
+	if len(rec.calls) != 2 {
+		t.Fatalf("expect 2 calls, got %+v", rec.calls)
+	}
+	call := rec.calls[0]
+	if call.Kind != middleware.KindRPC || call.Chain != "local" || call.Method != "eth_blockNumber" || rec.errs[0] != nil {
+		t.Fatalf("unexpected call %+v", call)
+	}
+	if call.Endpoint != server.URL() {
+		t.Fatalf("endpoint should not contain the path, got %s", call.Endpoint)
+	}
+	if call.RequestSize != 0 || call.ResponseSize != len(`"0x10"`) || call.Latency <= 0 {
+		t.Fatalf("unexpected call size or latency %+v", call)
+	}
+	if rec.calls[1].Method != "eth_chainId" || rec.errs[1] == nil {
+		t.Fatalf("unexpected call %+v %v", rec.calls[1], rec.errs[1])
+	}
+}
+
+func TestClientInterceptors(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_getBalance", "0x64")
+
+	rec := &recorder{}
+	p := server.Provider()
+	p.Interceptors = []middleware.Interceptor{rec.intercept}
+	cli, err := multichain.NewClient(multichain.TypeBinance, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := cli.BalanceOf(account, nil)
+	if err != nil || balance.Int64() != 100 {
+		t.Fatalf("unexpected balance %v %v", balance, err)
+	}
+	if _, err := cli.GetChainID(); err == nil {
+		t.Fatal("expect error for unscripted method")
+	}
+
+	var methods []string
+	for i, call := range rec.calls {
+		if call.Chain != "binance" {
+			t.Fatalf("unexpected chain %+v", call)
+		}
+		methods = append(methods, call.Kind+":"+call.Method)
+		if call.Method == "GetChainID" && rec.errs[i] == nil {
+			t.Fatal("expect client error to be recorded")
+		}
+	}
+	expect := "rpc:eth_getBalance client:BalanceOf rpc:eth_chainId client:GetChainID"
+	if strings.Join(methods, " ") != expect {
+		t.Fatalf("expect calls %s, got %v", expect, methods)
+	}
+}
+
+type counter struct{ labels [][]string }
+
+func (c *counter) Inc(labels ...string) { c.labels = append(c.labels, labels) }
+
+type span struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *span) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *span) RecordError(err error)                      { s.err = err }
+func (s *span) End()                                       { s.ended = true }
+
+type tracer struct{ spans []*span }
+
+func (t *tracer) Start(name string) middleware.Span {
+	s := &span{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+	return s
+}
+
+type logger struct{ debug, errors []string }
+
+func (l *logger) Debug(msg string, args ...interface{}) { l.debug = append(l.debug, msg) }
+func (l *logger) Error(msg string, args ...interface{}) { l.errors = append(l.errors, msg) }
+
+func TestAdapters(t *testing.T) {
+	requests := &counter{}
+	var latencies, sizes []float64
+	tr := &tracer{}
+	lg := &logger{}
+	interceptor := middleware.Chain(
+		middleware.Metrics(middleware.MetricsConfig{
+			Requests:    requests,
+			Latency:     middleware.HistogramFunc(func(v float64, labels ...string) { latencies = append(latencies, v) }),
+			RequestSize: middleware.HistogramFunc(func(v float64, labels ...string) { sizes = append(sizes, v) }),
+		}),
+		middleware.Logging(lg),
+		middleware.Tracing(tr),
+	)
+
+	failure := errors.New("failure")
+	call := &middleware.Call{Kind: middleware.KindRPC, Chain: "ethereum", Endpoint: "http://node", Method: "eth_call", RequestSize: 42}
+	if err := middleware.Invoke([]middleware.Interceptor{interceptor}, call, func() error { return failure }); err != failure {
+		t.Fatalf("expect error to be returned, got %v", err)
+	}
+	call = &middleware.Call{Kind: middleware.KindClient, Chain: "ethereum", Method: "Transfer"}
+	if err := middleware.Invoke([]middleware.Interceptor{interceptor}, call, func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests.labels) != 2 || strings.Join(requests.labels[0], ",") != "rpc,ethereum,http://node,eth_call,error" || requests.labels[1][4] != "ok" {
+		t.Fatalf("unexpected counter labels %v", requests.labels)
+	}
+	if len(latencies) != 2 || len(sizes) != 1 || sizes[0] != 42 {
+		t.Fatalf("unexpected histograms %v %v", latencies, sizes)
+	}
+	if len(lg.errors) != 1 || len(lg.debug) != 1 {
+		t.Fatalf("unexpected logs %+v", lg)
+	}
+	if len(tr.spans) != 2 || tr.spans[0].name != "eth_call" || tr.spans[1].name != "Client.Transfer" {
+		t.Fatalf("unexpected spans %+v", tr.spans)
+	}
+	if s := tr.spans[0]; s.err != failure || !s.ended || s.attrs["multichain.request_size"] != 42 || s.attrs["multichain.chain"] != "ethereum" {
+		t.Fatalf("unexpected span %+v", s)
+	}
+}
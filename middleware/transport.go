@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/mgintoki/go-web3/jsonrpc/transport"
+)
+
+// WrapTransport 包装 transport.Transport，每次请求时执行拦截器，没有拦截器时原样返回
+// 支持订阅的 transport 包装后仍然支持订阅，订阅本身不经过拦截器
+func WrapTransport(inner transport.Transport, chain, endpoint string, interceptors ...Interceptor) transport.Transport {
+	if len(interceptors) == 0 {
+		return inner
+	}
+	t := &rpcTransport{
+		inner:        inner,
+		chain:        chain,
+		endpoint:     Endpoint(endpoint),
+		interceptors: interceptors,
+	}
+	if pub, ok := inner.(transport.PubSubTransport); ok {
+		return &pubSubTransport{rpcTransport: t, pub: pub}
+	}
+	return t
+}
+
+// rpcTransport 包装 transport.Transport，在每次请求时执行拦截器
+type rpcTransport struct {
+	inner        transport.Transport
+	chain        string
+	endpoint     string
+	interceptors []Interceptor
+}
+
+func (t *rpcTransport) Call(method string, out interface{}, params ...interface{}) error {
+	call := &Call{
+		Kind:     KindRPC,
+		Chain:    t.chain,
+		Endpoint: t.endpoint,
+		Method:   method,
+	}
+	if len(params) != 0 {
+		call.RequestSize = payloadSize(params)
+	}
+	return Invoke(t.interceptors, call, func() error {
+		// 先取得原始的返回结果以统计大小，再解析到 out
+		var raw json.RawMessage
+		if err := t.inner.Call(method, &raw, params...); err != nil {
+			return err
+		}
+		call.ResponseSize = len(raw)
+		return json.Unmarshal(raw, out)
+	})
+}
+
+func (t *rpcTransport) Close() error {
+	return t.inner.Close()
+}
+
+// pubSubTransport 用于 websocket 等支持订阅的 transport，订阅本身不经过拦截器
+type pubSubTransport struct {
+	*rpcTransport
+	pub transport.PubSubTransport
+}
+
+func (t *pubSubTransport) Subscribe(method string, callback func(b []byte)) (func() error, error) {
+	return t.pub.Subscribe(method, callback)
+}
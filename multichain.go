@@ -10,6 +10,7 @@ import (
 	"github.com/mgintoki/multichain/chain/ethereum"
 	"github.com/mgintoki/multichain/chain/okex"
 	"github.com/mgintoki/multichain/errno"
+	"strings"
 )
 
 const (
@@ -18,8 +19,39 @@ const (
 	TypeOKEx     = 3 // okex
 )
 
+// chainNames 是链类型的名称，用于 provider.CommonProvider 的 Chain 为空时
+var chainNames = map[uint]string{
+	TypeEthereum: "ethereum",
+	TypeBinance:  "binance",
+	TypeOKEx:     "okex",
+}
+
+// withChainName 在 provider 未设置链名称时，使用链类型的名称
+func withChainName(chainType uint, provider provider.CommonProvider) provider.CommonProvider {
+	if provider.Chain == "" {
+		provider.Chain = chainNames[chainType]
+	}
+	return provider
+}
+
+// ParseChainType 将链名称转换为链类型，支持 ethereum (eth)、binance (bsc, bnb) 与 okex (okc, okt)，也可以直接使用链类型的数字
+func ParseChainType(name string) (uint, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ethereum", "eth", "1":
+		return TypeEthereum, nil
+	case "binance", "bsc", "bnb", "2":
+		return TypeBinance, nil
+	case "okex", "okc", "okt", "3":
+		return TypeOKEx, nil
+	}
+	return 0, errno.NotSupportChainType.Add(name)
+}
+
 // NewClient 新建一个多链客户端
+// provider 设置了 Interceptors 时，返回的 Client 会使用 WrapClient 包装，
+// 此时需要通过 Unwrap 取得各链的 Client 才能使用 SetSigner、SignMessage、SignTypedData、ExportKeystore 以及 Safe、UserOperation 等各链特有的方法
 func NewClient(chainType uint, provider provider.CommonProvider) (client.Client, error) {
+	provider = withChainName(chainType, provider)
 	var cli client.Client
 	var err error
 	switch chainType {
@@ -38,7 +70,7 @@ func NewClient(chainType uint, provider provider.CommonProvider) (client.Client,
 	if cli == nil {
 		return nil, errno.NotSupportChainType
 	} else {
-		return cli, nil
+		return WrapClient(cli, provider), nil
 	}
 }
 
@@ -58,6 +90,7 @@ func NewAddressManager(chainType uint) (address.Encoder, error) {
 
 // NewTxBuilder 新建一个交易构造器
 func NewTxBuilder(chainType uint, provider provider.CommonProvider) (txbuilder.TxBuilder, error) {
+	provider = withChainName(chainType, provider)
 	switch chainType {
 	case TypeEthereum:
 		builder, err := ethereum.NewTxBuilder(provider)
@@ -77,6 +110,7 @@ func NewTxBuilder(chainType uint, provider provider.CommonProvider) (txbuilder.T
 
 // NewContractTxBuilder 新建一个合约相关类型的交易构造器
 func NewContractTxBuilder(chainType uint, provider provider.CommonProvider) (txbuilder.ContractTxBuilder, error) {
+	provider = withChainName(chainType, provider)
 	switch chainType {
 	case TypeEthereum:
 		builder, err := ethereum.NewContractTxBuilder(provider)
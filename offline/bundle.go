@@ -0,0 +1,287 @@
+// Package offline 实现了离线 (冷钱包) 签名流程
+//
+// 1. 在线机器构建交易，使用 NewBundle 导出待签名交易包，并使用 Fragments 将其拆分为适合二维码的分片
+// 2. 离线机器使用 Assembler 收集分片，使用 Review 校验交易内容与待签名hash一致，然后使用 Sign 签名
+// 3. 离线机器将签名集合同样拆分为分片，在线机器收集后使用 Apply 将签名注入交易，再广播
+//
+// 整个流程只依赖 tx.Tx 的 GetTxHash、SignHash、InjectSignature 与 TxBuilder.DecodeTx，离线机器不需要 Provider
+package offline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/errno"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// Version 是待签名交易包与签名集合二进制格式的当前版本
+	Version = 1
+
+	kindBundle     = 1
+	kindSignatures = 2
+
+	encodingRaw = 0 // EncodeTx 的输出原样保存
+	encodingHex = 1 // EncodeTx 的输出为16进制，解码后保存以减小体积
+)
+
+var magic = []byte("MCOS")
+
+// Item 是待签名交易包中的一个交易
+type Item struct {
+	EncodedTx string // EncodeTx 的输出
+	Hash      []byte // GetTxHash 的结果，即需要被签名的hash
+}
+
+// Bundle 是在线机器导出的待签名交易包
+//
+// 二进制格式 (整数均为 uvarint，bytes 为 uvarint 长度前缀 + 内容):
+//
+//	"MCOS" | version(1B) | kind=1(1B) | chainType | chainID(bytes) | n |
+//	n * (encoding(1B) | encodedTx(bytes) | hash(bytes)) | crc32(4B, big endian)
+type Bundle struct {
+	ChainType uint   // 链类型，见 multichain.TypeEthereum 等定义
+	ChainID   string // 签名使用的链ID
+	Items     []Item
+}
+
+// Signatures 是离线机器对待签名交易包的签名结果
+//
+// 二进制格式:
+//
+//	"MCOS" | version(1B) | kind=2(1B) | bundleID(32B) | n | n * signature(bytes) | crc32(4B, big endian)
+type Signatures struct {
+	BundleID   [32]byte // 对应的待签名交易包的ID，见 Bundle.ID
+	Signatures [][]byte // 与 Bundle.Items 一一对应的签名
+}
+
+// NewBundle 使用在线机器构建好的交易新建待签名交易包
+func NewBundle(chainType uint, chainID string, txs ...tx.Tx) (*Bundle, error) {
+	if len(txs) == 0 {
+		return nil, errno.InvalidOfflineBundle.Add("no tx")
+	}
+	b := &Bundle{ChainType: chainType, ChainID: chainID}
+	for _, t := range txs {
+		encoded, err := t.EncodeTx()
+		if err != nil {
+			return nil, err
+		}
+		hexHash, err := t.GetTxHash(chainID)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil {
+			return nil, err
+		}
+		b.Items = append(b.Items, Item{EncodedTx: encoded, Hash: hash})
+	}
+	return b, nil
+}
+
+// ID 返回待签名交易包的ID，即其二进制格式的 sha256
+func (b *Bundle) ID() ([32]byte, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// MarshalBinary 将待签名交易包编码为二进制格式
+func (b *Bundle) MarshalBinary() ([]byte, error) {
+	w := newWriter(kindBundle)
+	w.uvarint(uint64(b.ChainType))
+	w.bytes([]byte(b.ChainID))
+	w.uvarint(uint64(len(b.Items)))
+	for _, item := range b.Items {
+		if raw, err := hex.DecodeString(item.EncodedTx); err == nil && hex.EncodeToString(raw) == item.EncodedTx {
+			w.buf.WriteByte(encodingHex)
+			w.bytes(raw)
+		} else {
+			w.buf.WriteByte(encodingRaw)
+			w.bytes([]byte(item.EncodedTx))
+		}
+		w.bytes(item.Hash)
+	}
+	return w.finish(), nil
+}
+
+// UnmarshalBinary 解析二进制格式的待签名交易包
+func (b *Bundle) UnmarshalBinary(data []byte) error {
+	r, err := newReader(data, kindBundle)
+	if err != nil {
+		return err
+	}
+	chainType := r.uvarint()
+	chainID := r.bytes()
+	n := r.uvarint()
+	if r.err == nil && n > uint64(r.Len()) {
+		r.err = fmt.Errorf("invalid item count %d", n)
+	}
+	var items []Item
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		var item Item
+		encoding := r.u8()
+		encoded := r.bytes()
+		switch encoding {
+		case encodingHex:
+			item.EncodedTx = hex.EncodeToString(encoded)
+		case encodingRaw:
+			item.EncodedTx = string(encoded)
+		default:
+			r.fail(fmt.Errorf("invalid encoding %d", encoding))
+		}
+		item.Hash = r.bytes()
+		items = append(items, item)
+	}
+	if err := r.close(); err != nil {
+		return errno.InvalidOfflineBundle.Add(err.Error())
+	}
+
+	b.ChainType = uint(chainType)
+	b.ChainID = string(chainID)
+	b.Items = items
+	return nil
+}
+
+// MarshalBinary 将签名集合编码为二进制格式
+func (s *Signatures) MarshalBinary() ([]byte, error) {
+	w := newWriter(kindSignatures)
+	w.buf.Write(s.BundleID[:])
+	w.uvarint(uint64(len(s.Signatures)))
+	for _, sig := range s.Signatures {
+		w.bytes(sig)
+	}
+	return w.finish(), nil
+}
+
+// UnmarshalBinary 解析二进制格式的签名集合
+func (s *Signatures) UnmarshalBinary(data []byte) error {
+	r, err := newReader(data, kindSignatures)
+	if err != nil {
+		return err
+	}
+	var id [32]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		r.fail(err)
+	}
+	n := r.uvarint()
+	if r.err == nil && n > uint64(r.Len()) {
+		r.err = fmt.Errorf("invalid signature count %d", n)
+	}
+	var sigs [][]byte
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		sigs = append(sigs, r.bytes())
+	}
+	if err := r.close(); err != nil {
+		return errno.InvalidOfflineBundle.Add(err.Error())
+	}
+
+	s.BundleID = id
+	s.Signatures = sigs
+	return nil
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+func newWriter(kind byte) *writer {
+	w := &writer{}
+	w.buf.Write(magic)
+	w.buf.WriteByte(Version)
+	w.buf.WriteByte(kind)
+	return w
+}
+
+func (w *writer) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *writer) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf.Write(b)
+}
+
+func (w *writer) finish() []byte {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(w.buf.Bytes()))
+	w.buf.Write(sum[:])
+	return w.buf.Bytes()
+}
+
+// reader 在遇到第一个错误后停止读取，错误在 close 时返回
+type reader struct {
+	*bytes.Reader
+	err error
+}
+
+func newReader(data []byte, kind byte) (*reader, error) {
+	if len(data) < len(magic)+2+4 || !bytes.Equal(data[:len(magic)], magic) {
+		return nil, errno.InvalidOfflineBundle.Add("unknown format")
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(sum) {
+		return nil, errno.InvalidOfflineBundle.Add("checksum mismatch")
+	}
+	if v := body[len(magic)]; v == 0 || v > Version {
+		return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("unsupported version %d", v))
+	}
+	if k := body[len(magic)+1]; k != kind {
+		return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("expect kind %d, got %d", kind, k))
+	}
+	return &reader{Reader: bytes.NewReader(body[len(magic)+2:])}, nil
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *reader) u8() byte {
+	if r.err != nil {
+		return 0
+	}
+	b, err := r.ReadByte()
+	r.fail(err)
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r)
+	r.fail(err)
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(r.Len()) {
+		r.fail(fmt.Errorf("length %d exceeds remaining %d bytes", n, r.Len()))
+		return nil
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	r.fail(err)
+	return b
+}
+
+func (r *reader) close() error {
+	if r.err == nil && r.Len() != 0 {
+		r.err = fmt.Errorf("%d trailing bytes", r.Len())
+	}
+	return r.err
+}
@@ -0,0 +1,191 @@
+package offline
+
+import (
+	"encoding/base32"
+	"fmt"
+	"github.com/mgintoki/multichain/errno"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TypeBundle 是待签名交易包分片的类型
+	TypeBundle = "MC-BUNDLE"
+	// TypeSignatures 是签名集合分片的类型
+	TypeSignatures = "MC-SIGS"
+
+	// DefaultFragmentLen 是默认每个分片携带的字节数，编码后约 200 个字符，适合低版本号的二维码
+	DefaultFragmentLen = 120
+
+	// maxFragments 是单个数据最多的分片数，分片总数来自扫码得到的不可信文本，需要限制以免按总数分配过多内存
+	maxFragments = 4096
+)
+
+// 分片只使用大写字母、数字和 ":/-"，可以使用二维码的 alphanumeric 模式编码，密度更高
+var fragmentEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EncodeFragments 将数据拆分为 UR 风格的分片:
+//
+//	UR:<type>/<seq>-<total>/<checksum>/<base32 data>
+//
+// seq 从1开始，checksum 是完整数据的 crc32 (8位16进制)，用于识别属于同一数据的分片
+// 分片可以作为动态二维码循环展示，接收方以任意顺序收集即可
+// maxLen 为每个分片携带的最大字节数，小于等于0时使用 DefaultFragmentLen，分片数超过接收方的上限时会自动增大
+func EncodeFragments(urType string, data []byte, maxLen int) []string {
+	if maxLen <= 0 {
+		maxLen = DefaultFragmentLen
+	}
+	if minLen := (len(data) + maxFragments - 1) / maxFragments; maxLen < minLen {
+		maxLen = minLen
+	}
+	total := (len(data) + maxLen - 1) / maxLen
+	if total == 0 {
+		total = 1
+	}
+	checksum := fmt.Sprintf("%08X", crc32.ChecksumIEEE(data))
+
+	parts := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * maxLen
+		if end > len(data) {
+			end = len(data)
+		}
+		parts = append(parts, fmt.Sprintf("UR:%s/%d-%d/%s/%s",
+			strings.ToUpper(urType), i+1, total, checksum, fragmentEncoding.EncodeToString(data[i*maxLen:end])))
+	}
+	return parts
+}
+
+// Fragments 将待签名交易包拆分为分片
+func (b *Bundle) Fragments(maxLen int) ([]string, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeFragments(TypeBundle, data, maxLen), nil
+}
+
+// Fragments 将签名集合拆分为分片
+func (s *Signatures) Fragments(maxLen int) ([]string, error) {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeFragments(TypeSignatures, data, maxLen), nil
+}
+
+// Assembler 收集 EncodeFragments 输出的分片并还原数据
+// 重复的分片会被忽略，不属于同一数据的分片会返回错误
+type Assembler struct {
+	urType   string
+	checksum string
+	parts    [][]byte
+	received int
+}
+
+// NewAssembler 新建一个分片收集器
+func NewAssembler() *Assembler {
+	return &Assembler{}
+}
+
+// Add 添加一个分片 (不区分大小写)，所有分片收集完成后返回 true
+func (a *Assembler) Add(fragment string) (complete bool, err error) {
+	fields := strings.Split(strings.ToUpper(strings.TrimSpace(fragment)), "/")
+	if len(fields) != 4 || !strings.HasPrefix(fields[0], "UR:") {
+		return false, errno.InvalidFragment.Add("malformed fragment")
+	}
+	urType, checksum := strings.TrimPrefix(fields[0], "UR:"), fields[2]
+
+	seqTotal := strings.SplitN(fields[1], "-", 2)
+	if len(seqTotal) != 2 {
+		return false, errno.InvalidFragment.Add("malformed sequence " + fields[1])
+	}
+	seq, err1 := strconv.Atoi(seqTotal[0])
+	total, err2 := strconv.Atoi(seqTotal[1])
+	if err1 != nil || err2 != nil || total <= 0 || seq <= 0 || seq > total {
+		return false, errno.InvalidFragment.Add("malformed sequence " + fields[1])
+	}
+	if total > maxFragments {
+		return false, errno.InvalidFragment.Add(fmt.Sprintf("too many fragments %d, max %d", total, maxFragments))
+	}
+
+	data, err := fragmentEncoding.DecodeString(fields[3])
+	if err != nil {
+		return false, errno.InvalidFragment.Add(err.Error())
+	}
+
+	if a.parts == nil {
+		a.urType, a.checksum, a.parts = urType, checksum, make([][]byte, total)
+	} else if urType != a.urType || checksum != a.checksum || total != len(a.parts) {
+		return false, errno.InvalidFragment.Add(fmt.Sprintf("fragment %s/%s does not belong to %s/%s", urType, checksum, a.urType, a.checksum))
+	}
+
+	if a.parts[seq-1] == nil {
+		a.parts[seq-1] = data
+		a.received++
+	}
+	return a.Complete(), nil
+}
+
+// Complete 返回是否已经收集了所有分片
+func (a *Assembler) Complete() bool {
+	return a.parts != nil && a.received == len(a.parts)
+}
+
+// Progress 返回已收集的分片数与分片总数
+func (a *Assembler) Progress() (received int, total int) {
+	return a.received, len(a.parts)
+}
+
+// Type 返回分片的类型，如 TypeBundle
+func (a *Assembler) Type() string {
+	return a.urType
+}
+
+// Data 返回还原后的数据
+func (a *Assembler) Data() ([]byte, error) {
+	if !a.Complete() {
+		return nil, errno.InvalidFragment.Add(fmt.Sprintf("received %d of %d fragments", a.received, len(a.parts)))
+	}
+	var data []byte
+	for _, part := range a.parts {
+		data = append(data, part...)
+	}
+	if checksum := fmt.Sprintf("%08X", crc32.ChecksumIEEE(data)); checksum != a.checksum {
+		return nil, errno.InvalidFragment.Add("checksum mismatch")
+	}
+	return data, nil
+}
+
+// Bundle 将还原后的数据解析为待签名交易包
+func (a *Assembler) Bundle() (*Bundle, error) {
+	if a.urType != TypeBundle {
+		return nil, errno.InvalidFragment.Add(fmt.Sprintf("expect %s, got %s", TypeBundle, a.urType))
+	}
+	data, err := a.Data()
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{}
+	if err := b.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Signatures 将还原后的数据解析为签名集合
+func (a *Assembler) Signatures() (*Signatures, error) {
+	if a.urType != TypeSignatures {
+		return nil, errno.InvalidFragment.Add(fmt.Sprintf("expect %s, got %s", TypeSignatures, a.urType))
+	}
+	data, err := a.Data()
+	if err != nil {
+		return nil, err
+	}
+	s := &Signatures{}
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
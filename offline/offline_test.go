@@ -0,0 +1,127 @@
+package offline
+
+import (
+	"errors"
+	"github.com/mgintoki/go-web3"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/chain/ethereum"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"testing"
+)
+
+func TestOfflineSigning(t *testing.T) {
+	const private = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	signer, err := ethereum.NewLocalSignerFromHex(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := web3.HexToAddress("0x3F43E75Aaba2c2fD6E227C10C6E7DC125A93DE3c")
+	var txs []tx.Tx
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		txs = append(txs, &ethereum.Txn{
+			From:     signer.Address(),
+			Addr:     &to,
+			Nonce:    nonce,
+			Value:    big.NewInt(1000),
+			GasPrice: 5,
+			GasLimit: 21000,
+		})
+	}
+
+	// 在线机器
+	bundle, err := NewBundle(1, "56", txs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments, err := bundle.Fragments(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fragments) < 2 {
+		t.Fatalf("expect multiple fragments, got %d", len(fragments))
+	}
+
+	// 离线机器，分片乱序且重复到达
+	dec := &ethereum.TxBuilder{}
+	assembler := NewAssembler()
+	for i := len(fragments) - 1; i >= 0; i-- {
+		if _, err := assembler.Add(fragments[i]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := assembler.Add(fragments[len(fragments)-1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received, err := assembler.Bundle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs, err := Sign(received, dec, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigFragments, err := sigs.Fragments(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 在线机器
+	assembler = NewAssembler()
+	for _, f := range sigFragments {
+		if _, err := assembler.Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receivedSigs, err := assembler.Signatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := Apply(bundle, receivedSigs, dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range signed {
+		if err := s.(*ethereum.Txn).VerifySignature("56"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 被篡改的交易包
+	bundle.Items[1].Hash = bundle.Items[0].Hash
+	if _, err := Sign(bundle, dec, private); err == nil {
+		t.Fatal("expect hash mismatch")
+	}
+	if _, err := Apply(bundle, receivedSigs, dec); err == nil {
+		t.Fatal("expect bundle id mismatch")
+	}
+}
+
+func TestAssemblerLimits(t *testing.T) {
+	a := NewAssembler()
+	if _, err := a.Add("UR:MC-BUNDLE/1-2000000000/00000000/AE"); !errors.Is(err, errno.InvalidFragment) {
+		t.Fatalf("expect InvalidFragment for a huge total, got %v", err)
+	}
+	if _, total := a.Progress(); total != 0 {
+		t.Fatalf("expect rejected fragment not to be kept, got total %d", total)
+	}
+
+	// 分片数超过上限时编码方自动增大每个分片的长度
+	data := make([]byte, maxFragments*2+1)
+	fragments := EncodeFragments(TypeBundle, data, 1)
+	if len(fragments) > maxFragments {
+		t.Fatalf("expect at most %d fragments, got %d", maxFragments, len(fragments))
+	}
+	for _, f := range fragments {
+		if _, err := a.Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := a.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(data) {
+		t.Fatalf("expect %d bytes, got %d", len(data), len(got))
+	}
+}
@@ -0,0 +1,104 @@
+package offline
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/errno"
+)
+
+// TxDecoder 解析 EncodeTx 输出的交易，txbuilder.TxBuilder 均实现了该接口
+// 离线机器上可以直接使用未设置 Provider 的 TxBuilder，如 &ethereum.TxBuilder{}
+type TxDecoder interface {
+	DecodeTx(encodedTx string) (tx tx.Tx, err error)
+}
+
+// HashSigner 对交易的待签名hash签名，返回16进制格式的签名
+// 签名算法应当与交易所属链的 Tx.SignHash 一致
+type HashSigner func(t tx.Tx, chainID string, hexHash string) (hexSignature string, err error)
+
+// Review 解析待签名交易包中的交易，用于在离线机器上展示并确认交易内容
+// 每个交易都会重新计算待签名hash，并与交易包中的hash比较，保证签名的就是展示的交易
+func Review(b *Bundle, dec TxDecoder) ([]tx.Tx, error) {
+	txs := make([]tx.Tx, 0, len(b.Items))
+	for i, item := range b.Items {
+		t, err := dec.DecodeTx(item.EncodedTx)
+		if err != nil {
+			return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("tx %d: %s", i, err))
+		}
+		hexHash, err := t.GetTxHash(b.ChainID)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(hash, item.Hash) {
+			return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("tx %d: hash %x does not match tx content", i, item.Hash))
+		}
+		txs = append(txs, t)
+	}
+	return txs, nil
+}
+
+// Sign 在离线机器上使用私钥对待签名交易包中的所有交易签名
+func Sign(b *Bundle, dec TxDecoder, privateHex string) (*Signatures, error) {
+	return SignWith(b, dec, func(t tx.Tx, chainID string, hexHash string) (string, error) {
+		return t.SignHash(privateHex, chainID, hexHash)
+	})
+}
+
+// SignWith 在离线机器上使用 HashSigner 对待签名交易包中的所有交易签名
+// 签名前会先执行 Review 的校验
+func SignWith(b *Bundle, dec TxDecoder, signer HashSigner) (*Signatures, error) {
+	txs, err := Review(b, dec)
+	if err != nil {
+		return nil, err
+	}
+	id, err := b.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := &Signatures{BundleID: id}
+	for i, t := range txs {
+		hexSig, err := signer(t, b.ChainID, hex.EncodeToString(b.Items[i].Hash))
+		if err != nil {
+			return nil, err
+		}
+		sig, err := hex.DecodeString(hexSig)
+		if err != nil {
+			return nil, err
+		}
+		sigs.Signatures = append(sigs.Signatures, sig)
+	}
+	return sigs, nil
+}
+
+// Apply 在在线机器上将签名集合注入待签名交易包中的交易，返回已签名、可以广播的交易
+// 签名集合必须是对该交易包的签名，签名的数量必须与交易数量一致
+func Apply(b *Bundle, sigs *Signatures, dec TxDecoder) ([]tx.Tx, error) {
+	id, err := b.ID()
+	if err != nil {
+		return nil, err
+	}
+	if id != sigs.BundleID {
+		return nil, errno.InvalidOfflineBundle.Add("signatures do not belong to this bundle")
+	}
+	if len(sigs.Signatures) != len(b.Items) {
+		return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("expect %d signatures, got %d", len(b.Items), len(sigs.Signatures)))
+	}
+
+	txs, err := Review(b, dec)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range txs {
+		if err := t.InjectSignature(hex.EncodeToString(sigs.Signatures[i]), b.ChainID); err != nil {
+			return nil, errno.InvalidOfflineBundle.Add(fmt.Sprintf("tx %d: %s", i, err))
+		}
+	}
+	return txs, nil
+}
@@ -0,0 +1,310 @@
+// Package conformance 是 client.Client、txbuilder.TxBuilder 与 address.Encoder 实现的一致性测试
+//
+// 新增链的实现可以在本地测试链 (如 chain/ethereum/simulated) 上运行 Run，检查实现是否符合 api 包中接口的约定：
+//
+//	func TestConformance(t *testing.T) {
+//		backend, _ := simulated.New(simulated.WithManualMining())
+//		defer backend.Close()
+//		conformance.Run(t, conformance.Chain{
+//			NewClient:    func(p provider.CommonProvider) (client.Client, error) { return ethereum.NewClient(p) },
+//			NewTxBuilder: func(p provider.CommonProvider) (txbuilder.TxBuilder, error) { return ethereum.NewTxBuilder(p) },
+//			Encoder:      ethereum.NewAddressEncoder(),
+//			Provider:     backend.Provider(),
+//			ChainID:      "1337",
+//			Accounts:     accounts,
+//			Mine:         backend.Mine,
+//		})
+//	}
+package conformance
+
+import (
+	"errors"
+	"github.com/mgintoki/multichain/api/address"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/fee"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/api/tx"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/common"
+	"github.com/mgintoki/multichain/errno"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Account 是测试链上有余额的账户
+type Account struct {
+	Address    string
+	PrivateKey string
+}
+
+// Chain 描述被测试的链实现与测试链
+type Chain struct {
+	NewClient    func(provider provider.CommonProvider) (client.Client, error)
+	NewTxBuilder func(provider provider.CommonProvider) (txbuilder.TxBuilder, error)
+	Encoder      address.Encoder
+
+	Provider provider.CommonProvider // 测试链的服务地址
+	ChainID  string                  // 测试链的链ID
+	Accounts []Account               // 至少两个有余额的账户，测试会在账户之间转账
+
+	// Mine 将 pending 的交易打包出块，为nil时认为测试链在交易发送后自动出块，不检查交易 pending 状态
+	Mine func()
+
+	// Addresses 是额外用于检查地址格式转换的地址
+	Addresses []string
+}
+
+// Run 运行一致性测试
+func Run(t *testing.T, c Chain) {
+	if len(c.Accounts) < 2 {
+		t.Fatal("conformance: at least 2 funded accounts are required")
+	}
+	t.Run("Address", func(t *testing.T) { testAddress(t, c) })
+	t.Run("ChainID", func(t *testing.T) { testChainID(t, c) })
+	t.Run("SignFlow", func(t *testing.T) { testSignFlow(t, c) })
+	t.Run("EstimateGas", func(t *testing.T) { testEstimateGas(t, c) })
+	t.Run("QueryTx", func(t *testing.T) { testQueryTx(t, c) })
+	t.Run("Errors", func(t *testing.T) { testErrors(t, c) })
+}
+
+func (c Chain) client(t *testing.T, account *Account) client.Client {
+	t.Helper()
+	cli, err := c.NewClient(c.Provider)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if account != nil {
+		if err := cli.SetPrivate(account.PrivateKey); err != nil {
+			t.Fatalf("SetPrivate: %v", err)
+		}
+	}
+	return cli
+}
+
+func (c Chain) txBuilder(t *testing.T) txbuilder.TxBuilder {
+	t.Helper()
+	builder, err := c.NewTxBuilder(c.Provider)
+	if err != nil {
+		t.Fatalf("NewTxBuilder: %v", err)
+	}
+	return builder
+}
+
+// transfer 构建从 Accounts[0] 到 Accounts[1] 的转账交易
+func (c Chain) transfer(t *testing.T, amount int64) tx.Tx {
+	t.Helper()
+	txn, err := c.txBuilder(t).BuildTx(txbuilder.BuildTxParam{
+		From:  c.Accounts[0].Address,
+		To:    c.Accounts[1].Address,
+		Value: big.NewInt(amount),
+	})
+	if err != nil {
+		t.Fatalf("BuildTx: %v", err)
+	}
+	return txn
+}
+
+func (c Chain) mine() {
+	if c.Mine != nil {
+		c.Mine()
+	}
+}
+
+func sameAddress(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+func testAddress(t *testing.T, c Chain) {
+	addrs := append([]string(nil), c.Addresses...)
+	for _, a := range c.Accounts {
+		addrs = append(addrs, a.Address)
+	}
+	for _, a := range addrs {
+		if got := c.Encoder.AddressToHex(c.Encoder.HexToAddress(a)); !sameAddress(got, a) {
+			t.Errorf("address round trip: expect %s, got %s", a, got)
+		}
+	}
+
+	cli := c.client(t, &c.Accounts[0])
+	if account := cli.GetAccount(); !sameAddress(account, c.Accounts[0].Address) {
+		t.Errorf("GetAccount: expect %s, got %s", c.Accounts[0].Address, account)
+	}
+}
+
+func testChainID(t *testing.T, c Chain) {
+	chainID, err := c.client(t, nil).GetChainID()
+	if err != nil {
+		t.Fatalf("GetChainID: %v", err)
+	}
+	if chainID != c.ChainID {
+		t.Fatalf("GetChainID: expect %s, got %s", c.ChainID, chainID)
+	}
+}
+
+// testSignFlow 检查 SignTx 与 GetTxHash + SignHash + InjectSignature 得到相同的签名交易，
+// 且签名交易经过 EncodeTx 与 DecodeTx 后保持不变
+func testSignFlow(t *testing.T, c Chain) {
+	key := c.Accounts[0].PrivateKey
+
+	signed := c.transfer(t, 1)
+	if !sameAddress(signed.GetFrom(), c.Accounts[0].Address) || !sameAddress(signed.GetTo(), c.Accounts[1].Address) ||
+		signed.GetValue().Int64() != 1 {
+		t.Fatalf("BuildTx: unexpected tx from %s to %s value %v", signed.GetFrom(), signed.GetTo(), signed.GetValue())
+	}
+	if err := signed.SignTx(key, c.ChainID); err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+
+	injected := c.transfer(t, 1)
+	hash, err := injected.GetTxHash(c.ChainID)
+	if err != nil {
+		t.Fatalf("GetTxHash: %v", err)
+	}
+	sig, err := injected.SignHash(key, c.ChainID, hash)
+	if err != nil {
+		t.Fatalf("SignHash: %v", err)
+	}
+	if err := injected.InjectSignature(sig, c.ChainID); err != nil {
+		t.Fatalf("InjectSignature: %v", err)
+	}
+	if signed.GetHash() == "" || signed.GetHash() != injected.GetHash() {
+		t.Fatalf("GetHash: SignTx got %q, InjectSignature got %q", signed.GetHash(), injected.GetHash())
+	}
+
+	encoded, err := signed.EncodeTx()
+	if err != nil {
+		t.Fatalf("EncodeTx: %v", err)
+	}
+	encodedInjected, err := injected.EncodeTx()
+	if err != nil {
+		t.Fatalf("EncodeTx: %v", err)
+	}
+	if encoded != encodedInjected {
+		t.Fatal("EncodeTx: SignTx and InjectSignature produce different txs")
+	}
+	decoded, err := c.txBuilder(t).DecodeTx(encoded)
+	if err != nil {
+		t.Fatalf("DecodeTx: %v", err)
+	}
+	if decoded.GetHash() != signed.GetHash() || decoded.GetNonce() != signed.GetNonce() || !sameAddress(decoded.GetFrom(), signed.GetFrom()) ||
+		!sameAddress(decoded.GetTo(), signed.GetTo()) || decoded.GetValue().Cmp(signed.GetValue()) != 0 {
+		t.Fatalf("DecodeTx: decoded tx %s does not match signed tx %s", decoded.GetHash(), signed.GetHash())
+	}
+
+	// 错误的签名不能注入交易
+	if err := c.transfer(t, 1).InjectSignature("00", c.ChainID); err == nil {
+		t.Fatal("InjectSignature: expect error for invalid signature")
+	}
+}
+
+func testEstimateGas(t *testing.T, c Chain) {
+	txn := c.transfer(t, 1)
+	f, err := c.client(t, &c.Accounts[0]).EstimateGas(txn)
+	if err != nil {
+		t.Fatalf("EstimateGas: %v", err)
+	}
+	if f == nil || f.GasLimit == 0 || f.GasPrice == 0 {
+		t.Fatalf("EstimateGas: unexpected fee %+v", f)
+	}
+
+	txn.SetFee(&fee.OptionFee{GasLimit: f.GasLimit + 1, GasPrice: f.GasPrice + 1})
+	if got := txn.GetFee(); got == nil || got.GasLimit != f.GasLimit+1 || got.GasPrice != f.GasPrice+1 {
+		t.Fatalf("SetFee: unexpected fee %+v", got)
+	}
+}
+
+// testQueryTx 检查交易上链前后 QueryTx 返回的状态与交易详情
+func testQueryTx(t *testing.T, c Chain) {
+	cli := c.client(t, &c.Accounts[0])
+	before, err := cli.BalanceOf(c.Accounts[1].Address, nil)
+	if err != nil {
+		t.Fatalf("BalanceOf: %v", err)
+	}
+
+	const amount = 1000
+	txn := c.transfer(t, amount)
+	if err := txn.SignTx(c.Accounts[0].PrivateKey, c.ChainID); err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	txHash, err := cli.SendSignedTx(txn)
+	if err != nil {
+		t.Fatalf("SendSignedTx: %v", err)
+	}
+	if txHash != txn.GetHash() {
+		t.Fatalf("SendSignedTx: expect hash %s, got %s", txn.GetHash(), txHash)
+	}
+
+	if c.Mine != nil {
+		txData, err := cli.QueryTx(txHash, false)
+		if err != nil {
+			t.Fatalf("QueryTx: %v", err)
+		}
+		if txData.Status != common.TxStatusPending {
+			t.Fatalf("QueryTx: expect pending status, got %d", txData.Status)
+		}
+		c.mine()
+	}
+
+	txData, err := cli.QueryTx(txHash, true)
+	if err != nil {
+		t.Fatalf("QueryTx: %v", err)
+	}
+	if txData.Status != common.TxStatusSuccess {
+		t.Fatalf("QueryTx: expect success status, got %d", txData.Status)
+	}
+	if txData.TxHash != txHash || !sameAddress(txData.From, c.Accounts[0].Address) || !sameAddress(txData.To, c.Accounts[1].Address) ||
+		txData.Nonce != txn.GetNonce() || txData.Value == nil || txData.Value.Int64() != amount || txData.BlockNumber == 0 || txData.GasUsed == 0 {
+		t.Fatalf("QueryTx: unexpected tx data %+v", txData)
+	}
+
+	after, err := cli.BalanceOf(c.Accounts[1].Address, nil)
+	if err != nil {
+		t.Fatalf("BalanceOf: %v", err)
+	}
+	if new(big.Int).Sub(after, before).Int64() != amount {
+		t.Fatalf("BalanceOf: expect balance to increase by %d, got %s -> %s", amount, before, after)
+	}
+
+	// 交易已上链，重复发送应当失败
+	if _, err := cli.SendSignedTx(txn); err == nil {
+		t.Fatal("SendSignedTx: expect error for replayed tx")
+	}
+	if _, err := cli.QueryTx("", false); err == nil {
+		t.Fatal("QueryTx: expect error for empty hash")
+	}
+}
+
+// foreignTx 是其他链实现的交易类型
+type foreignTx struct {
+	tx.Tx
+}
+
+// testErrors 检查接口返回 errno 中定义的错误
+func testErrors(t *testing.T, c Chain) {
+	cli := c.client(t, nil)
+	txn := c.transfer(t, 1)
+	if _, err := cli.SendTx(txn, nil); !errors.Is(err, errno.PrivateNotSet) {
+		t.Errorf("SendTx without private key: expect %v, got %v", errno.PrivateNotSet, err)
+	}
+	if _, err := cli.Transfer(c.Accounts[1].Address, big.NewInt(1), nil, nil); !errors.Is(err, errno.PrivateNotSet) {
+		t.Errorf("Transfer without private key: expect %v, got %v", errno.PrivateNotSet, err)
+	}
+
+	cli = c.client(t, &c.Accounts[0])
+	if _, err := cli.SendTx(foreignTx{}, nil); !isErrno(err, errno.InvalidTxType) {
+		t.Errorf("SendTx with foreign tx: expect %v, got %v", errno.InvalidTxType, err)
+	}
+	if _, err := cli.SendSignedTx(foreignTx{}); !isErrno(err, errno.InvalidTxType) {
+		t.Errorf("SendSignedTx with foreign tx: expect %v, got %v", errno.InvalidTxType, err)
+	}
+	if _, err := cli.EstimateGas(foreignTx{}); !isErrno(err, errno.InvalidTxType) {
+		t.Errorf("EstimateGas with foreign tx: expect %v, got %v", errno.InvalidTxType, err)
+	}
+}
+
+// isErrno 判断 err 是否为 target 或由 target.Add 得到的错误
+func isErrno(err error, target *errno.Errno) bool {
+	var e *errno.Errno
+	return errors.As(err, &e) && e.State == target.State
+}
@@ -0,0 +1,59 @@
+package conformance_test
+
+import (
+	"github.com/mgintoki/multichain"
+	"github.com/mgintoki/multichain/api/client"
+	"github.com/mgintoki/multichain/api/provider"
+	"github.com/mgintoki/multichain/api/txbuilder"
+	"github.com/mgintoki/multichain/chain/ethereum/simulated"
+	"github.com/mgintoki/multichain/testkit/conformance"
+	"testing"
+)
+
+func run(t *testing.T, chainType uint, manual bool) {
+	var opts []simulated.Option
+	if manual {
+		opts = append(opts, simulated.WithManualMining())
+	}
+	backend, err := simulated.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	encoder, err := multichain.NewAddressManager(chainType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := conformance.Chain{
+		NewClient: func(p provider.CommonProvider) (client.Client, error) {
+			return multichain.NewClient(chainType, p)
+		},
+		NewTxBuilder: func(p provider.CommonProvider) (txbuilder.TxBuilder, error) {
+			return multichain.NewTxBuilder(chainType, p)
+		},
+		Encoder:   encoder,
+		Provider:  backend.Provider(),
+		ChainID:   "1337",
+		Addresses: []string{"0x0000000000000000000000000000000000000000", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+	}
+	for _, a := range backend.Accounts() {
+		c.Accounts = append(c.Accounts, conformance.Account{Address: a.Address, PrivateKey: a.PrivateKey})
+	}
+	if manual {
+		c.Mine = backend.Mine
+	}
+	conformance.Run(t, c)
+}
+
+func TestEthereum(t *testing.T) {
+	run(t, multichain.TypeEthereum, true)
+}
+
+func TestBinance(t *testing.T) {
+	run(t, multichain.TypeBinance, false)
+}
+
+func TestOKEx(t *testing.T) {
+	run(t, multichain.TypeOKEx, false)
+}
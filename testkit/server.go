@@ -0,0 +1,339 @@
+// Package testkit 提供可编程的 JSON-RPC 模拟服务，用于在没有网络的情况下测试使用 client.Client 的代码
+//
+// Server 在本地同时提供 HTTP 与 WebSocket 的 JSON-RPC 服务，测试可以按方法设置返回值、注入错误与延迟，
+// 并检查服务收到的请求：
+//
+//	server := testkit.NewServer()
+//	defer server.Close()
+//	server.Respond("eth_chainId", "0x1")
+//	server.RespondError("eth_sendRawTransaction", -32000, "nonce too low")
+//	cli, _ := multichain.NewClient(multichain.TypeEthereum, server.Provider())
+//	...
+//	server.AssertCalled(t, "eth_sendRawTransaction", 1)
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/mgintoki/multichain/api/provider"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Error 是 JSON-RPC 的错误返回，HandlerFunc 返回 *Error 时按原样返回给客户端
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// HandlerFunc 根据请求参数生成返回值，返回的 error 不是 *Error 时使用 -32000 作为错误码
+type HandlerFunc func(params []json.RawMessage) (interface{}, error)
+
+// Request 是服务收到的 JSON-RPC 请求
+type Request struct {
+	Method    string
+	Params    []json.RawMessage
+	ID        json.RawMessage
+	Transport string // "http" 或 "ws"
+}
+
+// Param 将第 i 个参数解析到 out 中
+func (r Request) Param(i int, out interface{}) error {
+	if i >= len(r.Params) {
+		return fmt.Errorf("request %s has %d params", r.Method, len(r.Params))
+	}
+	return json.Unmarshal(r.Params[i], out)
+}
+
+// Raw 将 json 字符串作为返回值原样返回，如 testkit.Raw(`{"status":"0x1"}`)
+func Raw(s string) json.RawMessage {
+	return json.RawMessage(s)
+}
+
+type script struct {
+	once    []HandlerFunc
+	handler HandlerFunc
+	delay   time.Duration
+}
+
+// Server 是可编程的 JSON-RPC 模拟服务，可以并发使用
+type Server struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	lock     sync.Mutex
+	scripts  map[string]*script
+	requests []Request
+}
+
+// NewServer 创建并启动模拟服务，未设置返回值的方法返回 -32601 错误
+func NewServer() *Server {
+	s := &Server{scripts: map[string]*script{}}
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// URL 返回 HTTP 服务地址
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// WSURL 返回 WebSocket 服务地址
+func (s *Server) WSURL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+// Provider 返回使用 HTTP 服务地址的 provider
+func (s *Server) Provider() provider.CommonProvider {
+	return provider.CommonProvider{ProviderUrl: s.URL()}
+}
+
+// WSProvider 返回使用 WebSocket 服务地址的 provider
+func (s *Server) WSProvider() provider.CommonProvider {
+	return provider.CommonProvider{ProviderUrl: s.WSURL()}
+}
+
+// Close 关闭服务
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) script(method string) *script {
+	sc, ok := s.scripts[method]
+	if !ok {
+		sc = &script{}
+		s.scripts[method] = sc
+	}
+	return sc
+}
+
+func rpcError(code int, message string) HandlerFunc {
+	return func([]json.RawMessage) (interface{}, error) {
+		return nil, &Error{Code: code, Message: message}
+	}
+}
+
+// Respond 设置方法的返回值，result 按 json 编码，可以使用 Raw 返回原始 json，nil 返回 null
+func (s *Server) Respond(method string, result interface{}) *Server {
+	return s.RespondFunc(method, resultFunc(result))
+}
+
+// RespondOnce 设置方法下一次调用的返回值，多次调用时按顺序使用，用完后使用 Respond 设置的返回值
+func (s *Server) RespondOnce(method string, result interface{}) *Server {
+	return s.RespondOnceFunc(method, resultFunc(result))
+}
+
+// RespondError 设置方法返回 JSON-RPC 错误
+func (s *Server) RespondError(method string, code int, message string) *Server {
+	return s.RespondFunc(method, rpcError(code, message))
+}
+
+// RespondErrorOnce 设置方法下一次调用返回 JSON-RPC 错误
+func (s *Server) RespondErrorOnce(method string, code int, message string) *Server {
+	return s.RespondOnceFunc(method, rpcError(code, message))
+}
+
+// RespondFunc 设置方法使用 handler 生成返回值
+func (s *Server) RespondFunc(method string, handler HandlerFunc) *Server {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.script(method).handler = handler
+	return s
+}
+
+// RespondOnceFunc 设置方法下一次调用使用 handler 生成返回值
+func (s *Server) RespondOnceFunc(method string, handler HandlerFunc) *Server {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sc := s.script(method)
+	sc.once = append(sc.once, handler)
+	return s
+}
+
+// Delay 设置方法返回前的延迟，用于测试超时与并发
+func (s *Server) Delay(method string, d time.Duration) *Server {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.script(method).delay = d
+	return s
+}
+
+// Requests 返回服务收到的请求，传入 method 时只返回对应方法的请求
+func (s *Server) Requests(method ...string) []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var reqs []Request
+	for _, req := range s.requests {
+		if len(method) == 0 || contains(method, req.Method) {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// Calls 返回方法被调用的次数
+func (s *Server) Calls(method string) int {
+	return len(s.Requests(method))
+}
+
+// AssertCalled 检查方法被调用的次数
+func (s *Server) AssertCalled(t testing.TB, method string, times int) {
+	t.Helper()
+	if calls := s.Calls(method); calls != times {
+		t.Fatalf("expect %s to be called %d times, got %d", method, times, calls)
+	}
+}
+
+// Reset 清除设置的返回值与收到的请求
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.scripts = map[string]*script{}
+	s.requests = nil
+}
+
+func resultFunc(v interface{}) HandlerFunc {
+	return func([]json.RawMessage) (interface{}, error) {
+		return v, nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// ServeHTTP 处理 HTTP 与 WebSocket 请求，支持批量请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWS(w, r)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.handleMessage(body, "http"))
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var writeLock sync.Mutex
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// 每个请求单独处理，延迟的请求不会阻塞其他请求
+		go func() {
+			resp := s.handleMessage(msg, "ws")
+			writeLock.Lock()
+			defer writeLock.Unlock()
+			conn.WriteMessage(websocket.TextMessage, resp)
+		}()
+	}
+}
+
+func (s *Server) handleMessage(msg []byte, transport string) []byte {
+	msg = []byte(strings.TrimSpace(string(msg)))
+	if len(msg) != 0 && msg[0] == '[' {
+		var reqs []rpcRequest
+		if err := json.Unmarshal(msg, &reqs); err != nil {
+			return parseError(err)
+		}
+		resps := make([]rpcResponse, 0, len(reqs))
+		for _, req := range reqs {
+			resps = append(resps, s.handle(req, transport))
+		}
+		data, _ := json.Marshal(resps)
+		return data
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return parseError(err)
+	}
+	data, _ := json.Marshal(s.handle(req, transport))
+	return data
+}
+
+func parseError(err error) []byte {
+	data, _ := json.Marshal(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: -32700, Message: err.Error()}})
+	return data
+}
+
+func (s *Server) handle(req rpcRequest, transport string) rpcResponse {
+	s.lock.Lock()
+	s.requests = append(s.requests, Request{Method: req.Method, Params: req.Params, ID: req.ID, Transport: transport})
+	var handler HandlerFunc
+	var delay time.Duration
+	if sc, ok := s.scripts[req.Method]; ok {
+		delay = sc.delay
+		if len(sc.once) != 0 {
+			handler = sc.once[0]
+			sc.once = sc.once[1:]
+		} else {
+			handler = sc.handler
+		}
+	}
+	s.lock.Unlock()
+
+	if delay != 0 {
+		time.Sleep(delay)
+	}
+
+	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	if handler == nil {
+		resp.Error = &Error{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+		return resp
+	}
+	res, err := handler(req.Params)
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: -32000, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	if res == nil {
+		res = json.RawMessage("null")
+	}
+	resp.Result = res
+	return resp
+}
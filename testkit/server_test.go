@@ -0,0 +1,137 @@
+package testkit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/mgintoki/multichain"
+	"github.com/mgintoki/multichain/common"
+	"github.com/mgintoki/multichain/testkit"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	txHash    = "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+	blockHash = "0x1d59ff54b1eb26b013ce3cb5fc9dab3705b415a67127a003c3e61eb445bb8df2"
+	sender    = "0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0"
+)
+
+func TestQueryTx(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+
+	cli, err := multichain.NewClient(multichain.TypeEthereum, server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 节点中不存在的交易
+	server.Respond("eth_getTransactionReceipt", nil).Respond("eth_getTransactionByHash", nil)
+	txData, err := cli.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusPending || txData.From != "" {
+		t.Fatalf("unexpected tx %+v", txData)
+	}
+
+	// 部分节点返回的日志没有 removed 字段
+	server.Respond("eth_getTransactionReceipt", testkit.Raw(`{"transactionIndex":"0x0","blockHash":"`+blockHash+`","blockNumber":"0x1","gasUsed":"0x5208",
+		"cumulativeGasUsed":"0x5208","status":"0x1","logs":[{"address":"`+sender+`","logIndex":"0x0","data":"0x","topics":[]}]}`))
+	server.Respond("eth_getTransactionByHash", testkit.Raw(`{"hash":"`+txHash+`","from":"`+sender+`","to":"`+sender+`","nonce":"0x1","gas":"0x5208",
+		"gasPrice":"0x1","value":"0x0","input":"0x","v":"0x25","r":"0x1","s":"0x1","blockHash":"`+blockHash+`","blockNumber":"0x1","transactionIndex":"0x0"}`))
+	server.Respond("eth_getBlockByHash", testkit.Raw(`{"hash":"`+blockHash+`","parentHash":"`+blockHash+`","sha3Uncles":"`+blockHash+`","transactionsRoot":"`+blockHash+`",
+		"stateRoot":"`+blockHash+`","receiptsRoot":"`+blockHash+`","miner":"`+sender+`","number":"0x1","gasLimit":"0x1","gasUsed":"0x1","timestamp":"0x61f4b3c0",
+		"difficulty":"0x1","extraData":"0x","transactions":[],"uncles":[]}`))
+	txData, err = cli.QueryTx(txHash, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txData.Status != common.TxStatusSuccess || len(txData.Logs) != 1 || txData.From != sender {
+		t.Fatalf("unexpected tx %+v", txData)
+	}
+
+	reqs := server.Requests("eth_getTransactionReceipt")
+	if len(reqs) != 2 {
+		t.Fatalf("expect 2 receipt requests, got %d", len(reqs))
+	}
+	var hash string
+	if err := reqs[1].Param(0, &hash); err != nil || hash != txHash {
+		t.Fatalf("unexpected param %s %v", hash, err)
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+
+	cli, err := multichain.NewClient(multichain.TypeEthereum, server.Provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Respond("eth_getBalance", "0x10").RespondErrorOnce("eth_getBalance", -32005, "rate limited")
+	if _, err := cli.BalanceOf(sender, nil); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("expect rate limited, got %v", err)
+	}
+	balance, err := cli.BalanceOf(sender, nil)
+	if err != nil || balance.Int64() != 16 {
+		t.Fatalf("unexpected balance %v %v", balance, err)
+	}
+
+	server.RespondFunc("eth_getBalance", func(params []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("header not found")
+	})
+	if _, err := cli.BalanceOf(sender, nil); err == nil || !strings.Contains(err.Error(), "header not found") {
+		t.Fatalf("expect header not found, got %v", err)
+	}
+	if _, err := cli.GetChainID(); err == nil {
+		t.Fatal("expect error for unscripted method")
+	}
+	server.AssertCalled(t, "eth_getBalance", 3)
+	server.AssertCalled(t, "eth_chainId", 1)
+}
+
+// go-web3 的 WebSocket 客户端在响应很快时可能丢失响应，这里直接使用 websocket 连接测试
+func TestWebSocketDelay(t *testing.T) {
+	server := testkit.NewServer()
+	defer server.Close()
+	server.Respond("eth_chainId", "0x38").Respond("eth_getBalance", "0x1").Delay("eth_getBalance", 200*time.Millisecond)
+
+	conn, _, err := websocket.DefaultDialer.Dial(server.WSURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["` + sender + `","latest"]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]}`,
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(req)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 延迟的请求不会阻塞同一连接上的其他请求
+	var ids []int
+	for i := 0; i < 2; i++ {
+		var resp struct {
+			ID     int    `json:"id"`
+			Result string `json:"result"`
+		}
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, resp.ID)
+	}
+	if ids[0] != 2 || ids[1] != 1 {
+		t.Fatalf("unexpected response order %v", ids)
+	}
+
+	reqs := server.Requests()
+	if len(reqs) != 2 || reqs[0].Transport != "ws" || reqs[1].Transport != "ws" {
+		t.Fatalf("unexpected requests %+v", reqs)
+	}
+}